@@ -62,7 +62,7 @@ func TestIntegration_ServerEndpoints(t *testing.T) {
 	defer os.Chdir(originalWd)
 
 	// Create app config using the new config package
-	cfg, err := config.NewConfig()
+	cfg, err := config.NewConfig(config.Overrides{})
 	if err != nil {
 		t.Fatalf("Failed to create app config: %v", err)
 	}
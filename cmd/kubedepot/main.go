@@ -1,7 +1,15 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/rgeraskin/kubedepot/internal/config"
 	"github.com/rgeraskin/kubedepot/internal/server"
@@ -10,15 +18,30 @@ import (
 //go:embed kodata/web/*
 var embeddedFiles embed.FS
 
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+// shutdownTimeout is how long in-flight requests may take to finish on shutdown
+const shutdownTimeout = 30 * time.Second
+
 func main() {
+	overrides, args, err := parseFlags(os.Args[1:], os.Stderr)
+	if err != nil {
+		os.Exit(2)
+	}
+
 	// Load configuration
-	cfg, err := config.NewConfig()
+	cfg, err := config.NewConfig(overrides)
 	if err != nil {
 		panic("Failed to load configuration: " + err.Error())
 	}
 
+	if len(args) > 0 && args[0] == "validate" {
+		os.Exit(runValidate(cfg, args[1:], os.Stdout))
+	}
+
 	logger := cfg.Logger
-	logger.Info("Starting kubedepot")
+	logger.Info("Starting kubedepot", "version", version)
 
 	// Log effective configuration
 	logger.Info("Configuration loaded",
@@ -26,24 +49,204 @@ func main() {
 		"configsDir", cfg.ConfigsDir,
 		"webDir", cfg.WebDir,
 		"debug", cfg.Debug,
+		"maxScanDepth", cfg.MaxScanDepth,
+		"listenSocket", cfg.ListenSocket,
+		"responseDelay", cfg.ResponseDelay,
+		"skipMergeValidation", cfg.SkipMergeValidation,
+		"requestIDHeader", cfg.RequestIDHeader,
+		"defaultCurrentContext", cfg.DefaultCurrentContext,
+		"maxResponseSize", cfg.MaxResponseSize,
+		"normalize", cfg.Normalize,
+		"singleFile", cfg.SingleFile,
+		"watch", cfg.Watch,
+		"compressPaths", cfg.CompressPaths,
+		"maintenance", cfg.Maintenance,
+		"adminToken", cfg.AdminToken != "",
+		"requireName", cfg.RequireName,
+		"groupsFile", cfg.GroupsFile,
+		"tlsCertFile", cfg.TLSCertFile,
+		"tlsKeyFile", cfg.TLSKeyFile,
+		"idleTimeout", cfg.IdleTimeout,
+		"disableKeepAlive", cfg.DisableKeepAlive,
+		"authToken", cfg.AuthToken != "",
+		"forceSecure", cfg.ForceSecure,
+		"templateLeftDelim", cfg.TemplateLeftDelim,
+		"templateRightDelim", cfg.TemplateRightDelim,
+		"skipInvalidConfigs", cfg.SkipInvalidConfigs,
+		"currentContextPriority", cfg.CurrentContextPriority,
+		"secretSource", cfg.SecretSource,
+		"secretNamespace", cfg.SecretNamespace,
+		"secretLabelSelector", cfg.SecretLabelSelector,
+		"mergeConflictStrategy", cfg.MergeConflictStrategy,
+		"prefixWithConfigName", cfg.PrefixWithConfigName,
+		"contextNamePattern", cfg.ContextNamePattern,
+		"configDropWarnThreshold", cfg.ConfigDropWarnThreshold,
+		"maxRawCache", cfg.MaxRawCache,
+		"recursiveConfigs", cfg.RecursiveConfigs,
+		"securityHeaders", cfg.SecurityHeaders,
+		"allowedExtensions", cfg.AllowedExtensions,
+		"logQueryParams", cfg.LogQueryParams,
+		"redactQueryParams", cfg.RedactQueryParams,
+		"asyncLoad", cfg.AsyncLoad,
+		"accessLog", cfg.AccessLog,
+		"bindAddress", cfg.BindAddress,
+		"exposeInstanceHeader", cfg.ExposeInstanceHeader,
+		"outputApiVersion", cfg.OutputApiVersion,
+		"include", cfg.Include,
+		"corsAllowedOrigins", cfg.CORSAllowedOrigins,
+		"serverHeader", cfg.ServerHeader,
+		"getEmptyStatus", cfg.GetEmptyStatus,
+		"nameCase", cfg.NameCase,
+		"defaultFormat", cfg.DefaultFormat,
+		"currentContextOnMissing", cfg.CurrentContextOnMissing,
+		"strictServerURLs", cfg.StrictServerURLs,
+		"convertCAFiles", cfg.ConvertCAFiles,
+		"loadConcurrency", cfg.LoadConcurrency,
+		"resolveServerHosts", cfg.ResolveServerHosts,
 	)
 
-	// Create server configuration
-	serverConfig := &server.Server{
-		ConfigsDir:    cfg.ConfigsDir,
-		WebDir:        cfg.WebDir,
-		Logger:        logger,
-		EmbeddedFiles: &embeddedFiles,
-	}
-
 	// Create and start server
-	srv, err := server.NewServer(serverConfig)
+	srv, err := server.NewServer(newServerConfig(cfg))
 	if err != nil {
 		logger.Fatalf("Failed to initialize server: %+v", err)
 	}
 
+	// Drain in-flight requests on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	logger.Debug("Starting server", "port", cfg.Port)
-	if err := srv.Start(cfg.Port); err != nil {
-		logger.Fatalf("Server failed: %+v", err)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Start(cfg.Port)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			logger.Fatalf("Server failed: %+v", err)
+		}
+	case <-ctx.Done():
+		logger.Info("Shutdown signal received")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Fatalf("Failed to shut down gracefully: %+v", err)
+		}
+		if err := <-serveErr; err != nil {
+			logger.Fatalf("Server failed: %+v", err)
+		}
+		logger.Info("Server stopped")
+	}
+}
+
+// newServerConfig creates the server configuration from the application configuration
+func newServerConfig(cfg *config.Config) *server.Server {
+	return &server.Server{
+		ConfigsDir:              cfg.ConfigsDir,
+		WebDir:                  cfg.WebDir,
+		Logger:                  cfg.Logger,
+		EmbeddedFiles:           &embeddedFiles,
+		MaxScanDepth:            cfg.MaxScanDepth,
+		ListenSocket:            cfg.ListenSocket,
+		Debug:                   cfg.Debug,
+		ResponseDelay:           cfg.ResponseDelay,
+		SkipMergeValidation:     cfg.SkipMergeValidation,
+		RequestIDHeader:         cfg.RequestIDHeader,
+		DefaultCurrentContext:   cfg.DefaultCurrentContext,
+		MaxResponseSize:         cfg.MaxResponseSize,
+		Normalize:               cfg.Normalize,
+		SingleFile:              cfg.SingleFile,
+		Watch:                   cfg.Watch,
+		CompressPaths:           cfg.CompressPaths,
+		Maintenance:             cfg.Maintenance,
+		AdminToken:              cfg.AdminToken,
+		RequireName:             cfg.RequireName,
+		GroupsFile:              cfg.GroupsFile,
+		TLSCertFile:             cfg.TLSCertFile,
+		TLSKeyFile:              cfg.TLSKeyFile,
+		IdleTimeout:             cfg.IdleTimeout,
+		DisableKeepAlive:        cfg.DisableKeepAlive,
+		AuthToken:               cfg.AuthToken,
+		ForceSecure:             cfg.ForceSecure,
+		TemplateLeftDelim:       cfg.TemplateLeftDelim,
+		TemplateRightDelim:      cfg.TemplateRightDelim,
+		SkipInvalidConfigs:      cfg.SkipInvalidConfigs,
+		CurrentContextPriority:  cfg.CurrentContextPriority,
+		SecretSource:            cfg.SecretSource,
+		SecretNamespace:         cfg.SecretNamespace,
+		SecretLabelSelector:     cfg.SecretLabelSelector,
+		MergeConflictStrategy:   cfg.MergeConflictStrategy,
+		PrefixWithConfigName:    cfg.PrefixWithConfigName,
+		ContextNamePattern:      cfg.ContextNamePattern,
+		ConfigDropWarnThreshold: cfg.ConfigDropWarnThreshold,
+		MaxRawCache:             cfg.MaxRawCache,
+		RecursiveConfigs:        cfg.RecursiveConfigs,
+		SecurityHeaders:         cfg.SecurityHeaders,
+		AllowedExtensions:       cfg.AllowedExtensions,
+		LogQueryParams:          cfg.LogQueryParams,
+		RedactQueryParams:       cfg.RedactQueryParams,
+		AsyncLoad:               cfg.AsyncLoad,
+		AccessLog:               cfg.AccessLog,
+		BindAddress:             cfg.BindAddress,
+		ExposeInstanceHeader:    cfg.ExposeInstanceHeader,
+		Version:                 version,
+		OutputApiVersion:        cfg.OutputApiVersion,
+		Include:                 cfg.Include,
+		CORSAllowedOrigins:      cfg.CORSAllowedOrigins,
+		ServerHeader:            cfg.ServerHeader,
+		GetEmptyStatus:          cfg.GetEmptyStatus,
+		NameCase:                cfg.NameCase,
+		DefaultFormat:           cfg.DefaultFormat,
+		CurrentContextOnMissing: cfg.CurrentContextOnMissing,
+		StrictServerURLs:        cfg.StrictServerURLs,
+		ConvertCAFiles:          cfg.ConvertCAFiles,
+		LoadConcurrency:         cfg.LoadConcurrency,
+		ResolveServerHosts:      cfg.ResolveServerHosts,
+	}
+}
+
+// parseFlags parses the command line flags into config overrides and returns the
+// remaining arguments. Environment variables take precedence over flags
+func parseFlags(args []string, out io.Writer) (config.Overrides, []string, error) {
+	var overrides config.Overrides
+
+	flags := flag.NewFlagSet("kubedepot", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.StringVar(&overrides.Port, "port", "", "port to listen on (env PORT, default "+config.DefaultPort+")")
+	flags.StringVar(&overrides.ConfigsDir, "configs-dir", "", "directory with kubeconfigs (env CONFIGS_DIR, default "+config.DefaultConfigsDir+")")
+	flags.StringVar(&overrides.WebDir, "web-dir", "", "directory with web templates (env WEB_DIR, default "+config.DefaultWebDir+")")
+	flags.BoolVar(&overrides.Debug, "debug", false, "enable debug logging (env DEBUG)")
+
+	if err := flags.Parse(args); err != nil {
+		return config.Overrides{}, nil, err
+	}
+	return overrides, flags.Args(), nil
+}
+
+// runValidate implements "kubedepot validate [dir]": it loads and validates all configs of
+// dir, CONFIGS_DIR by default, without starting the server and returns the exit code,
+// non-zero if any config is invalid or the configs can't be merged together
+func runValidate(cfg *config.Config, args []string, out io.Writer) int {
+	if len(args) > 1 {
+		fmt.Fprintln(out, "Usage: kubedepot validate [dir]")
+		return 2
+	}
+	if len(args) == 1 {
+		cfg.ConfigsDir = args[0]
+	}
+
+	srv, err := server.NewValidationServer(newServerConfig(cfg))
+	if err != nil {
+		fmt.Fprintf(out, "Invalid configuration: %v\n", err)
+		return 1
+	}
+
+	count, err := srv.ValidateConfigs()
+	if err != nil {
+		fmt.Fprintf(out, "Configs in %s are invalid: %v\n", cfg.ConfigsDir, err)
+		return 1
 	}
+	fmt.Fprintf(out, "All %d configs in %s are valid\n", count, cfg.ConfigsDir)
+	return 0
 }
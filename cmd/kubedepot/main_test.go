@@ -1,11 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/charmbracelet/log"
 	"github.com/rgeraskin/kubedepot/internal/config"
+	"github.com/rgeraskin/kubedepot/internal/server"
 	"github.com/rgeraskin/kubedepot/internal/testutil"
 )
 
@@ -73,7 +80,7 @@ func TestMainPackageIntegration(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Test the same flow as main() but without starting the server
-			cfg, err := config.NewConfig()
+			cfg, err := config.NewConfig(config.Overrides{})
 			if err != nil {
 				if !tt.wantErr {
 					t.Errorf("Failed to create config: %v", err)
@@ -148,7 +155,7 @@ func TestMainPackageErrorHandling(t *testing.T) {
 			os.Setenv("WEB_DIR", tt.webDir)
 
 			// Test config creation (should not panic)
-			cfg, err := config.NewConfig()
+			cfg, err := config.NewConfig(config.Overrides{})
 			if err != nil {
 				t.Errorf("Unexpected error creating config: %v", err)
 				return
@@ -165,3 +172,98 @@ func TestMainPackageErrorHandling(t *testing.T) {
 		})
 	}
 }
+
+// TestRunValidate tests the validate subcommand exit codes
+func TestRunValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+	}{
+		{name: "valid directory", args: []string{testutil.GetValidKubeConfigsDir(t)}, expectedCode: 0},
+		{name: "invalid directory", args: []string{testutil.GetInvalidKubeConfigsDir(t)}, expectedCode: 1},
+		{name: "missing directory", args: []string{"/nonexistent/directory"}, expectedCode: 1},
+		{name: "too many arguments", args: []string{"a", "b"}, expectedCode: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := log.New(io.Discard)
+			cfg := &config.Config{Logger: logger}
+
+			var out bytes.Buffer
+			code := runValidate(cfg, tt.args, &out)
+			if code != tt.expectedCode {
+				t.Errorf("Expected exit code %d, got %d: %s", tt.expectedCode, code, out.String())
+			}
+			if out.Len() == 0 {
+				t.Error("Expected a report")
+			}
+		})
+	}
+
+	t.Run("invalid configs skipped on startup still fail", func(t *testing.T) {
+		cfg := &config.Config{Logger: log.New(io.Discard), SkipInvalidConfigs: true}
+
+		var out bytes.Buffer
+		if code := runValidate(cfg, []string{testutil.GetMixedKubeConfigsDir(t)}, &out); code != 1 {
+			t.Errorf("Expected exit code 1, got %d: %s", code, out.String())
+		}
+	})
+}
+
+func TestParseFlags(t *testing.T) {
+	overrides, args, err := parseFlags(
+		[]string{"--port", "9191", "--configs-dir", "/configs", "--web-dir", "/web", "--debug", "validate", "dir"},
+		io.Discard,
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := config.Overrides{Port: "9191", ConfigsDir: "/configs", WebDir: "/web", Debug: true}
+	if overrides != expected {
+		t.Errorf("Expected overrides %+v, got %+v", expected, overrides)
+	}
+	if len(args) != 2 || args[0] != "validate" || args[1] != "dir" {
+		t.Errorf("Expected remaining args [validate dir], got %v", args)
+	}
+
+	if _, _, err := parseFlags([]string{"--unknown"}, io.Discard); err == nil {
+		t.Error("Expected error for unknown flag")
+	}
+}
+
+func TestDefaultCurrentContextFromConfig(t *testing.T) {
+	t.Setenv("CONFIGS_DIR", testutil.GetValidKubeConfigsDir(t))
+	t.Setenv("WEB_DIR", testutil.GetTestDataDir(t))
+	t.Setenv("DEFAULT_CURRENT_CONTEXT", "prod-context")
+
+	cfg, err := config.NewConfig(config.Overrides{})
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	cfg.Logger = log.New(io.Discard)
+
+	srv, err := server.NewServer(newServerConfig(cfg))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/json/get?name=dev&name=prod", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var merged struct {
+		CurrentContext string `json:"current-context"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &merged); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if merged.CurrentContext != "prod-context" {
+		t.Errorf("Expected current context from DEFAULT_CURRENT_CONTEXT, got %q", merged.CurrentContext)
+	}
+}
@@ -1,40 +1,225 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/joomcode/errorx"
+	"gopkg.in/yaml.v3"
 )
 
 // Config represents the application configuration
 type Config struct {
+	Port         string
+	ConfigsDir   string
+	WebDir       string
+	Debug        bool
+	MaxScanDepth int
+	ListenSocket string
+	// ResponseDelay is only honored in debug mode to prevent accidental production use
+	ResponseDelay           time.Duration
+	SkipMergeValidation     bool
+	RequestIDHeader         string
+	DefaultCurrentContext   string
+	MaxResponseSize         int
+	Normalize               bool
+	SingleFile              bool
+	Watch                   bool
+	CompressPaths           []string
+	Maintenance             bool
+	AdminToken              string
+	RequireName             bool
+	GroupsFile              string
+	TLSCertFile             string
+	TLSKeyFile              string
+	IdleTimeout             time.Duration
+	DisableKeepAlive        bool
+	AuthToken               string
+	ForceSecure             bool
+	TemplateLeftDelim       string
+	TemplateRightDelim      string
+	SkipInvalidConfigs      bool
+	CurrentContextPriority  []string
+	SecretSource            bool
+	SecretNamespace         string
+	SecretLabelSelector     string
+	MergeConflictStrategy   string
+	PrefixWithConfigName    bool
+	ContextNamePattern      string
+	ConfigDropWarnThreshold int
+	MaxRawCache             int
+	RecursiveConfigs        bool
+	SecurityHeaders         map[string]string
+	AllowedExtensions       []string
+	LogQueryParams          bool
+	RedactQueryParams       []string
+	AsyncLoad               bool
+	AccessLog               bool
+	BindAddress             string
+	ExposeInstanceHeader    bool
+	OutputApiVersion        string
+	Include                 []string
+	CORSAllowedOrigins      []string
+	ServerHeader            string
+	GetEmptyStatus          int
+	NameCase                string
+	DefaultFormat           string
+	CurrentContextOnMissing string
+	StrictServerURLs        bool
+	ConvertCAFiles          bool
+	LoadConcurrency         int
+	ResolveServerHosts      bool
+	Logger                  *log.Logger
+}
+
+// Default values
+const (
+	DefaultPort                    = "8080"
+	DefaultConfigsDir              = "./configs"
+	DefaultWebDir                  = "./web"
+	DefaultRequestIDHeader         = "X-Request-ID"
+	DefaultCompressPaths           = "/json/list,/yaml/list"
+	DefaultSecretLabelSelector     = "kubedepot/kubeconfig=true"
+	DefaultMergeConflictStrategy   = "error"
+	DefaultAllowedExtensions       = ".yaml,.yml,.json"
+	DefaultRedactQueryParams       = "token,access_token,password,secret"
+	DefaultOutputApiVersion        = "v1"
+	DefaultGetEmptyStatus          = 200
+	DefaultNameCase                = "preserve"
+	DefaultDefaultFormat           = "yaml"
+	DefaultCurrentContextOnMissing = "clear"
+)
+
+// Overrides holds values set outside the environment, e.g. by command line flags.
+// They replace the config file values and built-in defaults, while a set environment
+// variable still takes precedence. Empty fields are ignored
+type Overrides struct {
 	Port       string
 	ConfigsDir string
 	WebDir     string
 	Debug      bool
-	Logger     *log.Logger
 }
 
-// Default values
-const (
-	DefaultPort       = "8080"
-	DefaultConfigsDir = "./configs"
-	DefaultWebDir     = "./web"
-)
+// FileConfig is the configuration read from the CONFIG_FILE YAML file
+type FileConfig struct {
+	Port        string `yaml:"port"`
+	ConfigsDir  string `yaml:"configsDir"`
+	WebDir      string `yaml:"webDir"`
+	Debug       bool   `yaml:"debug"`
+	AuthToken   string `yaml:"authToken"`
+	AdminToken  string `yaml:"adminToken"`
+	TLSCertFile string `yaml:"tlsCertFile"`
+	TLSKeyFile  string `yaml:"tlsKeyFile"`
+}
+
+// readConfigFile reads the config file, unknown keys are rejected to catch typos
+func readConfigFile(path string) (FileConfig, error) {
+	var fileConfig FileConfig
+	file, err := os.Open(path)
+	if err != nil {
+		return fileConfig, errorx.Decorate(err, "failed to open config file")
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&fileConfig); err != nil && !errors.Is(err, io.EOF) {
+		return fileConfig, errorx.Decorate(err, "invalid config file %s", path)
+	}
+	return fileConfig, nil
+}
+
+// NewConfig creates a new configuration from environment variables, falling back to
+// overrides, then to the CONFIG_FILE file if set and then to defaults
+func NewConfig(overrides Overrides) (*Config, error) {
+	securityHeaders, err := getEnvMap("SECURITY_HEADERS")
+	if err != nil {
+		return nil, err
+	}
+
+	var fileConfig FileConfig
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		fileConfig, err = readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-// NewConfig creates a new configuration from environment variables
-func NewConfig() (*Config, error) {
 	config := &Config{
-		Port:       getEnvOrDefault("PORT", DefaultPort),
-		ConfigsDir: getEnvOrDefault("CONFIGS_DIR", DefaultConfigsDir),
-		WebDir:     getEnvOrDefault("WEB_DIR", DefaultWebDir),
-		Debug:      getEnvBool("DEBUG", false),
+		Port:                    getEnvOrDefault("PORT", firstNonEmpty(overrides.Port, fileConfig.Port, DefaultPort)),
+		ConfigsDir:              getEnvOrDefault("CONFIGS_DIR", firstNonEmpty(overrides.ConfigsDir, fileConfig.ConfigsDir, DefaultConfigsDir)),
+		WebDir:                  getEnvOrDefault("WEB_DIR", firstNonEmpty(overrides.WebDir, fileConfig.WebDir, DefaultWebDir)),
+		Debug:                   getEnvBool("DEBUG", overrides.Debug || fileConfig.Debug),
+		MaxScanDepth:            getEnvInt("MAX_SCAN_DEPTH", 0),
+		ListenSocket:            os.Getenv("LISTEN_SOCKET"),
+		ResponseDelay:           getEnvDuration("DEBUG_RESPONSE_DELAY", 0),
+		SkipMergeValidation:     getEnvBool("SKIP_MERGE_VALIDATION", false),
+		RequestIDHeader:         getEnvOrDefault("REQUEST_ID_HEADER", DefaultRequestIDHeader),
+		DefaultCurrentContext:   os.Getenv("DEFAULT_CURRENT_CONTEXT"),
+		MaxResponseSize:         getEnvInt("MAX_RESPONSE_SIZE", 0),
+		Normalize:               getEnvBool("NORMALIZE", false),
+		SingleFile:              getEnvBool("SINGLE_FILE", false),
+		Watch:                   getEnvBool("WATCH_CONFIGS", false),
+		CompressPaths:           getEnvList("COMPRESS_PATHS", DefaultCompressPaths),
+		Maintenance:             getEnvBool("MAINTENANCE", false),
+		AdminToken:              getEnvOrDefault("ADMIN_TOKEN", fileConfig.AdminToken),
+		RequireName:             getEnvBool("REQUIRE_NAME", false),
+		GroupsFile:              os.Getenv("GROUPS_FILE"),
+		TLSCertFile:             getEnvOrDefault("TLS_CERT_FILE", fileConfig.TLSCertFile),
+		TLSKeyFile:              getEnvOrDefault("TLS_KEY_FILE", fileConfig.TLSKeyFile),
+		IdleTimeout:             getEnvDuration("IDLE_TIMEOUT", 0),
+		DisableKeepAlive:        getEnvBool("DISABLE_KEEPALIVE", false),
+		AuthToken:               getEnvOrDefault("AUTH_TOKEN", fileConfig.AuthToken),
+		ForceSecure:             getEnvBool("FORCE_SECURE", false),
+		TemplateLeftDelim:       os.Getenv("TEMPLATE_LEFT_DELIM"),
+		TemplateRightDelim:      os.Getenv("TEMPLATE_RIGHT_DELIM"),
+		SkipInvalidConfigs:      getEnvBool("SKIP_INVALID_CONFIGS", false),
+		CurrentContextPriority:  getEnvList("CURRENT_CONTEXT_PRIORITY", ""),
+		SecretSource:            getEnvBool("SECRET_SOURCE", false),
+		SecretNamespace:         os.Getenv("SECRET_NAMESPACE"),
+		SecretLabelSelector:     getEnvOrDefault("SECRET_LABEL_SELECTOR", DefaultSecretLabelSelector),
+		MergeConflictStrategy:   getEnvOrDefault("MERGE_CONFLICT_STRATEGY", DefaultMergeConflictStrategy),
+		PrefixWithConfigName:    getEnvBool("PREFIX_WITH_CONFIG_NAME", false),
+		ContextNamePattern:      os.Getenv("CONTEXT_NAME_PATTERN"),
+		ConfigDropWarnThreshold: getEnvInt("CONFIG_DROP_WARN_THRESHOLD", 0),
+		MaxRawCache:             getEnvInt("MAX_RAW_CACHE", 0),
+		RecursiveConfigs:        getEnvBool("RECURSIVE_CONFIGS", false),
+		SecurityHeaders:         securityHeaders,
+		AllowedExtensions:       getEnvList("ALLOWED_EXTENSIONS", DefaultAllowedExtensions),
+		LogQueryParams:          getEnvBool("LOG_QUERY_PARAMS", false),
+		RedactQueryParams:       getEnvList("REDACT_QUERY_PARAMS", DefaultRedactQueryParams),
+		AsyncLoad:               getEnvBool("ASYNC_LOAD", false),
+		AccessLog:               getEnvBool("ACCESS_LOG", false),
+		BindAddress:             os.Getenv("BIND_ADDRESS"),
+		ExposeInstanceHeader:    getEnvBool("EXPOSE_INSTANCE_HEADER", false),
+		OutputApiVersion:        getEnvOrDefault("OUTPUT_APIVERSION", DefaultOutputApiVersion),
+		Include:                 getEnvList("INCLUDE", ""),
+		CORSAllowedOrigins:      getEnvList("CORS_ALLOWED_ORIGINS", ""),
+		ServerHeader:            os.Getenv("SERVER_HEADER"),
+		GetEmptyStatus:          getEnvInt("GET_EMPTY_STATUS", DefaultGetEmptyStatus),
+		NameCase:                getEnvOrDefault("NAME_CASE", DefaultNameCase),
+		DefaultFormat:           getEnvOrDefault("DEFAULT_FORMAT", DefaultDefaultFormat),
+		CurrentContextOnMissing: getEnvOrDefault("CURRENT_CONTEXT_ON_MISSING", DefaultCurrentContextOnMissing),
+		StrictServerURLs:        getEnvBool("STRICT_SERVER_URLS", false),
+		ConvertCAFiles:          getEnvBool("CONVERT_CA_FILES", false),
+		LoadConcurrency:         getEnvInt("LOAD_CONCURRENCY", 0),
+		ResolveServerHosts:      getEnvBool("RESOLVE_SERVER_HOSTS", false),
 	}
 
 	// Create logger based on configuration
 	config.Logger = createLogger(config.Debug)
 
+	if config.ResponseDelay > 0 && !config.Debug {
+		config.Logger.Warn("Ignoring DEBUG_RESPONSE_DELAY because DEBUG is disabled")
+		config.ResponseDelay = 0
+	}
+
 	return config, nil
 }
 
@@ -46,6 +231,16 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
 // getEnvBool returns environment variable as boolean or default
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
@@ -56,6 +251,55 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+// getEnvInt returns environment variable as integer or default
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvDuration returns environment variable as duration or default
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList returns environment variable as a comma-separated list or default,
+// an explicitly empty variable yields an empty list
+func getEnvList(key, defaultValue string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		value = defaultValue
+	}
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvMap returns environment variable as a map parsed from a JSON object, nil if unset
+func getEnvMap(key string) (map[string]string, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil, nil
+	}
+	var parsed map[string]string
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, errorx.Decorate(err, "invalid %s, expected a JSON object of strings", key)
+	}
+	return parsed, nil
+}
+
 // createLogger creates a logger with appropriate level
 func createLogger(debug bool) *log.Logger {
 	logger := log.New(os.Stderr)
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/charmbracelet/log"
 )
@@ -90,7 +94,7 @@ func TestNewConfig(t *testing.T) {
 				os.Setenv(key, value)
 			}
 
-			cfg, err := NewConfig()
+			cfg, err := NewConfig(Overrides{})
 
 			if tt.wantErr {
 				if err == nil {
@@ -296,3 +300,235 @@ func TestGetEnvBool(t *testing.T) {
 		})
 	}
 }
+
+func TestGetEnvInt(t *testing.T) {
+	tests := []struct {
+		name         string
+		envValue     string
+		defaultValue int
+		expected     int
+	}{
+		{name: "valid value", envValue: "3", defaultValue: 0, expected: 3},
+		{name: "invalid value", envValue: "three", defaultValue: 1, expected: 1},
+		{name: "empty value", envValue: "", defaultValue: 2, expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_INT", tt.envValue)
+
+			result := getEnvInt("TEST_INT", tt.defaultValue)
+
+			if result != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestNewConfig_Overrides(t *testing.T) {
+	overrides := Overrides{
+		Port:       "9191",
+		ConfigsDir: "/flag/configs",
+		WebDir:     "/flag/web",
+		Debug:      true,
+	}
+
+	t.Run("flag over default", func(t *testing.T) {
+		for _, key := range []string{"PORT", "CONFIGS_DIR", "WEB_DIR", "DEBUG"} {
+			t.Setenv(key, "")
+		}
+
+		cfg, err := NewConfig(overrides)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if cfg.Port != "9191" || cfg.ConfigsDir != "/flag/configs" || cfg.WebDir != "/flag/web" || !cfg.Debug {
+			t.Errorf("Expected flag values, got port %q, configs dir %q, web dir %q, debug %v",
+				cfg.Port, cfg.ConfigsDir, cfg.WebDir, cfg.Debug)
+		}
+	})
+
+	t.Run("env over flag", func(t *testing.T) {
+		t.Setenv("PORT", "9090")
+		t.Setenv("CONFIGS_DIR", "/env/configs")
+		t.Setenv("WEB_DIR", "/env/web")
+		t.Setenv("DEBUG", "false")
+
+		cfg, err := NewConfig(overrides)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if cfg.Port != "9090" || cfg.ConfigsDir != "/env/configs" || cfg.WebDir != "/env/web" || cfg.Debug {
+			t.Errorf("Expected env values, got port %q, configs dir %q, web dir %q, debug %v",
+				cfg.Port, cfg.ConfigsDir, cfg.WebDir, cfg.Debug)
+		}
+	})
+}
+
+func TestNewConfig_ConfigFile(t *testing.T) {
+	writeConfigFile := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "kubedepot.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return path
+	}
+	clearEnv := func(t *testing.T) {
+		for _, key := range []string{"PORT", "CONFIGS_DIR", "WEB_DIR", "DEBUG", "AUTH_TOKEN", "TLS_CERT_FILE"} {
+			t.Setenv(key, "")
+		}
+	}
+	fileContent := `port: "9292"
+configsDir: /file/configs
+debug: true
+authToken: file-token
+tlsCertFile: /file/tls.crt
+`
+
+	t.Run("file only", func(t *testing.T) {
+		clearEnv(t)
+		t.Setenv("CONFIG_FILE", writeConfigFile(t, fileContent))
+
+		cfg, err := NewConfig(Overrides{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if cfg.Port != "9292" || cfg.ConfigsDir != "/file/configs" || !cfg.Debug {
+			t.Errorf("Expected file values, got port %q, configs dir %q, debug %v", cfg.Port, cfg.ConfigsDir, cfg.Debug)
+		}
+		if cfg.AuthToken != "file-token" || cfg.TLSCertFile != "/file/tls.crt" {
+			t.Errorf("Expected file auth and TLS values, got %q and %q", cfg.AuthToken, cfg.TLSCertFile)
+		}
+		if cfg.WebDir != DefaultWebDir {
+			t.Errorf("Expected default web dir %q, got %q", DefaultWebDir, cfg.WebDir)
+		}
+	})
+
+	t.Run("env over file", func(t *testing.T) {
+		clearEnv(t)
+		t.Setenv("CONFIG_FILE", writeConfigFile(t, fileContent))
+		t.Setenv("PORT", "9090")
+		t.Setenv("DEBUG", "false")
+		t.Setenv("AUTH_TOKEN", "env-token")
+
+		cfg, err := NewConfig(Overrides{ConfigsDir: "/flag/configs"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if cfg.Port != "9090" || cfg.Debug || cfg.AuthToken != "env-token" {
+			t.Errorf("Expected env values, got port %q, debug %v, auth token %q", cfg.Port, cfg.Debug, cfg.AuthToken)
+		}
+		if cfg.ConfigsDir != "/flag/configs" {
+			t.Errorf("Expected flag to win over file, got configs dir %q", cfg.ConfigsDir)
+		}
+	})
+
+	for name, content := range map[string]string{
+		"malformed file": "port: [9292",
+		"unknown key":    "prot: 9292\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("CONFIG_FILE", writeConfigFile(t, content))
+
+			if _, err := NewConfig(Overrides{}); err == nil {
+				t.Error("Expected error for invalid config file")
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+
+		if _, err := NewConfig(Overrides{}); err == nil {
+			t.Error("Expected error for missing config file")
+		}
+	})
+}
+
+func TestNewConfig_ResponseDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		debug    string
+		delay    string
+		expected time.Duration
+	}{
+		{name: "default is no delay", debug: "true", delay: "", expected: 0},
+		{name: "honored in debug mode", debug: "true", delay: "250ms", expected: 250 * time.Millisecond},
+		{name: "ignored without debug mode", debug: "false", delay: "250ms", expected: 0},
+		{name: "invalid duration", debug: "true", delay: "soon", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEBUG", tt.debug)
+			t.Setenv("DEBUG_RESPONSE_DELAY", tt.delay)
+
+			cfg, err := NewConfig(Overrides{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if cfg.ResponseDelay != tt.expected {
+				t.Errorf("Expected ResponseDelay %v, got %v", tt.expected, cfg.ResponseDelay)
+			}
+		})
+	}
+}
+
+func TestGetEnvList(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue *string
+		expected []string
+	}{
+		{name: "unset uses default", envValue: nil, expected: []string{"/a", "/b"}},
+		{name: "custom value", envValue: ptr(" /c , /d,"), expected: []string{"/c", "/d"}},
+		{name: "empty value", envValue: ptr(""), expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.envValue != nil {
+				t.Setenv("TEST_LIST", *tt.envValue)
+			}
+
+			result := getEnvList("TEST_LIST", "/a,/b")
+
+			if !slices.Equal(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetEnvMap(t *testing.T) {
+	t.Setenv("TEST_MAP", `{"X-Frame-Options": "SAMEORIGIN", "Cache-Control": ""}`)
+	result, err := getEnvMap("TEST_MAP")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{"X-Frame-Options": "SAMEORIGIN", "Cache-Control": ""}
+	if !maps.Equal(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	t.Setenv("TEST_MAP", "X-Frame-Options: DENY")
+	if _, err := getEnvMap("TEST_MAP"); err == nil {
+		t.Error("Expected error for a value that isn't a JSON object")
+	}
+
+	t.Setenv("TEST_MAP", "")
+	if result, err := getEnvMap("TEST_MAP"); err != nil || result != nil {
+		t.Errorf("Expected nil for an empty value, got %v and %v", result, err)
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
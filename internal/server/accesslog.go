@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// statusRecorder remembers the status code and body size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if r.status == 0 {
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// withAccessLog logs every request as a JSON line for log aggregation when AccessLog is
+// enabled. The duration covers the inner handlers only
+func (s *Server) withAccessLog(next http.Handler) http.Handler {
+	if !s.AccessLog {
+		return next
+	}
+	logger := s.Logger.With()
+	logger.SetFormatter(log.JSONFormatter)
+	logger.SetLevel(log.InfoLevel)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+		duration := time.Since(start)
+
+		// Handlers that write nothing respond with 200
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		logger.Info("Request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", recorder.status,
+			"duration", duration,
+			"bytes", recorder.bytes,
+			"remoteAddr", r.RemoteAddr,
+			"requestID", RequestID(r.Context()),
+		)
+	})
+}
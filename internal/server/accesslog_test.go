@@ -0,0 +1,72 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/log"
+)
+
+func TestServer_AccessLog(t *testing.T) {
+	tests := []struct {
+		name           string
+		enabled        bool
+		url            string
+		expectedStatus int
+	}{
+		{name: "ok", enabled: true, url: "/json/list", expectedStatus: http.StatusOK},
+		{name: "not found", enabled: true, url: "/json/get?name=staging", expectedStatus: http.StatusNotFound},
+		{name: "disabled", enabled: false, url: "/json/list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.AccessLog = tt.enabled
+			var logs bytes.Buffer
+			server.Logger = log.New(&logs)
+			server.Logger.SetLevel(log.ErrorLevel)
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			var entries []map[string]any
+			for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+				var entry map[string]any
+				if json.Unmarshal([]byte(line), &entry) == nil && entry["msg"] == "Request" {
+					entries = append(entries, entry)
+				}
+			}
+
+			if !tt.enabled {
+				if len(entries) != 0 {
+					t.Errorf("Expected no access log entries, got %v", entries)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("Expected one access log entry, got %d in %q", len(entries), logs.String())
+			}
+			entry := entries[0]
+			if entry["status"] != float64(tt.expectedStatus) {
+				t.Errorf("Expected status %d, got %v", tt.expectedStatus, entry["status"])
+			}
+			if entry["method"] != "GET" || entry["path"] != req.URL.Path {
+				t.Errorf("Expected method GET and path %s, got %v and %v", req.URL.Path, entry["method"], entry["path"])
+			}
+			if entry["bytes"] != float64(w.Body.Len()) {
+				t.Errorf("Expected %d bytes, got %v", w.Body.Len(), entry["bytes"])
+			}
+			for _, field := range []string{"duration", "remoteAddr", "requestID"} {
+				if entry[field] == nil || entry[field] == "" {
+					t.Errorf("Expected %s field to be set, got %v", field, entry)
+				}
+			}
+		})
+	}
+}
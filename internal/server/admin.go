@@ -0,0 +1,68 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// maintenanceRetryAfter is the Retry-After sent by config routes in maintenance mode
+const maintenanceRetryAfter = 5 * time.Minute
+
+// maintenanceExemptPaths keep working in maintenance mode so the service can be monitored
+// and taken out of maintenance
+var maintenanceExemptPaths = []string{"/ping", "/healthz", "/readyz", "/metrics", "/admin/maintenance"}
+
+// requireAdmin checks the admin bearer token of the request and writes an error response
+// if it doesn't match, admin endpoints are disabled when no AdminToken is configured
+func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if s.AdminToken == "" {
+		http.Error(w, "Admin endpoints are disabled, set ADMIN_TOKEN to enable them", http.StatusForbidden)
+		return false
+	}
+	if !bearerTokenMatches(r, s.AdminToken) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Invalid admin token", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// withMaintenance returns 503 for all routes except maintenanceExemptPaths while the
+// server is in maintenance mode
+func (s *Server) withMaintenance(next http.Handler) http.Handler {
+	retryAfter := strconv.Itoa(int(maintenanceRetryAfter.Seconds()))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.maintenance.Load() && !slices.Contains(maintenanceExemptPaths, r.URL.Path) {
+			w.Header().Set("Retry-After", retryAfter)
+			http.Error(w, "Service is in maintenance mode", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandleMaintenance reports maintenance mode on GET and toggles it on POST with
+// the enabled query parameter
+func (s *Server) HandleMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+		if err != nil {
+			http.Error(w, "Query parameter enabled must be true or false", http.StatusBadRequest)
+			return
+		}
+		s.maintenance.Store(enabled)
+		s.Logger.Warn("Maintenance mode changed", "enabled", enabled)
+	}
+
+	err := createJSONEncoder(w).Encode(map[string]bool{"maintenance": s.maintenance.Load()})
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to encode maintenance status", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+func TestServer_Maintenance(t *testing.T) {
+	server, _ := createTestServerValid(t)
+	server.AdminToken = "secret"
+	handler := server.Handler()
+
+	serve := func(method, url, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve("GET", "/json/list", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d before maintenance, got %d", http.StatusOK, w.Code)
+	}
+
+	w := serve("POST", "/admin/maintenance?enabled=true", "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"maintenance":true`) {
+		t.Errorf("Expected maintenance to be enabled, got %s", w.Body.String())
+	}
+
+	for _, url := range []string{"/json/list", "/yaml/get?name=dev", "/download", "/"} {
+		w := serve("GET", url, "")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected status code %d in maintenance, got %d", url, http.StatusServiceUnavailable, w.Code)
+		}
+		if w.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: expected Retry-After header in maintenance", url)
+		}
+	}
+	if w := serve("GET", "/ping", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected ping to stay %d in maintenance, got %d", http.StatusOK, w.Code)
+	}
+	if w := serve("GET", "/admin/maintenance", "secret"); !strings.Contains(w.Body.String(), `"maintenance":true`) {
+		t.Errorf("Expected maintenance status true, got %s", w.Body.String())
+	}
+
+	if w := serve("POST", "/admin/maintenance?enabled=false", "secret"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if w := serve("GET", "/json/list", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d after maintenance, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestServer_HandleMaintenance_Auth(t *testing.T) {
+	tests := []struct {
+		name           string
+		adminToken     string
+		url            string
+		token          string
+		expectedStatus int
+	}{
+		{name: "disabled without admin token", adminToken: "", url: "/admin/maintenance?enabled=true", token: "", expectedStatus: http.StatusForbidden},
+		{name: "missing token", adminToken: "secret", url: "/admin/maintenance?enabled=true", token: "", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong token", adminToken: "secret", url: "/admin/maintenance?enabled=true", token: "guess", expectedStatus: http.StatusUnauthorized},
+		{name: "invalid enabled value", adminToken: "secret", url: "/admin/maintenance?enabled=maybe", token: "secret", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.AdminToken = tt.adminToken
+
+			req := httptest.NewRequest("POST", tt.url, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			server.HandleMaintenance(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if server.maintenance.Load() {
+				t.Error("Expected maintenance mode to stay disabled")
+			}
+		})
+	}
+}
+
+func TestNewServer_Maintenance(t *testing.T) {
+	serverConfig, _ := createTestServerRaw(t, testutil.GetValidKubeConfigsDir(t))
+	serverConfig.Maintenance = true
+	server, err := NewServer(serverConfig)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/json/list", nil)
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
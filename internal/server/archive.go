@@ -0,0 +1,204 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/joomcode/errorx"
+)
+
+const (
+	archiveFormatZip = "zip"
+	archiveFormatTar = "tar"
+
+	archiveCopyBufferSize = 32 * 1024
+
+	// defaultArchiveExtension is the extension of archive entries of source files without one
+	defaultArchiveExtension = ".yaml"
+)
+
+// archiveEntryName names the archive entry of a config after the config and the extension
+// of its source file, so JSON sources stay .json and YAML sources .yaml or .yml
+func archiveEntryName(name, path string) string {
+	ext := filepath.Ext(path)
+	if ext == "" {
+		ext = defaultArchiveExtension
+	}
+	return name + ext
+}
+
+// archiveEntry is a config file to be added to an archive
+type archiveEntry struct {
+	config string
+	name   string
+	path   string
+}
+
+// archiveEntries resolves the source files of the requested configs
+func (s *Server) archiveEntries(names []string) ([]archiveEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]archiveEntry, 0, len(names))
+	for _, name := range names {
+		if _, exists := s.LoadedConfigs[name]; !exists {
+			return nil, NotFound.New("kubeconfig not found: %s", name)
+		}
+		meta := s.ConfigMeta[name]
+		if meta == nil || meta.Path == "" {
+			return nil, errorx.InternalError.New("kubeconfig has no source file: %s", name)
+		}
+		entries = append(entries, archiveEntry{
+			config: name,
+			name:   archiveEntryName(name, meta.Path),
+			path:   meta.Path,
+		})
+	}
+	return entries, nil
+}
+
+// archiveWriter adds files to an archive one at a time
+type archiveWriter interface {
+	addFile(name string, file io.Reader, info os.FileInfo, buf []byte) error
+	Flush() error
+	Close() error
+}
+
+// zipArchiveWriter writes a zip archive
+type zipArchiveWriter struct {
+	*zip.Writer
+}
+
+func (z zipArchiveWriter) addFile(name string, file io.Reader, info os.FileInfo, buf []byte) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	entry, err := z.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyBuffer(entry, file, buf)
+	return err
+}
+
+// tarArchiveWriter writes a tar archive
+type tarArchiveWriter struct {
+	*tar.Writer
+}
+
+func (t tarArchiveWriter) addFile(name string, file io.Reader, info os.FileInfo, buf []byte) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := t.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.CopyBuffer(t, file, buf)
+	return err
+}
+
+// writeArchiveEntry copies a source file into the archive through buf. With the raw cache
+// enabled the file is served from memory, otherwise it's streamed from disk
+func (s *Server) writeArchiveEntry(archive archiveWriter, entry archiveEntry, buf []byte) error {
+	if s.MaxRawCache > 0 {
+		raw, err := s.readRawConfig(entry.config)
+		if err != nil {
+			return err
+		}
+		info := rawFileInfo{name: entry.name, raw: raw}
+		if err := archive.addFile(entry.name, bytes.NewReader(raw.data), info, buf); err != nil {
+			return errorx.Decorate(err, "failed to archive kubeconfig: %s", entry.config)
+		}
+		return nil
+	}
+
+	file, err := os.Open(entry.path)
+	if err != nil {
+		return errorx.Decorate(err, "failed to open kubeconfig file: %s", entry.path)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return errorx.Decorate(err, "failed to stat kubeconfig file: %s", entry.path)
+	}
+	// Hide the file's WriteTo so the shared buffer is used instead of a new one per file
+	if err := archive.addFile(entry.name, struct{ io.Reader }{file}, info, buf); err != nil {
+		return errorx.Decorate(err, "failed to archive kubeconfig file: %s", entry.path)
+	}
+	return nil
+}
+
+// HandleArchive streams the source files of the requested configs as a zip or tar archive.
+// Files are read one at a time and flushed to the client after each entry, so memory use
+// doesn't grow with the number of configs
+func (s *Server) HandleArchive(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = archiveFormatZip
+	}
+	if format != archiveFormatZip && format != archiveFormatTar {
+		http.Error(w, "Unsupported archive format: "+format, http.StatusBadRequest)
+		return
+	}
+
+	if !s.requireRequestedNames(w, r) {
+		return
+	}
+
+	configNames, err := s.listConfigs()
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to read configs directory", http.StatusInternalServerError)
+		return
+	}
+	requestedNames, err := s.getRequestedConfigNames(r, configNames)
+	if err != nil {
+		s.handleError(w, err, "Failed to resolve requested configs")
+		return
+	}
+	entries, err := s.archiveEntries(requestedNames)
+	if err != nil {
+		s.handleError(w, err, "Failed to archive configs")
+		return
+	}
+
+	var archive archiveWriter
+	if format == archiveFormatTar {
+		w.Header().Set("Content-Type", "application/x-tar")
+		archive = tarArchiveWriter{tar.NewWriter(w)}
+	} else {
+		w.Header().Set("Content-Type", "application/zip")
+		archive = zipArchiveWriter{zip.NewWriter(w)}
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="configs.`+format+`"`)
+
+	// Headers are sent with the first entry, errors past this point can only be logged
+	controller := http.NewResponseController(w)
+	buf := make([]byte, archiveCopyBufferSize)
+	for _, entry := range entries {
+		if err := s.writeArchiveEntry(archive, entry, buf); err != nil {
+			s.Logger.Error("Failed to stream archive", "error", err)
+			return
+		}
+		if err := archive.Flush(); err != nil {
+			s.Logger.Error("Failed to stream archive", "error", err)
+			return
+		}
+		if err := controller.Flush(); err != nil {
+			s.Logger.Debug("Response doesn't support flushing", "error", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		s.Logger.Error("Failed to finish archive", "error", err)
+	}
+}
@@ -0,0 +1,291 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+// flushCountingRecorder records how many times the response was flushed
+type flushCountingRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushCountingRecorder) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
+
+// createArchiveTestServer creates a server over count generated single-entry configs
+func createArchiveTestServer(tb testing.TB, count int) *Server {
+	configsDir := tb.TempDir()
+	for i := range count {
+		name := fmt.Sprintf("config-%04d", i)
+		data := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: %[1]s
+  cluster:
+    server: https://%[1]s.example.com
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s
+    user: %[1]s
+current-context: %[1]s
+users:
+- name: %[1]s
+  user:
+    token: %[1]s-token
+`, name)
+		if err := os.WriteFile(filepath.Join(configsDir, name+".yaml"), []byte(data), 0o644); err != nil {
+			tb.Fatalf("Failed to write config: %v", err)
+		}
+	}
+
+	logger := log.New(os.Stderr)
+	logger.SetLevel(log.ErrorLevel)
+	server := &Server{ConfigsDir: configsDir, Logger: logger}
+	if err := server.loadAllConfigs(); err != nil {
+		tb.Fatalf("Failed to load configs: %v", err)
+	}
+	return server
+}
+
+func TestServer_HandleArchive(t *testing.T) {
+	server, _ := createTestServerValid(t)
+	devConfig := testutil.LoadTestData(t, "kubeconfigs/dev.yaml")
+
+	t.Run("zip", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/archive?name=dev&name=prod", nil)
+		w := httptest.NewRecorder()
+		server.HandleArchive(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/zip" {
+			t.Errorf("Expected zip content type, got %q", got)
+		}
+
+		archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		if err != nil {
+			t.Fatalf("Failed to read zip archive: %v", err)
+		}
+		var names []string
+		for _, file := range archive.File {
+			names = append(names, file.Name)
+		}
+		if !slices.Equal(names, []string{"dev.yaml", "prod.yaml"}) {
+			t.Errorf("Expected dev.yaml and prod.yaml, got %v", names)
+		}
+
+		file, err := archive.File[0].Open()
+		if err != nil {
+			t.Fatalf("Failed to open archived file: %v", err)
+		}
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		if !bytes.Equal(data, devConfig) {
+			t.Error("Expected archived file to match the source file")
+		}
+	})
+
+	t.Run("tar", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/archive?format=tar&name=dev", nil)
+		w := httptest.NewRecorder()
+		server.HandleArchive(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		archive := tar.NewReader(w.Body)
+		header, err := archive.Next()
+		if err != nil {
+			t.Fatalf("Failed to read tar archive: %v", err)
+		}
+		if header.Name != "dev.yaml" {
+			t.Errorf("Expected dev.yaml, got %s", header.Name)
+		}
+		data, _ := io.ReadAll(archive)
+		if !bytes.Equal(data, devConfig) {
+			t.Error("Expected archived file to match the source file")
+		}
+		if _, err := archive.Next(); err != io.EOF {
+			t.Errorf("Expected a single archived file, got %v", err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			url  string
+			code int
+		}{
+			{"/archive?name=nonexistent", http.StatusNotFound},
+			{"/archive?format=rar", http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			server.HandleArchive(w, req)
+			if w.Code != tt.code {
+				t.Errorf("%s: expected status code %d, got %d", tt.url, tt.code, w.Code)
+			}
+		}
+	})
+}
+
+func TestServer_HandleArchive_MixedFormats(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{
+		"prod.yml":        "prod.yaml",
+		"valid-test.yaml": "valid-test.yaml",
+		"integration-dev": "integration-dev.yaml",
+	})
+	devJSON := `{"apiVersion": "v1", "kind": "Config",
+"clusters": [{"name": "dev-cluster", "cluster": {"server": "https://dev.example.com"}}],
+"contexts": [{"name": "dev-context", "context": {"cluster": "dev-cluster", "user": "dev-user"}}],
+"users": [{"name": "dev-user", "user": {"token": "dev-token"}}]}
+`
+	if err := os.WriteFile(filepath.Join(configsDir, "dev.json"), []byte(devJSON), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	serverConfig, _ := createTestServerRaw(t, configsDir)
+	// Load all files, whatever their extension
+	serverConfig.AllowedExtensions = []string{}
+	server, err := NewServer(serverConfig)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/archive?name=dev&name=prod&name=valid-test&name=integration-dev", nil)
+	w := httptest.NewRecorder()
+	server.HandleArchive(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read zip archive: %v", err)
+	}
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	// Sources without an extension are archived as YAML
+	expected := []string{"dev.json", "prod.yml", "valid-test.yaml", "integration-dev.yaml"}
+	if !slices.Equal(names, expected) {
+		t.Errorf("Expected archive entries %v, got %v", expected, names)
+	}
+}
+
+func TestServer_HandleArchive_Streaming(t *testing.T) {
+	const count = 200
+	server := createArchiveTestServer(t, count)
+
+	req := httptest.NewRequest("GET", "/archive", nil)
+	w := &flushCountingRecorder{ResponseRecorder: httptest.NewRecorder()}
+	server.HandleArchive(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	// Every entry is flushed to the client as soon as it is written
+	if w.flushes != count {
+		t.Errorf("Expected %d flushes, got %d", count, w.flushes)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read zip archive: %v", err)
+	}
+	if len(archive.File) != count {
+		t.Errorf("Expected %d archived files, got %d", count, len(archive.File))
+	}
+}
+
+// archiveAllocatedBytes returns the bytes allocated by archiving all configs of the server,
+// averaged over a few runs, with the response body discarded
+func archiveAllocatedBytes(t *testing.T, server *Server) uint64 {
+	const runs = 5
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	for range runs {
+		req := httptest.NewRequest("GET", "/archive?format=tar", nil)
+		w := &flushCountingRecorder{ResponseRecorder: httptest.NewRecorder()}
+		w.Body = nil
+		server.HandleArchive(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+	runtime.ReadMemStats(&after)
+	return (after.TotalAlloc - before.TotalAlloc) / runs
+}
+
+// TestServer_HandleArchive_BoundedMemory checks archiving allocates a bounded amount per
+// config: 10 times more configs take about 10 times the memory, and much less than the
+// archived files, so their content is streamed rather than the archive built up in memory
+func TestServer_HandleArchive_BoundedMemory(t *testing.T) {
+	const (
+		count       = 50
+		paddingSize = 64 << 10
+	)
+	// Pad the files after loading, the archive is read from disk
+	createPaddedServer := func(count int) *Server {
+		server := createArchiveTestServer(t, count)
+		padding := "# " + strings.Repeat("x", paddingSize) + "\n"
+		for _, meta := range server.ConfigMeta {
+			file, err := os.OpenFile(meta.Path, os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatalf("Failed to open config: %v", err)
+			}
+			if _, err := file.WriteString(padding); err != nil {
+				t.Fatalf("Failed to pad config: %v", err)
+			}
+			file.Close()
+		}
+		return server
+	}
+
+	small := archiveAllocatedBytes(t, createPaddedServer(count)) / count
+	large := archiveAllocatedBytes(t, createPaddedServer(10*count)) / (10 * count)
+
+	if large > 2*small {
+		t.Errorf("Expected bytes allocated per config to stay about the same, got %d for %d configs and %d for %d configs",
+			small, count, large, 10*count)
+	}
+	if large > paddingSize/4 {
+		t.Errorf("Expected bytes allocated per config well below the config size %d, got %d", paddingSize, large)
+	}
+}
+
+// BenchmarkHandleArchive reports allocations of archiving a large config set,
+// TestServer_HandleArchive_BoundedMemory checks they grow linearly
+func BenchmarkHandleArchive(b *testing.B) {
+	server := createArchiveTestServer(b, 500)
+	b.ReportAllocs()
+	for b.Loop() {
+		req := httptest.NewRequest("GET", "/archive?format=tar", nil)
+		w := &flushCountingRecorder{ResponseRecorder: httptest.NewRecorder()}
+		w.Body = nil // discard output so only the handler's memory is measured
+		server.HandleArchive(w, req)
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// UserUsage lists the configs a user appears in
+type UserUsage struct {
+	Name    string   `json:"name"    yaml:"name"`
+	Configs []string `json:"configs" yaml:"configs"`
+}
+
+// collectUsers returns the distinct users across all loaded configs sorted by name,
+// credentials of the users are never included
+func (s *Server) collectUsers() []UserUsage {
+	s.mu.RLock()
+	configsByUser := make(map[string][]string)
+	for configName, kubeConfig := range s.LoadedConfigs {
+		for _, user := range kubeConfig.Users {
+			if !slices.Contains(configsByUser[user.Name], configName) {
+				configsByUser[user.Name] = append(configsByUser[user.Name], configName)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	users := make([]UserUsage, 0, len(configsByUser))
+	for name, configs := range configsByUser {
+		slices.Sort(configs)
+		users = append(users, UserUsage{Name: name, Configs: configs})
+	}
+	slices.SortFunc(users, func(a, b UserUsage) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return users
+}
+
+// HandleListUsers returns all distinct users and the configs they appear in
+func (s *Server) HandleListUsers(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info("HandleListUsers")
+	users := s.collectUsers()
+
+	err := createJSONEncoder(w).Encode(users)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to encode users list", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Kinds of kubeconfig entries
+const (
+	entryKindCluster = "cluster"
+	entryKindContext = "context"
+	entryKindUser    = "user"
+)
+
+// OrphanEntry is a cluster or user of a config that no context of the config refers to
+type OrphanEntry struct {
+	Config string `json:"config" yaml:"config"`
+	Kind   string `json:"kind"   yaml:"kind"`
+	Name   string `json:"name"   yaml:"name"`
+}
+
+// orphanEntries returns the clusters and users of the config that no context refers to
+func (k *KubeConfig) orphanEntries(configName string) []OrphanEntry {
+	clusters := make(map[string]bool, len(k.Contexts))
+	users := make(map[string]bool, len(k.Contexts))
+	for _, context := range k.Contexts {
+		clusters[context.Context.Cluster] = true
+		users[context.Context.User] = true
+	}
+
+	var orphans []OrphanEntry
+	for _, cluster := range k.Clusters {
+		if !clusters[cluster.Name] {
+			orphans = append(orphans, OrphanEntry{Config: configName, Kind: entryKindCluster, Name: cluster.Name})
+		}
+	}
+	for _, user := range k.Users {
+		if !users[user.Name] {
+			orphans = append(orphans, OrphanEntry{Config: configName, Kind: entryKindUser, Name: user.Name})
+		}
+	}
+	return orphans
+}
+
+// collectOrphans returns the unreferenced clusters and users across all loaded configs
+// sorted by config, kind and name
+func (s *Server) collectOrphans() []OrphanEntry {
+	s.mu.RLock()
+	orphans := []OrphanEntry{}
+	for configName, kubeConfig := range s.LoadedConfigs {
+		orphans = append(orphans, kubeConfig.orphanEntries(configName)...)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(orphans, func(a, b OrphanEntry) int {
+		return cmp.Or(
+			strings.Compare(a.Config, b.Config),
+			strings.Compare(a.Kind, b.Kind),
+			strings.Compare(a.Name, b.Name),
+		)
+	})
+	return orphans
+}
+
+// HandleListOrphans returns the clusters and users no context refers to, to help clean
+// up dead entries
+func (s *Server) HandleListOrphans(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info("HandleListOrphans")
+	orphans := s.collectOrphans()
+
+	err := createJSONEncoder(w).Encode(orphans)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to encode orphans list", http.StatusInternalServerError)
+		return
+	}
+}
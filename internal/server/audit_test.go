@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+func TestServer_HandleListUsers(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{
+		"dev.yaml":  "dev.yaml",
+		"prod.yaml": "prod.yaml",
+	})
+	// A second config reusing dev-user credentials
+	shared := strings.NewReplacer("dev-cluster", "stage-cluster", "dev-context", "stage-context").
+		Replace(string(testutil.LoadTestData(t, "kubeconfigs/dev.yaml")))
+	if err := os.WriteFile(filepath.Join(configsDir, "stage.yaml"), []byte(shared), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	server, _ := createTestServerRaw(t, configsDir)
+	if err := server.loadAllConfigs(); err != nil {
+		t.Fatalf("Failed to load configs: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/json/users", nil)
+	w := httptest.NewRecorder()
+	server.HandleListUsers(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if strings.Contains(w.Body.String(), "token") {
+		t.Errorf("Expected no credentials in response, got %s", w.Body.String())
+	}
+
+	var users []UserUsage
+	if err := json.Unmarshal(w.Body.Bytes(), &users); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+
+	expected := []UserUsage{
+		{Name: "dev-user", Configs: []string{"dev", "stage"}},
+		{Name: "prod-user", Configs: []string{"prod"}},
+	}
+	if len(users) != len(expected) {
+		t.Fatalf("Expected %d users, got %+v", len(expected), users)
+	}
+	for i := range expected {
+		if users[i].Name != expected[i].Name || !slices.Equal(users[i].Configs, expected[i].Configs) {
+			t.Errorf("Expected user %+v, got %+v", expected[i], users[i])
+		}
+	}
+}
+
+func TestServer_HandleListOrphans(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"prod.yaml": "prod.yaml"})
+	// A user no context refers to
+	orphaned := string(testutil.LoadTestData(t, "kubeconfigs/dev.yaml")) +
+		"  - name: old-admin\n    user:\n      token: old-token\n"
+	if err := os.WriteFile(filepath.Join(configsDir, "dev.yaml"), []byte(orphaned), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	server, _ := createTestServerRaw(t, configsDir)
+	if err := server.loadAllConfigs(); err != nil {
+		t.Fatalf("Failed to load configs: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/json/orphans", nil)
+	w := httptest.NewRecorder()
+	server.HandleListOrphans(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if strings.Contains(w.Body.String(), "token") {
+		t.Errorf("Expected no credentials in response, got %s", w.Body.String())
+	}
+
+	var orphans []OrphanEntry
+	if err := json.Unmarshal(w.Body.Bytes(), &orphans); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	expected := []OrphanEntry{{Config: "dev", Kind: entryKindUser, Name: "old-admin"}}
+	if !slices.Equal(orphans, expected) {
+		t.Errorf("Expected orphans %+v, got %+v", expected, orphans)
+	}
+}
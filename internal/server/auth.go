@@ -0,0 +1,43 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// authExemptPaths are served without AuthToken so probes and monitors keep working
+var authExemptPaths = []string{"/healthz", "/readyz", "/ping"}
+
+// adminPaths are the registered admin endpoints, including the ones changing the served
+// configs, they check AdminToken instead of AuthToken. Only exact paths are exempt, other
+// paths under /admin/ would reach the index catch-all
+var adminPaths = []string{"/admin/maintenance", "/upload", "/config", "/reload"}
+
+// bearerTokenMatches reports whether the request carries the bearer token,
+// compared in constant time to avoid timing leaks
+func bearerTokenMatches(r *http.Request, token string) bool {
+	requestToken, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return found && subtle.ConstantTimeCompare([]byte(requestToken), []byte(token)) == 1
+}
+
+// withAuth requires the AuthToken bearer token on all routes except authExemptPaths
+// and admin endpoints, it does nothing when AuthToken is empty
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	if s.AuthToken == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(authExemptPaths, r.URL.Path) || slices.Contains(adminPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !bearerTokenMatches(r, s.AuthToken) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_Auth(t *testing.T) {
+	tests := []struct {
+		name           string
+		authToken      string
+		url            string
+		header         string
+		expectedStatus int
+	}{
+		{name: "no-op when unset", authToken: "", url: "/json/list", header: "", expectedStatus: http.StatusOK},
+		{name: "missing header", authToken: "secret", url: "/json/list", header: "", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong token", authToken: "secret", url: "/json/list", header: "Bearer guess", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", authToken: "secret", url: "/json/list", header: "Basic secret", expectedStatus: http.StatusUnauthorized},
+		{name: "correct token", authToken: "secret", url: "/json/list", header: "Bearer secret", expectedStatus: http.StatusOK},
+		{name: "get requires token", authToken: "secret", url: "/yaml/get?name=dev", header: "", expectedStatus: http.StatusUnauthorized},
+		{name: "ping exempt", authToken: "secret", url: "/ping", header: "", expectedStatus: http.StatusOK},
+		{name: "unknown admin path requires token", authToken: "secret", url: "/admin/unknown", header: "", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.AuthToken = tt.authToken
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("Expected WWW-Authenticate: Bearer, got %q", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestServer_Auth_AdminUsesAdminToken(t *testing.T) {
+	server, _ := createTestServerValid(t)
+	server.AuthToken = "secret"
+	server.AdminToken = "admin-secret"
+
+	req := httptest.NewRequest("GET", "/admin/maintenance", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"compress/gzip"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DefaultCompressPaths are the request paths compressed when none are configured. Only
+// lists are compressed by default, responses carrying credentials are left uncompressed
+// to avoid BREACH-style attacks
+var DefaultCompressPaths = []string{"/json/list", "/yaml/list"}
+
+// compressPaths returns the configured request paths to compress
+func (s *Server) compressPaths() []string {
+	if s.CompressPaths == nil {
+		return DefaultCompressPaths
+	}
+	return s.CompressPaths
+}
+
+// gzipResponseWriter compresses the response body with gzip
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz       *gzip.Writer
+	bodyless bool // Whether the status doesn't allow a body, so nothing is compressed
+}
+
+func (g *gzipResponseWriter) WriteHeader(statusCode int) {
+	g.Header().Del("Content-Length")
+	if statusCode == http.StatusNotModified || statusCode == http.StatusNoContent {
+		g.Header().Del("Content-Encoding")
+		g.bodyless = true
+	}
+	g.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Close finishes the gzip stream unless the response has no body
+func (g *gzipResponseWriter) Close() error {
+	if g.bodyless {
+		return nil
+	}
+	return g.gz.Close()
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	g.Header().Del("Content-Length")
+	return g.gz.Write(b)
+}
+
+func (g *gzipResponseWriter) Flush() {
+	_ = g.gz.Flush()
+	_ = http.NewResponseController(g.ResponseWriter).Flush()
+}
+
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// acceptsGzip reports whether the client accepts gzip-encoded responses
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		encoding, _, _ = strings.Cut(encoding, ";")
+		if strings.TrimSpace(encoding) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+// withCompression gzips responses of the configured request paths for clients accepting it
+func (s *Server) withCompression(next http.Handler) http.Handler {
+	paths := s.compressPaths()
+	if len(paths) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(paths, r.URL.Path) || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		gw := &gzipResponseWriter{ResponseWriter: w, gz: gzip.NewWriter(w)}
+		defer gw.Close()
+		next.ServeHTTP(gw, r)
+	})
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer_Compression(t *testing.T) {
+	tests := []struct {
+		name           string
+		compressPaths  []string
+		url            string
+		acceptEncoding string
+		expectGzip     bool
+	}{
+		{name: "list compressed by default", url: "/json/list", acceptEncoding: "gzip", expectGzip: true},
+		{name: "get not compressed by default", url: "/json/get?name=dev", acceptEncoding: "gzip", expectGzip: false},
+		{name: "client without gzip support", url: "/json/list", acceptEncoding: "", expectGzip: false},
+		{name: "gzip with quality value", url: "/yaml/list", acceptEncoding: "br, gzip;q=0.8", expectGzip: true},
+		{
+			name:           "configured get path",
+			compressPaths:  []string{"/json/get"},
+			url:            "/json/get?name=dev",
+			acceptEncoding: "gzip",
+			expectGzip:     true,
+		},
+		{
+			name:           "compression disabled",
+			compressPaths:  []string{},
+			url:            "/json/list",
+			acceptEncoding: "gzip",
+			expectGzip:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.CompressPaths = tt.compressPaths
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+			compressed := w.Header().Get("Content-Encoding") == "gzip"
+			if compressed != tt.expectGzip {
+				t.Fatalf("Expected gzip %v, got Content-Encoding %q", tt.expectGzip, w.Header().Get("Content-Encoding"))
+			}
+
+			body := io.Reader(w.Body)
+			if compressed {
+				gz, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("Failed to read gzip response: %v", err)
+				}
+				body = gz
+			}
+			data, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("Failed to read response: %v", err)
+			}
+			if !strings.Contains(string(data), "dev") {
+				t.Errorf("Expected response to mention the dev config, got %q", data)
+			}
+		})
+	}
+}
+
+func TestServer_Compression_NotModified(t *testing.T) {
+	server, _ := createTestServerValid(t)
+	handler := server.Handler()
+
+	req := httptest.NewRequest("GET", "/json/list", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+
+	req = httptest.NewRequest("GET", "/json/list", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("Expected status code %d, got %d", http.StatusNotModified, w.Code)
+	}
+	// No gzip stream is written for a response without a body
+	if w.Body.Len() != 0 || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected empty uncompressed body, got %d bytes with Content-Encoding %q",
+			w.Body.Len(), w.Header().Get("Content-Encoding"))
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+)
+
+const (
+	// corsAnyOrigin allows requests from any origin
+	corsAnyOrigin = "*"
+
+	// corsAllowedMethods are the methods browsers may use cross-origin, the read-only ones
+	corsAllowedMethods = "GET, HEAD"
+
+	// corsAllowedHeaders are the request headers browsers may send cross-origin
+	corsAllowedHeaders = "Authorization, If-None-Match"
+)
+
+// corsOrigin returns the Access-Control-Allow-Origin value for a request origin,
+// empty if the origin isn't allowed
+func (s *Server) corsOrigin(origin string) string {
+	if slices.Contains(s.CORSAllowedOrigins, corsAnyOrigin) {
+		return corsAnyOrigin
+	}
+	if slices.Contains(s.CORSAllowedOrigins, origin) {
+		return origin
+	}
+	return ""
+}
+
+// withCORS lets browsers on CORSAllowedOrigins call the read-only endpoints and answers
+// their preflight requests. Preflights pass before authentication since browsers send them
+// without credentials. It does nothing when CORSAllowedOrigins is empty
+func (s *Server) withCORS(next http.Handler) http.Handler {
+	if len(s.CORSAllowedOrigins) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed := s.corsOrigin(origin)
+		if allowed != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+		}
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !preflight {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if allowed == "" {
+			s.Logger.Warn("Rejected CORS preflight from disallowed origin", "origin", origin)
+			http.Error(w, "Origin not allowed", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+		w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
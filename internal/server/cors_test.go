@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_CORS(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowedOrigins []string
+		method         string
+		origin         string
+		preflight      bool
+		expectedOrigin string
+		expectedStatus int
+	}{
+		{
+			name:           "allowed origin",
+			allowedOrigins: []string{"https://dashboard.example.com"},
+			method:         "GET",
+			origin:         "https://dashboard.example.com",
+			expectedOrigin: "https://dashboard.example.com",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "disallowed origin",
+			allowedOrigins: []string{"https://dashboard.example.com"},
+			method:         "GET",
+			origin:         "https://evil.example.com",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "wildcard",
+			allowedOrigins: []string{"*"},
+			method:         "GET",
+			origin:         "https://any.example.com",
+			expectedOrigin: "*",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "disabled",
+			method:         "GET",
+			origin:         "https://dashboard.example.com",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "preflight",
+			allowedOrigins: []string{"https://dashboard.example.com"},
+			method:         "OPTIONS",
+			origin:         "https://dashboard.example.com",
+			preflight:      true,
+			expectedOrigin: "https://dashboard.example.com",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "preflight from disallowed origin",
+			allowedOrigins: []string{"https://dashboard.example.com"},
+			method:         "OPTIONS",
+			origin:         "https://evil.example.com",
+			preflight:      true,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.CORSAllowedOrigins = tt.allowedOrigins
+			// Preflights are answered without credentials
+			server.AuthToken = "secret"
+
+			req := httptest.NewRequest(tt.method, "/json/list", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Authorization", "Bearer secret")
+			if tt.preflight {
+				req.Header.Del("Authorization")
+				req.Header.Set("Access-Control-Request-Method", "GET")
+			}
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.expectedOrigin {
+				t.Errorf("Expected Access-Control-Allow-Origin %q, got %q", tt.expectedOrigin, got)
+			}
+			if tt.preflight && tt.expectedStatus == http.StatusNoContent {
+				if got := w.Header().Get("Access-Control-Allow-Methods"); got != corsAllowedMethods {
+					t.Errorf("Expected Access-Control-Allow-Methods %q, got %q", corsAllowedMethods, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+)
+
+// EntryDiff lists names of kubeconfig entries that differ between two configs
+type EntryDiff struct {
+	Added   []string `json:"added"   yaml:"added"`
+	Removed []string `json:"removed" yaml:"removed"`
+	Changed []string `json:"changed" yaml:"changed"`
+}
+
+// ConfigDiff describes how a client-provided kubeconfig differs from a served one
+type ConfigDiff struct {
+	Clusters              EntryDiff `json:"clusters"                yaml:"clusters"`
+	Contexts              EntryDiff `json:"contexts"                yaml:"contexts"`
+	Users                 EntryDiff `json:"users"                   yaml:"users"`
+	CurrentContextChanged bool      `json:"current-context-changed" yaml:"current-context-changed"`
+}
+
+// diffKubeConfigs compares a stored kubeconfig with a provided one,
+// entries present only in the provided config are reported as added
+func diffKubeConfigs(stored *KubeConfig, provided *KubeConfig) ConfigDiff {
+	return ConfigDiff{
+		Clusters: diffEntries(stored.Clusters, provided.Clusters, func(e clusterEntry) string {
+			return e.Name
+		}),
+		Contexts: diffEntries(stored.Contexts, provided.Contexts, func(e contextEntry) string {
+			return e.Name
+		}),
+		Users: diffEntries(stored.Users, provided.Users, func(e userEntry) string {
+			return e.Name
+		}),
+		CurrentContextChanged: stored.CurrentContext != provided.CurrentContext,
+	}
+}
+
+// diffEntries compares two lists of named entries
+func diffEntries[T any](stored []T, provided []T, name func(T) string) EntryDiff {
+	diff := EntryDiff{Added: []string{}, Removed: []string{}, Changed: []string{}}
+
+	storedByName := make(map[string]T, len(stored))
+	for _, entry := range stored {
+		storedByName[name(entry)] = entry
+	}
+	providedByName := make(map[string]T, len(provided))
+	for _, entry := range provided {
+		providedByName[name(entry)] = entry
+	}
+
+	for entryName, entry := range providedByName {
+		storedEntry, exists := storedByName[entryName]
+		if !exists {
+			diff.Added = append(diff.Added, entryName)
+		} else if !reflect.DeepEqual(storedEntry, entry) {
+			diff.Changed = append(diff.Changed, entryName)
+		}
+	}
+	for entryName := range storedByName {
+		if _, exists := providedByName[entryName]; !exists {
+			diff.Removed = append(diff.Removed, entryName)
+		}
+	}
+
+	slices.Sort(diff.Added)
+	slices.Sort(diff.Removed)
+	slices.Sort(diff.Changed)
+	return diff
+}
+
+// HandleDiffConfig compares a posted kubeconfig against a loaded one
+func (s *Server) HandleDiffConfig(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		s.handleHTTPError(w, nil, "Config name is required", http.StatusBadRequest)
+		return
+	}
+	stored, err := s.lookupConfig(name)
+	if err != nil {
+		s.handleError(w, err, "Failed to diff config")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUploadSize))
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	provided, err := parseKubeConfig(body)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to parse kubeconfig", http.StatusBadRequest)
+		return
+	}
+
+	s.Logger.Info("Diffing config", "name", name)
+	diff := diffKubeConfigs(stored, provided)
+
+	err = createJSONEncoder(w).Encode(diff)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to encode config diff", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+func TestServer_HandleDiffConfig(t *testing.T) {
+	server, _ := createTestServerValid(t)
+	devConfig := string(testutil.LoadTestData(t, "kubeconfigs/dev.yaml"))
+
+	t.Run("identical config has no differences", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/json/diff?name=dev", strings.NewReader(devConfig))
+		w := httptest.NewRecorder()
+		server.HandleDiffConfig(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var diff ConfigDiff
+		if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil {
+			t.Fatalf("Failed to parse diff response: %v", err)
+		}
+		for _, entries := range []EntryDiff{diff.Clusters, diff.Contexts, diff.Users} {
+			if len(entries.Added)+len(entries.Removed)+len(entries.Changed) != 0 {
+				t.Errorf("Expected no differences, got %+v", diff)
+			}
+		}
+		if diff.CurrentContextChanged {
+			t.Error("Expected current context to be unchanged")
+		}
+	})
+
+	t.Run("modified config is reported", func(t *testing.T) {
+		modified := strings.Replace(devConfig, "https://dev.example.com", "https://dev.local", 1)
+		modified = strings.ReplaceAll(modified, "dev-user", "dev-admin")
+
+		req := httptest.NewRequest("POST", "/json/diff?name=dev", strings.NewReader(modified))
+		w := httptest.NewRecorder()
+		server.HandleDiffConfig(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var diff ConfigDiff
+		if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil {
+			t.Fatalf("Failed to parse diff response: %v", err)
+		}
+		if !slices.Equal(diff.Clusters.Changed, []string{"dev-cluster"}) {
+			t.Errorf("Expected dev-cluster to be changed, got %v", diff.Clusters.Changed)
+		}
+		if !slices.Equal(diff.Contexts.Changed, []string{"dev-context"}) {
+			t.Errorf("Expected dev-context to be changed, got %v", diff.Contexts.Changed)
+		}
+		if !slices.Equal(diff.Users.Added, []string{"dev-admin"}) {
+			t.Errorf("Expected dev-admin to be added, got %v", diff.Users.Added)
+		}
+		if !slices.Equal(diff.Users.Removed, []string{"dev-user"}) {
+			t.Errorf("Expected dev-user to be removed, got %v", diff.Users.Removed)
+		}
+	})
+
+	t.Run("error cases", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			url            string
+			body           string
+			expectedStatus int
+		}{
+			{name: "missing name", url: "/json/diff", body: devConfig, expectedStatus: http.StatusBadRequest},
+			{name: "unknown name", url: "/json/diff?name=nonexistent", body: devConfig, expectedStatus: http.StatusNotFound},
+			{name: "invalid yaml", url: "/json/diff?name=dev", body: "invalid: yaml: content", expectedStatus: http.StatusBadRequest},
+			{name: "oversized body", url: "/json/diff?name=dev", body: devConfig + "# " + strings.Repeat("x", maxUploadSize), expectedStatus: http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req := httptest.NewRequest("POST", tt.url, strings.NewReader(tt.body))
+				w := httptest.NewRecorder()
+				server.HandleDiffConfig(w, req)
+
+				if w.Code != tt.expectedStatus {
+					t.Errorf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+				}
+			})
+		}
+	})
+}
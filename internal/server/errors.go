@@ -7,6 +7,15 @@ import (
 	"github.com/joomcode/errorx"
 )
 
+// Errors is the namespace of kubedepot error types
+var Errors = errorx.NewNamespace("kubedepot")
+
+// NotFound is the type of errors about requested configs or groups that don't exist
+var NotFound = Errors.NewType("not_found")
+
+// SuggestionsProperty holds names similar to a missing one, they are listed in error responses
+var SuggestionsProperty = errorx.RegisterProperty("suggestions")
+
 // ErrorType represents different types of errors
 type ErrorType int
 
@@ -19,11 +28,18 @@ const (
 // handleHTTPError logs an error and sends an HTTP error response
 func (s *Server) handleHTTPError(w http.ResponseWriter, err error, message string, statusCode int) {
 	s.Logger.Error(message, "error", err)
-	if err != nil {
-		http.Error(w, message+": "+err.Error(), statusCode)
-	} else {
+	if err == nil {
 		http.Error(w, message, statusCode)
+		return
 	}
+
+	body := message + ": " + err.Error()
+	if suggestions, ok := errorx.ExtractProperty(err, SuggestionsProperty); ok {
+		if names, _ := suggestions.([]string); len(names) > 0 {
+			body += "\nsuggestions: " + strings.Join(names, ", ")
+		}
+	}
+	http.Error(w, body, statusCode)
 }
 
 // handleError determines the appropriate HTTP status code and handles the error
@@ -44,16 +60,15 @@ func (s *Server) handleError(w http.ResponseWriter, err error, defaultMessage st
 	s.handleHTTPError(w, err, message, statusCode)
 }
 
-// getStatusCodeFromError determines the appropriate HTTP status code from an error
+// getStatusCodeFromError determines the appropriate HTTP status code from the error type:
+// missing resources are 404, bad input is 400 and anything else is a server fault
 func (s *Server) getStatusCodeFromError(err error) int {
-	if strings.Contains(err.Error(), "not found") {
+	switch {
+	case errorx.IsOfType(err, NotFound):
 		return http.StatusNotFound
-	}
-
-	if errorx.IsOfType(err, errorx.InternalError) {
+	case errorx.IsOfType(err, errorx.IllegalArgument):
+		return http.StatusBadRequest
+	default:
 		return http.StatusInternalServerError
 	}
-
-	// Default to internal server error
-	return http.StatusInternalServerError
 }
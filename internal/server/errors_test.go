@@ -0,0 +1,146 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joomcode/errorx"
+)
+
+func TestServer_getStatusCodeFromError(t *testing.T) {
+	server, _ := createTestServerValid(t)
+
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "not found", err: NotFound.New("kubeconfig not found: staging"), expected: http.StatusNotFound},
+		{
+			name:     "decorated not found",
+			err:      errorx.Decorate(NotFound.New("group not found: team"), "failed to resolve"),
+			expected: http.StatusNotFound,
+		},
+		{name: "illegal argument", err: errorx.IllegalArgument.New("bad mode"), expected: http.StatusBadRequest},
+		{name: "internal error", err: errorx.InternalError.New("broken"), expected: http.StatusInternalServerError},
+		{name: "plain error", err: errors.New("broken"), expected: http.StatusInternalServerError},
+		{
+			name:     "internal error mentioning not found",
+			err:      errorx.InternalError.New("kubeconfig has duplicate cluster name: not found"),
+			expected: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := server.getStatusCodeFromError(tt.err); got != tt.expected {
+				t.Errorf("Expected status code %d, got %d", tt.expected, got)
+			}
+
+			w := httptest.NewRecorder()
+			server.handleError(w, tt.err, "Failed")
+			if w.Code != tt.expected {
+				t.Errorf("Expected response status code %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestServer_lookupConfig_NotFound(t *testing.T) {
+	server, _ := createTestServerValid(t)
+
+	_, err := server.lookupConfig("staging")
+	if !errorx.IsOfType(err, NotFound) {
+		t.Errorf("Expected a NotFound error for a missing config, got %v", err)
+	}
+	_, err = server.expandGroups(nil, []string{"team"})
+	if !errorx.IsOfType(err, NotFound) {
+		t.Errorf("Expected a NotFound error for a missing group, got %v", err)
+	}
+}
+
+func TestServer_NotFoundSuggestions(t *testing.T) {
+	server, _ := createTestServerValid(t)
+
+	tests := []struct {
+		name                string
+		url                 string
+		expectedSuggestions string
+	}{
+		{name: "misspelled", url: "/json/get?name=prrod", expectedSuggestions: "suggestions: prod"},
+		{name: "case", url: "/json/get?name=DEV", expectedSuggestions: "suggestions: dev"},
+		{name: "unrelated", url: "/json/get?name=kubernetes-cluster"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsJson(w, req)
+
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+			}
+			body := w.Body.String()
+			if tt.expectedSuggestions == "" {
+				if strings.Contains(body, "suggestions") {
+					t.Errorf("Expected no suggestions, got %q", body)
+				}
+				return
+			}
+			if !strings.Contains(body, tt.expectedSuggestions) {
+				t.Errorf("Expected body to contain %q, got %q", tt.expectedSuggestions, body)
+			}
+		})
+	}
+}
+
+// TestServer_InternalErrorMentioningNotFound tests status codes don't depend on error wording
+func TestServer_InternalErrorMentioningNotFound(t *testing.T) {
+	configsDir := t.TempDir()
+	// Both configs define a cluster named "not found", so merging them fails with an
+	// internal error whose message contains the words
+	for _, name := range []string{"a", "b"} {
+		config := `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://` + name + `.example.com
+  name: not found
+contexts:
+- context:
+    cluster: not found
+    user: ` + name + `-user
+  name: ` + name + `-context
+users:
+- name: ` + name + `-user
+  user:
+    token: ` + name + `-token
+`
+		if err := os.WriteFile(filepath.Join(configsDir, name+".yaml"), []byte(config), 0o644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	serverConfig, _ := createTestServerRaw(t, configsDir)
+	serverConfig.SkipMergeValidation = true
+	server, err := NewServer(serverConfig)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/json/get?name=a&name=b", nil)
+	w := httptest.NewRecorder()
+	server.HandleGetKubeConfigsJson(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusInternalServerError, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "not found") {
+		t.Errorf("Expected the error to mention the cluster name, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/joomcode/errorx"
+)
+
+// GetConfigsRequest is the body of POST /get, the configs to merge
+type GetConfigsRequest struct {
+	Names []string `json:"names"`
+}
+
+// acceptedFormat returns the format of the first JSON or YAML media type of the Accept
+// header, DefaultFormat if there is none
+func (s *Server) acceptedFormat(r *http.Request) string {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json":
+			return formatJSON
+		case "application/yaml", "application/x-yaml", "text/yaml":
+			return formatYAML
+		}
+	}
+	return s.indexFormat()
+}
+
+// missingConfigs returns the names that aren't loaded configs, in the given order
+func (s *Server) missingConfigs(names []string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var missing []string
+	for _, name := range names {
+		if _, exists := s.LoadedConfigs[name]; !exists {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// HandleGetKubeConfigsPost merges the configs named in a JSON body, for selections too large
+// for query parameters. The format is chosen by the Accept header. Unknown names return 404
+// listing all of them
+func (s *Server) HandleGetKubeConfigsPost(w http.ResponseWriter, r *http.Request) {
+	var request GetConfigsRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUploadSize)).Decode(&request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.handleHTTPError(w, err, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(request.Names) == 0 {
+		s.handleHTTPError(w, errorx.IllegalArgument.New("names are required"), "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if missing := s.missingConfigs(request.Names); len(missing) > 0 {
+		s.handleError(w, NotFound.New("kubeconfigs not found: %s", strings.Join(missing, ", ")), "")
+		return
+	}
+
+	kubeConfig, _, err := s.mergeConfigs(request.Names, mergeOptions{})
+	if err != nil {
+		s.handleError(w, err, "Failed to load and merge configs")
+		return
+	}
+
+	encoder, contentType := createYAMLEncoder, "application/yaml"
+	if s.acceptedFormat(r) == formatJSON {
+		encoder, contentType = createJSONEncoder, "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	if err := s.writeEncoded(w, r, encoder, kubeConfig); err != nil {
+		s.handleHTTPError(w, err, "Failed to serialize kubeconfig", http.StatusInternalServerError)
+	}
+}
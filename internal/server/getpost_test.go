@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer_HandleGetKubeConfigsPost(t *testing.T) {
+	server, _ := createTestServerValid(t)
+	handler := server.Handler()
+
+	tests := []struct {
+		name           string
+		body           string
+		accept         string
+		expectedStatus int
+		expectedType   string
+		expectedBody   []string
+	}{
+		{
+			name:           "valid list as JSON",
+			body:           `{"names": ["dev", "prod"]}`,
+			accept:         "application/json",
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/json",
+			expectedBody:   []string{`"dev-context"`, `"prod-context"`},
+		},
+		{
+			name:           "valid list as YAML by default",
+			body:           `{"names": ["dev"]}`,
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/yaml",
+			expectedBody:   []string{"name: dev-context"},
+		},
+		{
+			name:           "unknown names",
+			body:           `{"names": ["dev", "missing", "unknown"]}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   []string{"missing, unknown"},
+		},
+		{
+			name:           "empty list",
+			body:           `{"names": []}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed body",
+			body:           `{"names": [`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/get", strings.NewReader(tt.body))
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedType != "" && w.Header().Get("Content-Type") != tt.expectedType {
+				t.Errorf("Expected content type %s, got %s", tt.expectedType, w.Header().Get("Content-Type"))
+			}
+			for _, expected := range tt.expectedBody {
+				if !strings.Contains(w.Body.String(), expected) {
+					t.Errorf("Expected body to contain %q, got %s", expected, w.Body.String())
+				}
+			}
+			if tt.expectedType == "application/json" {
+				var served KubeConfig
+				if err := json.Unmarshal(w.Body.Bytes(), &served); err != nil {
+					t.Errorf("Failed to parse JSON response: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_acceptedFormat(t *testing.T) {
+	server, _ := createTestServerValid(t)
+
+	for accept, expected := range map[string]string{
+		"":                                     formatYAML,
+		"*/*":                                  formatYAML,
+		"application/json":                     formatJSON,
+		"application/yaml":                     formatYAML,
+		"text/html, application/json;q=0.9":    formatJSON,
+		"application/x-yaml, application/json": formatYAML,
+	} {
+		req := httptest.NewRequest("POST", "/get", nil)
+		req.Header.Set("Accept", accept)
+		if got := server.acceptedFormat(req); got != expected {
+			t.Errorf("acceptedFormat(%q) = %q, want %q", accept, got, expected)
+		}
+	}
+}
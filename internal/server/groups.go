@@ -0,0 +1,147 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/joomcode/errorx"
+	"gopkg.in/yaml.v3"
+)
+
+// loadGroups loads the groups file mapping group names to lists of config names
+func (s *Server) loadGroups() error {
+	if s.GroupsFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.GroupsFile)
+	if err != nil {
+		return errorx.Decorate(err, "can't read groups file")
+	}
+	groups := map[string][]string{}
+	if err := yaml.Unmarshal(data, &groups); err != nil {
+		return errorx.Decorate(err, "can't parse groups file")
+	}
+
+	s.mu.Lock()
+	s.groups = groups
+	s.mu.Unlock()
+
+	s.Logger.Info("Loaded config groups", "file", s.GroupsFile, "count", len(groups))
+	return nil
+}
+
+// validateGroups checks that all members of all groups are loaded configs
+func (s *Server) validateGroups() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return validateGroupMembers(s.groups, s.LoadedConfigs)
+}
+
+// validateReloadedGroups checks that all members of all groups are among reloaded configs,
+// before they replace the loaded ones
+func (s *Server) validateReloadedGroups(configs map[string]*KubeConfig) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return validateGroupMembers(s.groups, configs)
+}
+
+// validateGroupMembers checks that all members of the groups are among the configs
+func validateGroupMembers(groups map[string][]string, configs map[string]*KubeConfig) error {
+	for group, members := range groups {
+		for _, member := range members {
+			if _, exists := configs[member]; !exists {
+				return errorx.IllegalArgument.New("group %s has unknown config: %s", group, member)
+			}
+		}
+	}
+	return nil
+}
+
+// isGroupsFile reports whether the path is the groups file, so it isn't loaded as a config
+func (s *Server) isGroupsFile(filePath string) bool {
+	if s.GroupsFile == "" {
+		return false
+	}
+	return filepath.Clean(filePath) == filepath.Clean(s.GroupsFile)
+}
+
+// expandGroups returns the names followed by the members of the groups,
+// names already present are not repeated
+func (s *Server) expandGroups(names []string, groups []string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expanded := slices.Clone(names)
+	for _, group := range groups {
+		members, exists := s.groups[group]
+		if !exists {
+			return nil, NotFound.New("group not found: %s", group)
+		}
+		for _, member := range members {
+			if !slices.Contains(expanded, member) {
+				expanded = append(expanded, member)
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// GroupCheck reports whether the members of a group merge cleanly, without their credentials
+type GroupCheck struct {
+	Group     string         `json:"group"`
+	Members   []string       `json:"members"`
+	Valid     bool           `json:"valid"`
+	Conflicts []MergeWarning `json:"conflicts"`
+}
+
+// checkGroup merges the members of a group the way get requests do and reports every member
+// that is missing or can't be merged, instead of stopping at the first one
+func (s *Server) checkGroup(group string) (*GroupCheck, error) {
+	members, err := s.expandGroups(nil, []string{group})
+	if err != nil {
+		return nil, err
+	}
+
+	kubeConfig, err := NewKubeConfig("", s.Logger)
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to create empty kubeconfig")
+	}
+
+	check := &GroupCheck{Group: group, Members: members, Conflicts: []MergeWarning{}}
+	for _, name := range members {
+		config, err := s.lookupConfig(name)
+		if err == nil {
+			config = s.resolveConflicts(kubeConfig, config, name)
+			var merged *KubeConfig
+			if merged, err = mergeKubeConfigs(kubeConfig, config); err == nil {
+				kubeConfig = merged
+			}
+		}
+		if err != nil {
+			check.Conflicts = append(check.Conflicts, MergeWarning{Name: name, Reason: err.Error()})
+		}
+	}
+	check.Valid = len(check.Conflicts) == 0
+	return check, nil
+}
+
+// HandleCheckGroup reports whether a group merges cleanly, so group definitions can be
+// validated in CI
+func (s *Server) HandleCheckGroup(w http.ResponseWriter, r *http.Request) {
+	group := r.PathValue("group")
+	check, err := s.checkGroup(group)
+	if err != nil {
+		s.handleError(w, err, "Failed to check group")
+		return
+	}
+
+	s.Logger.Info("Checked group", "group", group, "valid", check.Valid, "conflicts", len(check.Conflicts))
+	err = createJSONEncoder(w).Encode(check)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to encode group check", http.StatusInternalServerError)
+		return
+	}
+}
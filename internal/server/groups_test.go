@@ -0,0 +1,243 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+// createGroupsTestServer creates a server over valid configs with the given groups file content
+func createGroupsTestServer(t *testing.T, groups string) (*Server, error) {
+	groupsFile := filepath.Join(t.TempDir(), "groups.yaml")
+	if err := os.WriteFile(groupsFile, []byte(groups), 0o644); err != nil {
+		t.Fatalf("Failed to write groups file: %v", err)
+	}
+	serverConfig, _ := createTestServerRaw(t, testutil.GetValidKubeConfigsDir(t))
+	serverConfig.GroupsFile = groupsFile
+	return NewServer(serverConfig)
+}
+
+func TestServer_Groups(t *testing.T) {
+	server, err := createGroupsTestServer(t, `
+team-payments:
+  - dev
+  - prod
+team-integration:
+  - integration-dev
+  - integration-prod
+`)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		url              string
+		expectedStatus   int
+		expectedContexts []string
+	}{
+		{
+			name:             "group",
+			url:              "/json/get?group=team-payments",
+			expectedStatus:   http.StatusOK,
+			expectedContexts: []string{"dev-context", "prod-context"},
+		},
+		{
+			name:             "group with name",
+			url:              "/json/get?group=team-integration&name=valid-test",
+			expectedStatus:   http.StatusOK,
+			expectedContexts: []string{"test-context", "integration-dev-context", "integration-prod-context"},
+		},
+		{
+			name:             "name already in group",
+			url:              "/json/get?name=dev&group=team-payments",
+			expectedStatus:   http.StatusOK,
+			expectedContexts: []string{"dev-context", "prod-context"},
+		},
+		{
+			name:           "unknown group",
+			url:            "/json/get?group=team-unknown",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsJson(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var kubeConfig KubeConfig
+			if err := json.Unmarshal(w.Body.Bytes(), &kubeConfig); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			var contexts []string
+			for _, context := range kubeConfig.Contexts {
+				contexts = append(contexts, context.Name)
+			}
+			if !slices.Equal(contexts, tt.expectedContexts) {
+				t.Errorf("Expected contexts %v, got %v", tt.expectedContexts, contexts)
+			}
+		})
+	}
+}
+
+func TestNewServer_GroupsValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  string
+		wantErr bool
+	}{
+		{name: "known members", groups: "team: [dev, prod]", wantErr: false},
+		{name: "unknown member", groups: "team: [dev, staging]", wantErr: true},
+		{name: "invalid file", groups: "team: [dev", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := createGroupsTestServer(t, tt.groups)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestServer_GroupsFileInConfigsDir(t *testing.T) {
+	server := createUploadTestServer(t)
+	server.GroupsFile = filepath.Join(server.ConfigsDir, "groups.yaml")
+	if err := os.WriteFile(server.GroupsFile, []byte("team: [dev]"), 0o644); err != nil {
+		t.Fatalf("Failed to write groups file: %v", err)
+	}
+
+	server, err := NewServer(server)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+	if names := listConfigNames(t, server); !slices.Equal(names, []string{"dev"}) {
+		t.Errorf("Expected groups file not to be loaded as a config, got %v", names)
+	}
+}
+
+func TestServer_CheckGroup(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{
+		"dev.yaml":      "dev.yaml",
+		"dev-copy.yaml": "dev.yaml",
+		"prod.yaml":     "prod.yaml",
+	})
+	groupsFile := filepath.Join(t.TempDir(), "groups.yaml")
+	groups := "clean: [dev, prod]\nconflicting: [dev, dev-copy, prod]\n"
+	if err := os.WriteFile(groupsFile, []byte(groups), 0o644); err != nil {
+		t.Fatalf("Failed to write groups file: %v", err)
+	}
+	serverConfig, _ := createTestServerRaw(t, configsDir)
+	serverConfig.GroupsFile = groupsFile
+	// dev-copy conflicts with dev, so the configs can't all be merged together
+	serverConfig.SkipMergeValidation = true
+	server, err := NewServer(serverConfig)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	tests := []struct {
+		name              string
+		group             string
+		expectedStatus    int
+		expectedValid     bool
+		expectedConflicts []string
+	}{
+		{name: "clean group", group: "clean", expectedStatus: http.StatusOK, expectedValid: true},
+		{
+			name:              "conflicting group",
+			group:             "conflicting",
+			expectedStatus:    http.StatusOK,
+			expectedConflicts: []string{"dev-copy"},
+		},
+		{name: "unknown group", group: "unknown", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/json/get/group/"+tt.group+"/check", nil)
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			if strings.Contains(w.Body.String(), "dev-token") {
+				t.Errorf("Expected group check not to return credentials, got %s", w.Body.String())
+			}
+
+			var check GroupCheck
+			if err := json.Unmarshal(w.Body.Bytes(), &check); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if check.Valid != tt.expectedValid {
+				t.Errorf("Expected valid %v, got %v", tt.expectedValid, check.Valid)
+			}
+			var conflicts []string
+			for _, conflict := range check.Conflicts {
+				conflicts = append(conflicts, conflict.Name)
+			}
+			if !slices.Equal(conflicts, tt.expectedConflicts) {
+				t.Errorf("Expected conflicts %v, got %v", tt.expectedConflicts, check.Conflicts)
+			}
+		})
+	}
+}
+
+func TestServer_ReloadValidatesGroups(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml", "prod.yaml": "prod.yaml"})
+	groupsFile := filepath.Join(t.TempDir(), "groups.yaml")
+	if err := os.WriteFile(groupsFile, []byte("team: [dev, prod]"), 0o644); err != nil {
+		t.Fatalf("Failed to write groups file: %v", err)
+	}
+	serverConfig, _ := createTestServerRaw(t, configsDir)
+	serverConfig.GroupsFile = groupsFile
+	server, err := NewServer(serverConfig)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(configsDir, "prod.yaml")); err != nil {
+		t.Fatalf("Failed to remove config: %v", err)
+	}
+
+	for name, reload := range map[string]func() (int, error){
+		"reload":         server.Reload,
+		"reload changed": server.reloadChanged,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := reload(); err == nil || !strings.Contains(err.Error(), "group team has unknown config: prod") {
+				t.Fatalf("Expected reload to fail on the group member, got %v", err)
+			}
+
+			req := httptest.NewRequest("GET", "/json/get?group=team", nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsJson(w, req)
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected group to keep being served, got status code %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
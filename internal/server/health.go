@@ -0,0 +1,24 @@
+package server
+
+import "net/http"
+
+// HandlePing returns an empty 200 response for uptime monitors,
+// it never touches configs and doesn't log to stay as cheap as possible
+func (s *Server) HandlePing(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleHealthz is the liveness probe, it always returns 200 with body ok
+func (s *Server) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte("ok"))
+}
+
+// HandleReadyz is the readiness probe, it returns 200 once configs are loaded and
+// validated and 503 while they aren't or the last reload failed
+func (s *Server) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	if !s.ready.Load() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok"))
+}
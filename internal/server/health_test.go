@@ -0,0 +1,132 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+func TestServer_HandlePing(t *testing.T) {
+	server, _ := createTestServerValid(t)
+
+	req := httptest.NewRequest("GET", "/ping", nil)
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected empty body, got %q", w.Body.String())
+	}
+}
+
+func TestServer_HandleHealthz(t *testing.T) {
+	server, _ := createTestServerRaw(t, "")
+
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	w := httptest.NewRecorder()
+	server.HandleHealthz(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if w.Body.String() != "ok" {
+		t.Errorf("Expected body ok, got %q", w.Body.String())
+	}
+}
+
+func TestServer_HandleReadyz(t *testing.T) {
+	readyz := func(server *Server) int {
+		req := httptest.NewRequest("GET", "/readyz", nil)
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("not ready before configs are loaded", func(t *testing.T) {
+		server, _ := createTestServerRaw(t, "")
+		if code := readyz(server); code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, code)
+		}
+	})
+
+	t.Run("ready after startup", func(t *testing.T) {
+		server, _ := createTestServerValid(t)
+		if code := readyz(server); code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d", http.StatusOK, code)
+		}
+	})
+
+	t.Run("not ready after failed reload", func(t *testing.T) {
+		server := createUploadTestServer(t)
+		testutil.CopyTestKubeConfigs(t, server.ConfigsDir, map[string]string{"dev-copy.yaml": "dev.yaml"})
+		if _, err := server.Reload(); err == nil {
+			t.Fatal("Expected reload of conflicting configs to fail")
+		}
+		if code := readyz(server); code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, code)
+		}
+
+		if err := os.Remove(filepath.Join(server.ConfigsDir, "dev-copy.yaml")); err != nil {
+			t.Fatalf("Failed to remove config: %v", err)
+		}
+		if _, err := server.Reload(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if code := readyz(server); code != http.StatusOK {
+			t.Errorf("Expected status code %d after successful reload, got %d", http.StatusOK, code)
+		}
+	})
+}
+
+func TestServer_HandleReadyz_AsyncLoad(t *testing.T) {
+	interval := asyncLoadRetryInterval
+	asyncLoadRetryInterval = 10 * time.Millisecond
+	t.Cleanup(func() { asyncLoadRetryInterval = interval })
+
+	// The configs directory shows up only after the server is created, like a slow source
+	configsDir := filepath.Join(t.TempDir(), "configs")
+	serverConfig, _ := createTestServerRaw(t, configsDir)
+	serverConfig.AsyncLoad = true
+	server, err := NewServer(serverConfig)
+	if err != nil {
+		t.Fatalf("Expected server to start without configs in async mode: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go server.loadUntilReady(stop)
+
+	readyz := func() int {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+		return w.Code
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if code := readyz(); code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status code %d before configs are loaded, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	if err := os.Mkdir(configsDir, 0o755); err != nil {
+		t.Fatalf("Failed to create configs directory: %v", err)
+	}
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for readyz() != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected server to become ready once configs are loaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if names := listConfigNames(t, server); len(names) != 1 || names[0] != "dev" {
+		t.Errorf("Expected dev config to be served, got %v", names)
+	}
+}
@@ -1,7 +1,17 @@
 package server
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/joomcode/errorx"
@@ -13,29 +23,118 @@ const (
 	kubeConfigKind       = "Config"
 )
 
+// Strategies of handling names that conflict between merged configs
+const (
+	MergeConflictError  = "error"  // Fail the merge
+	MergeConflictRename = "rename" // Prefix conflicting names with the config name
+)
+
 // KubeConfig represents a kubeconfig file
 type KubeConfig struct {
-	ApiVersion string `yaml:"apiVersion"      json:"apiVersion"`
-	Kind       string `yaml:"kind"            json:"kind"`
-	Clusters   []struct {
-		Cluster struct {
-			CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-			Server                   string `yaml:"server" json:"server"`
-		} `yaml:"cluster" json:"cluster"`
-		Name string `yaml:"name" json:"name"`
-	} `yaml:"clusters"        json:"clusters"`
-	Contexts []struct {
-		Context struct {
-			Cluster string `yaml:"cluster" json:"cluster"`
-			User    string `yaml:"user" json:"user"`
-		} `yaml:"context" json:"context"`
-		Name string `yaml:"name" json:"name"`
-	} `yaml:"contexts"        json:"contexts"`
-	CurrentContext string `yaml:"current-context" json:"current-context"`
-	Users          []struct {
-		User any    `yaml:"user" json:"user"`
-		Name string `yaml:"name" json:"name"`
-	} `yaml:"users"           json:"users"`
+	ApiVersion     string         `yaml:"apiVersion"      json:"apiVersion"`
+	Kind           string         `yaml:"kind"            json:"kind"`
+	Clusters       []clusterEntry `yaml:"clusters"        json:"clusters"`
+	Contexts       []contextEntry `yaml:"contexts"        json:"contexts"`
+	CurrentContext string         `yaml:"current-context" json:"current-context"`
+	Users          []userEntry    `yaml:"users"           json:"users"`
+	// Extra holds unknown top-level fields like preferences and extensions so they are served back
+	Extra map[string]any `yaml:",inline" json:"-"`
+}
+
+// kubeConfigFields is KubeConfig without its JSON methods
+type kubeConfigFields KubeConfig
+
+// MarshalJSON encodes the kubeconfig together with its extra top-level fields
+func (k KubeConfig) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(kubeConfigFields(k))
+	if err != nil || len(k.Extra) == 0 {
+		return data, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range k.Extra {
+		if _, known := fields[key]; known {
+			continue
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, errorx.Decorate(err, "can't encode kubeconfig field: %s", key)
+		}
+		fields[key] = raw
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON decodes the kubeconfig keeping unknown top-level fields in Extra
+func (k *KubeConfig) UnmarshalJSON(data []byte) error {
+	var fields kubeConfigFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	fields.Extra = nil
+
+	var all map[string]any
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	known, err := json.Marshal(kubeConfigFields{})
+	if err != nil {
+		return err
+	}
+	var knownKeys map[string]any
+	if err := json.Unmarshal(known, &knownKeys); err != nil {
+		return err
+	}
+	for key, value := range all {
+		if _, exists := knownKeys[key]; exists {
+			continue
+		}
+		if fields.Extra == nil {
+			fields.Extra = map[string]any{}
+		}
+		fields.Extra[key] = value
+	}
+
+	*k = KubeConfig(fields)
+	return nil
+}
+
+// clusterEntry is a named cluster of a kubeconfig
+type clusterEntry struct {
+	Cluster clusterInfo `yaml:"cluster" json:"cluster"`
+	Name    string      `yaml:"name" json:"name"`
+}
+
+// clusterInfo holds the connection settings of a cluster
+type clusterInfo struct {
+	CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
+	CertificateAuthority     string `yaml:"certificate-authority,omitempty" json:"certificate-authority,omitempty"`
+	Server                   string `yaml:"server" json:"server"`
+	InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify,omitempty" json:"insecure-skip-tls-verify,omitempty"`
+	TLSServerName            string `yaml:"tls-server-name,omitempty" json:"tls-server-name,omitempty"`
+	ProxyURL                 string `yaml:"proxy-url,omitempty" json:"proxy-url,omitempty"`
+}
+
+// contextEntry is a named context of a kubeconfig
+type contextEntry struct {
+	Context contextInfo `yaml:"context" json:"context"`
+	Name    string      `yaml:"name" json:"name"`
+}
+
+// contextInfo holds the cluster and user a context refers to and its default namespace
+type contextInfo struct {
+	Cluster   string `yaml:"cluster" json:"cluster"`
+	User      string `yaml:"user" json:"user"`
+	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+}
+
+// userEntry is a named user of a kubeconfig
+type userEntry struct {
+	User any    `yaml:"user" json:"user"`
+	Name string `yaml:"name" json:"name"`
 }
 
 // NewKubeConfig creates a new KubeConfig with default values
@@ -43,13 +142,10 @@ func NewKubeConfig(filePath string, logger *log.Logger) (*KubeConfig, error) {
 	kubeConfig := &KubeConfig{}
 
 	if filePath != "" {
-		data, err := os.ReadFile(filePath)
-		if err != nil {
-			return nil, errorx.Decorate(err, "can't read kubeconfig file")
-		}
-		err = yaml.Unmarshal(data, &kubeConfig)
+		var err error
+		kubeConfig, _, err = readKubeConfigFile(filePath)
 		if err != nil {
-			return nil, errorx.Decorate(err, "can't parse kubeconfig file")
+			return nil, err
 		}
 	} else {
 		logger.Debug("No kubeconfig file provided, using empty kubeconfig")
@@ -58,55 +154,191 @@ func NewKubeConfig(filePath string, logger *log.Logger) (*KubeConfig, error) {
 	return kubeConfig, nil
 }
 
+// readKubeConfigFile reads and parses a kubeconfig file, its original content is returned too
+func readKubeConfigFile(filePath string) (*KubeConfig, []byte, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, errorx.Decorate(err, "can't read kubeconfig file")
+	}
+	kubeConfig, err := parseKubeConfigFile(filePath, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return kubeConfig, data, nil
+}
+
+// parseKubeConfigFile parses the content of a kubeconfig file, as JSON for .json files
+func parseKubeConfigFile(filePath string, data []byte) (*KubeConfig, error) {
+	parse := parseKubeConfig
+	if strings.EqualFold(filepath.Ext(filePath), ".json") {
+		parse = parseKubeConfigJSON
+	}
+	kubeConfig, err := parse(data)
+	if err != nil {
+		return nil, errorx.Decorate(err, "can't parse kubeconfig file")
+	}
+	return kubeConfig, nil
+}
+
+// inlineCertificateAuthorities replaces certificate-authority file references of clusters
+// with the file content as certificate-authority-data, so the config is self-contained.
+// Relative paths are resolved against baseDir, the directory of the config file like kubectl
+// does. A reference next to existing data is dropped
+func (k *KubeConfig) inlineCertificateAuthorities(baseDir string) error {
+	for i := range k.Clusters {
+		cluster := &k.Clusters[i].Cluster
+		if cluster.CertificateAuthority == "" {
+			continue
+		}
+		if cluster.CertificateAuthorityData == "" {
+			path := cluster.CertificateAuthority
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(baseDir, path)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return errorx.Decorate(err, "can't read certificate authority of cluster %s", k.Clusters[i].Name)
+			}
+			cluster.CertificateAuthorityData = base64.StdEncoding.EncodeToString(data)
+		}
+		cluster.CertificateAuthority = ""
+	}
+	return nil
+}
+
+// parseKubeConfig parses kubeconfig YAML (or JSON) data
+func parseKubeConfig(data []byte) (*KubeConfig, error) {
+	kubeConfig := &KubeConfig{}
+	if err := yaml.Unmarshal(data, kubeConfig); err != nil {
+		return nil, err
+	}
+	return kubeConfig, nil
+}
+
+// parseKubeConfigJSON parses kubeconfig JSON data, e.g. exported by tools emitting JSON.
+// Unlike the YAML parser it accepts everything JSON allows, such as tab indentation
+func parseKubeConfigJSON(data []byte) (*KubeConfig, error) {
+	kubeConfig := &KubeConfig{}
+	if err := json.Unmarshal(data, kubeConfig); err != nil {
+		return nil, err
+	}
+	return kubeConfig, nil
+}
+
+// normalizeKubeConfig re-serializes a kubeconfig trimming trailing whitespace of all
+// string values, so served output doesn't depend on the source formatting. Leading
+// whitespace is kept, it can only come from a quoted value and may be intended
+func normalizeKubeConfig(k *KubeConfig) (*KubeConfig, error) {
+	var node yaml.Node
+	if err := node.Encode(k); err != nil {
+		return nil, errorx.Decorate(err, "can't encode kubeconfig")
+	}
+	trimStringNodes(&node)
+
+	normalized := &KubeConfig{}
+	if err := node.Decode(normalized); err != nil {
+		return nil, errorx.Decorate(err, "can't decode normalized kubeconfig")
+	}
+	return normalized, nil
+}
+
+// trimStringNodes trims trailing whitespace of string scalars in a YAML node tree
+func trimStringNodes(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+		node.Value = strings.TrimRight(node.Value, " \t\r\n")
+	}
+	for _, child := range node.Content {
+		trimStringNodes(child)
+	}
+}
+
 // Validate checks if the kubeconfig has required fields
 func (k *KubeConfig) Validate() error {
+	if errs := k.missingEntries(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// missingEntries returns an error for each kind of entry the kubeconfig has none of
+func (k *KubeConfig) missingEntries() []error {
+	var errs []error
 	if len(k.Clusters) == 0 {
-		return errorx.InternalError.New("kubeconfig has no clusters")
+		errs = append(errs, errorx.InternalError.New("kubeconfig has no clusters"))
 	}
 	if len(k.Contexts) == 0 {
-		return errorx.InternalError.New("kubeconfig has no contexts")
+		errs = append(errs, errorx.InternalError.New("kubeconfig has no contexts"))
 	}
 	if len(k.Users) == 0 {
-		return errorx.InternalError.New("kubeconfig has no users")
+		errs = append(errs, errorx.InternalError.New("kubeconfig has no users"))
 	}
-	return nil
+	return errs
 }
 
-// HasDuplicateNames checks if this config has duplicate names with another config
+// HasDuplicateNames checks if any cluster, context, or user name of another config
+// is already present in this config or repeated within the other config
 func (k *KubeConfig) HasDuplicateNames(other *KubeConfig) error {
 	// Check cluster name duplicates
-	if len(k.Clusters) > 0 && len(other.Clusters) > 0 &&
-		other.Clusters[0].Name == k.Clusters[0].Name {
-		return errorx.InternalError.New("kubeconfig has duplicate cluster name")
+	clusterNames := make(map[string]bool, len(k.Clusters)+len(other.Clusters))
+	for _, cluster := range k.Clusters {
+		clusterNames[cluster.Name] = true
+	}
+	for _, cluster := range other.Clusters {
+		if clusterNames[cluster.Name] {
+			return errorx.InternalError.New("kubeconfig has duplicate cluster name: %s", cluster.Name)
+		}
+		clusterNames[cluster.Name] = true
 	}
 
 	// Check context name duplicates
-	if len(k.Contexts) > 0 && len(other.Contexts) > 0 &&
-		other.Contexts[0].Name == k.Contexts[0].Name {
-		return errorx.InternalError.New("kubeconfig has duplicate context name")
+	contextNames := make(map[string]bool, len(k.Contexts)+len(other.Contexts))
+	for _, context := range k.Contexts {
+		contextNames[context.Name] = true
+	}
+	for _, context := range other.Contexts {
+		if contextNames[context.Name] {
+			return errorx.InternalError.New("kubeconfig has duplicate context name: %s", context.Name)
+		}
+		contextNames[context.Name] = true
 	}
 
 	// Check user name duplicates
-	if len(k.Users) > 0 && len(other.Users) > 0 &&
-		other.Users[0].Name == k.Users[0].Name {
-		return errorx.InternalError.New("kubeconfig has duplicate user name")
+	userNames := make(map[string]bool, len(k.Users)+len(other.Users))
+	for _, user := range k.Users {
+		userNames[user.Name] = true
+	}
+	for _, user := range other.Users {
+		if userNames[user.Name] {
+			return errorx.InternalError.New("kubeconfig has duplicate user name: %s", user.Name)
+		}
+		userNames[user.Name] = true
 	}
 
 	return nil
 }
 
-// HasMultipleEntries checks if the config has more than one cluster, context, or user
+// HasMultipleEntries checks if the config has more than one cluster, context, or user.
+// Merging supports such configs, this is for callers that want single-entry configs only
 func (k *KubeConfig) HasMultipleEntries() error {
+	if errs := k.multipleEntries(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// multipleEntries returns an error for each kind of entry the kubeconfig has more than one of
+func (k *KubeConfig) multipleEntries() []error {
+	var errs []error
 	if len(k.Clusters) > 1 {
-		return errorx.InternalError.New("kubeconfig has more than one cluster")
+		errs = append(errs, errorx.InternalError.New("kubeconfig has more than one cluster"))
 	}
 	if len(k.Contexts) > 1 {
-		return errorx.InternalError.New("kubeconfig has more than one context")
+		errs = append(errs, errorx.InternalError.New("kubeconfig has more than one context"))
 	}
 	if len(k.Users) > 1 {
-		return errorx.InternalError.New("kubeconfig has more than one user")
+		errs = append(errs, errorx.InternalError.New("kubeconfig has more than one user"))
 	}
-	return nil
+	return errs
 }
 
 // mergeKubeConfigs merges two kubeconfigs into a new one
@@ -121,20 +353,24 @@ func mergeKubeConfigs(config1 *KubeConfig, config2 *KubeConfig) (*KubeConfig, er
 		return nil, err
 	}
 
-	// Check for duplicates
+	// Check for duplicates against all accumulated entries
 	if err := config1.HasDuplicateNames(config2); err != nil {
 		return nil, err
 	}
 
-	// Check for multiple entries in config2
-	if err := config2.HasMultipleEntries(); err != nil {
-		return nil, err
-	}
+	// Merge the configs, config2 may bundle several entries of each kind
+	merged.Clusters = append(slices.Clone(config1.Clusters), config2.Clusters...)
+	merged.Contexts = append(slices.Clone(config1.Contexts), config2.Contexts...)
+	merged.Users = append(slices.Clone(config1.Users), config2.Users...)
 
-	// Merge the configs
-	merged.Clusters = append(config1.Clusters, config2.Clusters...)
-	merged.Contexts = append(config1.Contexts, config2.Contexts...)
-	merged.Users = append(config1.Users, config2.Users...)
+	// Extra top-level fields of later configs win
+	if len(config1.Extra) > 0 || len(config2.Extra) > 0 {
+		merged.Extra = maps.Clone(config1.Extra)
+		if merged.Extra == nil {
+			merged.Extra = make(map[string]any, len(config2.Extra))
+		}
+		maps.Copy(merged.Extra, config2.Extra)
+	}
 
 	// Set current context: use first config's current context always
 	if config1.CurrentContext == "" {
@@ -145,3 +381,195 @@ func mergeKubeConfigs(config1 *KubeConfig, config2 *KubeConfig) (*KubeConfig, er
 
 	return merged, nil
 }
+
+// renameConflicts returns a copy of other where cluster, context and user names already
+// present in k are prefixed with prefix, e.g. prod-user. The names that were renamed are
+// returned as well
+func (k *KubeConfig) renameConflicts(other *KubeConfig, prefix string) (*KubeConfig, []string) {
+	return other.withPrefixedNames(prefix, k.hasCluster, k.hasContext, k.hasUser)
+}
+
+// prefixAllNames returns a copy of k where all cluster, context and user names are prefixed
+// with prefix, names that already start with it are kept as is
+func (k *KubeConfig) prefixAllNames(prefix string) *KubeConfig {
+	unprefixed := func(name string) bool { return !strings.HasPrefix(name, prefix+"-") }
+	prefixed, _ := k.withPrefixedNames(prefix, unprefixed, unprefixed, unprefixed)
+	return prefixed
+}
+
+// withPrefixedNames returns a copy of k where the cluster, context and user names selected
+// by the given functions are prefixed with prefix. References of the contexts and the current
+// context are rewritten to match, so the copy stays valid. The names that were prefixed are
+// returned as well
+func (k *KubeConfig) withPrefixedNames(
+	prefix string,
+	clusterSelected, contextSelected, userSelected func(string) bool,
+) (*KubeConfig, []string) {
+	var renamed []string
+	rename := func(name string, selected func(string) bool, names map[string]string) string {
+		if !selected(name) {
+			return name
+		}
+		newName := prefix + "-" + name
+		names[name] = newName
+		renamed = append(renamed, name)
+		return newName
+	}
+
+	clusters := make(map[string]string)
+	contexts := make(map[string]string)
+	users := make(map[string]string)
+
+	result := *k
+	result.Clusters = slices.Clone(k.Clusters)
+	for i := range result.Clusters {
+		result.Clusters[i].Name = rename(result.Clusters[i].Name, clusterSelected, clusters)
+	}
+	result.Users = slices.Clone(k.Users)
+	for i := range result.Users {
+		result.Users[i].Name = rename(result.Users[i].Name, userSelected, users)
+	}
+	result.Contexts = slices.Clone(k.Contexts)
+	for i := range result.Contexts {
+		context := &result.Contexts[i]
+		context.Name = rename(context.Name, contextSelected, contexts)
+		if newName, ok := clusters[context.Context.Cluster]; ok {
+			context.Context.Cluster = newName
+		}
+		if newName, ok := users[context.Context.User]; ok {
+			context.Context.User = newName
+		}
+	}
+	if newName, ok := contexts[k.CurrentContext]; ok {
+		result.CurrentContext = newName
+	}
+	return &result, renamed
+}
+
+// hasCluster reports whether the kubeconfig has a cluster with the given name
+func (k *KubeConfig) hasCluster(name string) bool {
+	for _, cluster := range k.Clusters {
+		if cluster.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasUser reports whether the kubeconfig has a user with the given name
+func (k *KubeConfig) hasUser(name string) bool {
+	for _, user := range k.Users {
+		if user.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// overrideServer points the only cluster of the kubeconfig at server, it fails when there
+// are several clusters since it would be ambiguous which one to change
+func (k *KubeConfig) overrideServer(server string) error {
+	if len(k.Clusters) != 1 {
+		return errorx.IllegalArgument.New(
+			"server can only be overridden for a single cluster, got %d clusters", len(k.Clusters))
+	}
+	k.Clusters[0].Cluster.Server = server
+	return nil
+}
+
+// clearInsecureSkipTLSVerify turns off insecure-skip-tls-verify of all clusters
+// and returns the names of the clusters that had it on
+func (k *KubeConfig) clearInsecureSkipTLSVerify() []string {
+	var cleared []string
+	for i := range k.Clusters {
+		if k.Clusters[i].Cluster.InsecureSkipTLSVerify {
+			k.Clusters[i].Cluster.InsecureSkipTLSVerify = false
+			cleared = append(cleared, k.Clusters[i].Name)
+		}
+	}
+	return cleared
+}
+
+// serverURLProblems returns a problem for each cluster whose server URL is malformed, not
+// https or points at a loopback address, usually a copy-paste mistake
+func (k *KubeConfig) serverURLProblems() []error {
+	var problems []error
+	for _, cluster := range k.Clusters {
+		server := cluster.Cluster.Server
+		parsed, err := url.Parse(server)
+		switch {
+		case err != nil || parsed.Host == "":
+			problems = append(problems, errorx.IllegalArgument.New(
+				"cluster %s has a malformed server URL: %q", cluster.Name, server))
+		case parsed.Scheme != "https":
+			problems = append(problems, errorx.IllegalArgument.New(
+				"cluster %s has a non-https server URL: %s", cluster.Name, server))
+		case isLoopbackHost(parsed.Hostname()):
+			problems = append(problems, errorx.IllegalArgument.New(
+				"cluster %s has a loopback server URL: %s", cluster.Name, server))
+		}
+	}
+	return problems
+}
+
+// serverHostLookupTimeout bounds the DNS lookup of each cluster server host
+const serverHostLookupTimeout = 2 * time.Second
+
+// lookupHost resolves a host name, replaced in tests to avoid depending on DNS
+var lookupHost = net.DefaultResolver.LookupHost
+
+// unresolvableServerHosts returns a problem for each cluster whose server host name doesn't
+// resolve within serverHostLookupTimeout. IP addresses and malformed URLs, already reported
+// by serverURLProblems, aren't looked up
+func (k *KubeConfig) unresolvableServerHosts() []error {
+	var problems []error
+	for _, cluster := range k.Clusters {
+		parsed, err := url.Parse(cluster.Cluster.Server)
+		if err != nil || parsed.Hostname() == "" || net.ParseIP(parsed.Hostname()) != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), serverHostLookupTimeout)
+		_, err = lookupHost(ctx, parsed.Hostname())
+		cancel()
+		if err != nil {
+			problems = append(problems, errorx.IllegalArgument.New(
+				"cluster %s has an unresolvable server host: %s", cluster.Name, parsed.Hostname()))
+		}
+	}
+	return problems
+}
+
+// isLoopbackHost reports whether the host is localhost or a loopback IP address
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// hasContext checks if the kubeconfig has a context with the given name
+func (k *KubeConfig) hasContext(name string) bool {
+	for _, context := range k.Contexts {
+		if context.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveCurrentContext sets the current context of a merged kubeconfig so it always points
+// to one of its contexts. Precedence: defaultContext if present, then the current context
+// already set by the merge, then the first context
+func (k *KubeConfig) resolveCurrentContext(defaultContext string) {
+	switch {
+	case defaultContext != "" && k.hasContext(defaultContext):
+		k.CurrentContext = defaultContext
+	case k.CurrentContext != "" && k.hasContext(k.CurrentContext):
+		// Keep the current context chosen by the merge
+	case len(k.Contexts) > 0:
+		k.CurrentContext = k.Contexts[0].Name
+	default:
+		k.CurrentContext = ""
+	}
+}
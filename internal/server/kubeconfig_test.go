@@ -1,12 +1,16 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/charmbracelet/log"
 	"github.com/joomcode/errorx"
+	"gopkg.in/yaml.v3"
 )
 
 func TestNewKubeConfig(t *testing.T) {
@@ -165,58 +169,25 @@ func TestMergeKubeConfigs(t *testing.T) {
 			config1: &KubeConfig{
 				ApiVersion: "v1",
 				Kind:       "Config",
-				Clusters: []struct {
-					Cluster struct {
-						CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-						Server                   string `yaml:"server" json:"server"`
-					} `yaml:"cluster" json:"cluster"`
-					Name string `yaml:"name" json:"name"`
-				}{},
-				Contexts: []struct {
-					Context struct {
-						Cluster string `yaml:"cluster" json:"cluster"`
-						User    string `yaml:"user" json:"user"`
-					} `yaml:"context" json:"context"`
-					Name string `yaml:"name" json:"name"`
-				}{},
-				Users: []struct {
-					User any    `yaml:"user" json:"user"`
-					Name string `yaml:"name" json:"name"`
-				}{},
+				Clusters:   []clusterEntry{},
+				Contexts:   []contextEntry{},
+				Users:      []userEntry{},
 			},
 			config2: &KubeConfig{
 				ApiVersion: "v1",
 				Kind:       "Config",
-				Clusters: []struct {
-					Cluster struct {
-						CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-						Server                   string `yaml:"server" json:"server"`
-					} `yaml:"cluster" json:"cluster"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Clusters: []clusterEntry{
 					{
-						Cluster: struct {
-							CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-							Server                   string `yaml:"server" json:"server"`
-						}{
+						Cluster: clusterInfo{
 							CertificateAuthorityData: "dGVzdA==",
 							Server:                   "https://test.example.com",
 						},
 						Name: "test-cluster",
 					},
 				},
-				Contexts: []struct {
-					Context struct {
-						Cluster string `yaml:"cluster" json:"cluster"`
-						User    string `yaml:"user" json:"user"`
-					} `yaml:"context" json:"context"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Contexts: []contextEntry{
 					{
-						Context: struct {
-							Cluster string `yaml:"cluster" json:"cluster"`
-							User    string `yaml:"user" json:"user"`
-						}{
+						Context: contextInfo{
 							Cluster: "test-cluster",
 							User:    "test-user",
 						},
@@ -224,10 +195,7 @@ func TestMergeKubeConfigs(t *testing.T) {
 					},
 				},
 				CurrentContext: "test-context",
-				Users: []struct {
-					User any    `yaml:"user" json:"user"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Users: []userEntry{
 					{
 						Name: "test-user",
 						User: map[string]interface{}{"token": "test-token"},
@@ -261,24 +229,9 @@ func TestMergeKubeConfigs(t *testing.T) {
 			name:    "config2 has no clusters",
 			config1: &KubeConfig{},
 			config2: &KubeConfig{
-				Clusters: []struct {
-					Cluster struct {
-						CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-						Server                   string `yaml:"server" json:"server"`
-					} `yaml:"cluster" json:"cluster"`
-					Name string `yaml:"name" json:"name"`
-				}{},
-				Contexts: []struct {
-					Context struct {
-						Cluster string `yaml:"cluster" json:"cluster"`
-						User    string `yaml:"user" json:"user"`
-					} `yaml:"context" json:"context"`
-					Name string `yaml:"name" json:"name"`
-				}{},
-				Users: []struct {
-					User any    `yaml:"user" json:"user"`
-					Name string `yaml:"name" json:"name"`
-				}{},
+				Clusters: []clusterEntry{},
+				Contexts: []contextEntry{},
+				Users:    []userEntry{},
 			},
 			wantErr:  true,
 			validate: func(t *testing.T, merged *KubeConfig) {},
@@ -287,26 +240,11 @@ func TestMergeKubeConfigs(t *testing.T) {
 			name:    "config2 has no contexts",
 			config1: &KubeConfig{},
 			config2: &KubeConfig{
-				Clusters: []struct {
-					Cluster struct {
-						CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-						Server                   string `yaml:"server" json:"server"`
-					} `yaml:"cluster" json:"cluster"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Clusters: []clusterEntry{
 					{Name: "test-cluster"},
 				},
-				Contexts: []struct {
-					Context struct {
-						Cluster string `yaml:"cluster" json:"cluster"`
-						User    string `yaml:"user" json:"user"`
-					} `yaml:"context" json:"context"`
-					Name string `yaml:"name" json:"name"`
-				}{},
-				Users: []struct {
-					User any    `yaml:"user" json:"user"`
-					Name string `yaml:"name" json:"name"`
-				}{},
+				Contexts: []contextEntry{},
+				Users:    []userEntry{},
 			},
 			wantErr:  true,
 			validate: func(t *testing.T, merged *KubeConfig) {},
@@ -315,28 +253,13 @@ func TestMergeKubeConfigs(t *testing.T) {
 			name:    "config2 has no users",
 			config1: &KubeConfig{},
 			config2: &KubeConfig{
-				Clusters: []struct {
-					Cluster struct {
-						CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-						Server                   string `yaml:"server" json:"server"`
-					} `yaml:"cluster" json:"cluster"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Clusters: []clusterEntry{
 					{Name: "test-cluster"},
 				},
-				Contexts: []struct {
-					Context struct {
-						Cluster string `yaml:"cluster" json:"cluster"`
-						User    string `yaml:"user" json:"user"`
-					} `yaml:"context" json:"context"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Contexts: []contextEntry{
 					{Name: "test-context"},
 				},
-				Users: []struct {
-					User any    `yaml:"user" json:"user"`
-					Name string `yaml:"name" json:"name"`
-				}{},
+				Users: []userEntry{},
 			},
 			wantErr:  true,
 			validate: func(t *testing.T, merged *KubeConfig) {},
@@ -344,39 +267,18 @@ func TestMergeKubeConfigs(t *testing.T) {
 		{
 			name: "duplicate cluster names",
 			config1: &KubeConfig{
-				Clusters: []struct {
-					Cluster struct {
-						CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-						Server                   string `yaml:"server" json:"server"`
-					} `yaml:"cluster" json:"cluster"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Clusters: []clusterEntry{
 					{Name: "duplicate-cluster"},
 				},
 			},
 			config2: &KubeConfig{
-				Clusters: []struct {
-					Cluster struct {
-						CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-						Server                   string `yaml:"server" json:"server"`
-					} `yaml:"cluster" json:"cluster"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Clusters: []clusterEntry{
 					{Name: "duplicate-cluster"},
 				},
-				Contexts: []struct {
-					Context struct {
-						Cluster string `yaml:"cluster" json:"cluster"`
-						User    string `yaml:"user" json:"user"`
-					} `yaml:"context" json:"context"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Contexts: []contextEntry{
 					{Name: "test-context"},
 				},
-				Users: []struct {
-					User any    `yaml:"user" json:"user"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Users: []userEntry{
 					{Name: "test-user"},
 				},
 			},
@@ -387,34 +289,23 @@ func TestMergeKubeConfigs(t *testing.T) {
 			name:    "multiple clusters in config2",
 			config1: &KubeConfig{},
 			config2: &KubeConfig{
-				Clusters: []struct {
-					Cluster struct {
-						CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-						Server                   string `yaml:"server" json:"server"`
-					} `yaml:"cluster" json:"cluster"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Clusters: []clusterEntry{
 					{Name: "cluster1"},
 					{Name: "cluster2"},
 				},
-				Contexts: []struct {
-					Context struct {
-						Cluster string `yaml:"cluster" json:"cluster"`
-						User    string `yaml:"user" json:"user"`
-					} `yaml:"context" json:"context"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Contexts: []contextEntry{
 					{Name: "test-context"},
 				},
-				Users: []struct {
-					User any    `yaml:"user" json:"user"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Users: []userEntry{
 					{Name: "test-user"},
 				},
 			},
-			wantErr:  true,
-			validate: func(t *testing.T, merged *KubeConfig) {},
+			wantErr: false,
+			validate: func(t *testing.T, merged *KubeConfig) {
+				if len(merged.Clusters) != 2 {
+					t.Errorf("Expected 2 clusters, got %d", len(merged.Clusters))
+				}
+			},
 		},
 	}
 
@@ -469,50 +360,20 @@ func TestMergeKubeConfigs_CurrentContext(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			config1 := &KubeConfig{
 				CurrentContext: tt.config1CurrentCtx,
-				Clusters: []struct {
-					Cluster struct {
-						CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-						Server                   string `yaml:"server" json:"server"`
-					} `yaml:"cluster" json:"cluster"`
-					Name string `yaml:"name" json:"name"`
-				}{},
-				Contexts: []struct {
-					Context struct {
-						Cluster string `yaml:"cluster" json:"cluster"`
-						User    string `yaml:"user" json:"user"`
-					} `yaml:"context" json:"context"`
-					Name string `yaml:"name" json:"name"`
-				}{},
-				Users: []struct {
-					User any    `yaml:"user" json:"user"`
-					Name string `yaml:"name" json:"name"`
-				}{},
+				Clusters:       []clusterEntry{},
+				Contexts:       []contextEntry{},
+				Users:          []userEntry{},
 			}
 
 			config2 := &KubeConfig{
 				CurrentContext: tt.config2CurrentCtx,
-				Clusters: []struct {
-					Cluster struct {
-						CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-						Server                   string `yaml:"server" json:"server"`
-					} `yaml:"cluster" json:"cluster"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Clusters: []clusterEntry{
 					{Name: "test-cluster"},
 				},
-				Contexts: []struct {
-					Context struct {
-						Cluster string `yaml:"cluster" json:"cluster"`
-						User    string `yaml:"user" json:"user"`
-					} `yaml:"context" json:"context"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Contexts: []contextEntry{
 					{Name: "test-context"},
 				},
-				Users: []struct {
-					User any    `yaml:"user" json:"user"`
-					Name string `yaml:"name" json:"name"`
-				}{
+				Users: []userEntry{
 					{Name: "test-user"},
 				},
 			}
@@ -530,58 +391,125 @@ func TestMergeKubeConfigs_CurrentContext(t *testing.T) {
 	}
 }
 
+func TestKubeConfig_serverURLProblems(t *testing.T) {
+	tests := []struct {
+		server  string
+		problem string
+	}{
+		{server: "https://dev.example.com", problem: ""},
+		{server: "https://dev.example.com:6443/prefix", problem: ""},
+		{server: "http://dev.example.com", problem: "non-https"},
+		{server: "https://localhost:6443", problem: "loopback"},
+		{server: "https://127.0.0.1:6443", problem: "loopback"},
+		{server: "https://[::1]:6443", problem: "loopback"},
+		{server: "https://dev example com", problem: "malformed"},
+		{server: "dev.example.com", problem: "malformed"},
+		{server: "", problem: "malformed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.server, func(t *testing.T) {
+			kubeConfig := &KubeConfig{Clusters: []clusterEntry{
+				{Name: "dev-cluster", Cluster: clusterInfo{Server: tt.server}},
+			}}
+
+			problems := kubeConfig.serverURLProblems()
+			if tt.problem == "" {
+				if len(problems) != 0 {
+					t.Errorf("Expected no problems, got %v", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || !strings.Contains(problems[0].Error(), tt.problem) {
+				t.Errorf("Expected a %s problem, got %v", tt.problem, problems)
+			}
+		})
+	}
+}
+
+// TestKubeConfig_resolveCurrentContext tests current context precedence of merged configs
+func TestKubeConfig_resolveCurrentContext(t *testing.T) {
+	newMerged := func(currentContext string, contexts ...string) *KubeConfig {
+		kubeConfig := &KubeConfig{CurrentContext: currentContext}
+		for _, name := range contexts {
+			kubeConfig.Contexts = append(kubeConfig.Contexts, contextEntry{Name: name})
+		}
+		return kubeConfig
+	}
+
+	tests := []struct {
+		name           string
+		merged         *KubeConfig
+		defaultContext string
+		expected       string
+	}{
+		{
+			name:           "configured default wins",
+			merged:         newMerged("dev-context", "dev-context", "prod-context"),
+			defaultContext: "prod-context",
+			expected:       "prod-context",
+		},
+		{
+			name:           "missing configured default is ignored",
+			merged:         newMerged("dev-context", "dev-context", "prod-context"),
+			defaultContext: "pp-dev",
+			expected:       "dev-context",
+		},
+		{
+			name:     "merged current context kept without default",
+			merged:   newMerged("prod-context", "dev-context", "prod-context"),
+			expected: "prod-context",
+		},
+		{
+			name:     "dangling current context falls back to first context",
+			merged:   newMerged("pp-dev", "dev-context", "prod-context"),
+			expected: "dev-context",
+		},
+		{
+			name:     "empty current context falls back to first context",
+			merged:   newMerged("", "dev-context", "prod-context"),
+			expected: "dev-context",
+		},
+		{
+			name:     "no contexts",
+			merged:   newMerged("pp-dev"),
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.merged.resolveCurrentContext(tt.defaultContext)
+
+			if tt.merged.CurrentContext != tt.expected {
+				t.Errorf("Expected current context %q, got %q", tt.expected, tt.merged.CurrentContext)
+			}
+		})
+	}
+}
+
 // TestMergeKubeConfigs_DuplicateContexts tests duplicate context name detection
 func TestMergeKubeConfigs_DuplicateContexts(t *testing.T) {
 	config1 := &KubeConfig{
-		Clusters: []struct {
-			Cluster struct {
-				CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-				Server                   string `yaml:"server" json:"server"`
-			} `yaml:"cluster" json:"cluster"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Clusters: []clusterEntry{
 			{Name: "cluster1"},
 		},
-		Contexts: []struct {
-			Context struct {
-				Cluster string `yaml:"cluster" json:"cluster"`
-				User    string `yaml:"user" json:"user"`
-			} `yaml:"context" json:"context"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Contexts: []contextEntry{
 			{Name: "duplicate-context"},
 		},
-		Users: []struct {
-			User any    `yaml:"user" json:"user"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Users: []userEntry{
 			{Name: "user1"},
 		},
 	}
 
 	config2 := &KubeConfig{
-		Clusters: []struct {
-			Cluster struct {
-				CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-				Server                   string `yaml:"server" json:"server"`
-			} `yaml:"cluster" json:"cluster"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Clusters: []clusterEntry{
 			{Name: "cluster2"},
 		},
-		Contexts: []struct {
-			Context struct {
-				Cluster string `yaml:"cluster" json:"cluster"`
-				User    string `yaml:"user" json:"user"`
-			} `yaml:"context" json:"context"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Contexts: []contextEntry{
 			{Name: "duplicate-context"}, // Same name as config1
 		},
-		Users: []struct {
-			User any    `yaml:"user" json:"user"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Users: []userEntry{
 			{Name: "user2"},
 		},
 	}
@@ -598,55 +526,25 @@ func TestMergeKubeConfigs_DuplicateContexts(t *testing.T) {
 // TestMergeKubeConfigs_DuplicateUsers tests duplicate user name detection
 func TestMergeKubeConfigs_DuplicateUsers(t *testing.T) {
 	config1 := &KubeConfig{
-		Clusters: []struct {
-			Cluster struct {
-				CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-				Server                   string `yaml:"server" json:"server"`
-			} `yaml:"cluster" json:"cluster"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Clusters: []clusterEntry{
 			{Name: "cluster1"},
 		},
-		Contexts: []struct {
-			Context struct {
-				Cluster string `yaml:"cluster" json:"cluster"`
-				User    string `yaml:"user" json:"user"`
-			} `yaml:"context" json:"context"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Contexts: []contextEntry{
 			{Name: "context1"},
 		},
-		Users: []struct {
-			User any    `yaml:"user" json:"user"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Users: []userEntry{
 			{Name: "duplicate-user"},
 		},
 	}
 
 	config2 := &KubeConfig{
-		Clusters: []struct {
-			Cluster struct {
-				CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-				Server                   string `yaml:"server" json:"server"`
-			} `yaml:"cluster" json:"cluster"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Clusters: []clusterEntry{
 			{Name: "cluster2"},
 		},
-		Contexts: []struct {
-			Context struct {
-				Cluster string `yaml:"cluster" json:"cluster"`
-				User    string `yaml:"user" json:"user"`
-			} `yaml:"context" json:"context"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Contexts: []contextEntry{
 			{Name: "context2"},
 		},
-		Users: []struct {
-			User any    `yaml:"user" json:"user"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Users: []userEntry{
 			{Name: "duplicate-user"}, // Same name as config1
 		},
 	}
@@ -660,83 +558,108 @@ func TestMergeKubeConfigs_DuplicateUsers(t *testing.T) {
 	}
 }
 
-// TestMergeKubeConfigs_MultipleContexts tests multiple contexts in config2
+// TestMergeKubeConfigs_DuplicateBeyondFirstEntry tests that a collision with any accumulated
+// entry is detected, not only with the first one
+func TestMergeKubeConfigs_DuplicateBeyondFirstEntry(t *testing.T) {
+	newConfig := func(cluster, context, user string) *KubeConfig {
+		return &KubeConfig{
+			Clusters: []clusterEntry{{Name: cluster}},
+			Contexts: []contextEntry{{Name: context}},
+			Users:    []userEntry{{Name: user}},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		third         *KubeConfig
+		expectedError string
+	}{
+		{
+			name:          "cluster collides with first config",
+			third:         newConfig("first-cluster", "third-context", "third-user"),
+			expectedError: "duplicate cluster name: first-cluster",
+		},
+		{
+			name:          "context collides with first config",
+			third:         newConfig("third-cluster", "first-context", "third-user"),
+			expectedError: "duplicate context name: first-context",
+		},
+		{
+			name:          "user collides with first config",
+			third:         newConfig("third-cluster", "third-context", "first-user"),
+			expectedError: "duplicate user name: first-user",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := mergeKubeConfigs(&KubeConfig{}, newConfig("first-cluster", "first-context", "first-user"))
+			if err != nil {
+				t.Fatalf("Unexpected error merging first config: %v", err)
+			}
+			merged, err = mergeKubeConfigs(merged, newConfig("second-cluster", "second-context", "second-user"))
+			if err != nil {
+				t.Fatalf("Unexpected error merging second config: %v", err)
+			}
+
+			_, err = mergeKubeConfigs(merged, tt.third)
+			if err == nil {
+				t.Fatal("Expected duplicate name error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("Expected error containing %q, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+// TestMergeKubeConfigs_MultipleContexts tests merging multiple contexts in config2
 func TestMergeKubeConfigs_MultipleContexts(t *testing.T) {
 	config1 := &KubeConfig{}
 	config2 := &KubeConfig{
-		Clusters: []struct {
-			Cluster struct {
-				CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-				Server                   string `yaml:"server" json:"server"`
-			} `yaml:"cluster" json:"cluster"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Clusters: []clusterEntry{
 			{Name: "test-cluster"},
 		},
-		Contexts: []struct {
-			Context struct {
-				Cluster string `yaml:"cluster" json:"cluster"`
-				User    string `yaml:"user" json:"user"`
-			} `yaml:"context" json:"context"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Contexts: []contextEntry{
 			{Name: "context1"},
 			{Name: "context2"}, // Multiple contexts
 		},
-		Users: []struct {
-			User any    `yaml:"user" json:"user"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Users: []userEntry{
 			{Name: "test-user"},
 		},
 	}
 
-	_, err := mergeKubeConfigs(config1, config2)
-	if err == nil {
-		t.Error("Expected error for multiple contexts in config2, got nil")
+	merged, err := mergeKubeConfigs(config1, config2)
+	if err != nil {
+		t.Fatalf("Unexpected error for multiple contexts in config2: %v", err)
 	}
-	if !errorx.IsOfType(err, errorx.InternalError) {
-		t.Errorf("Expected InternalError, got %T", err)
+	if len(merged.Contexts) != 2 {
+		t.Errorf("Expected 2 contexts, got %d", len(merged.Contexts))
 	}
 }
 
-// TestMergeKubeConfigs_MultipleUsers tests multiple users in config2
+// TestMergeKubeConfigs_MultipleUsers tests merging multiple users in config2
 func TestMergeKubeConfigs_MultipleUsers(t *testing.T) {
 	config1 := &KubeConfig{}
 	config2 := &KubeConfig{
-		Clusters: []struct {
-			Cluster struct {
-				CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-				Server                   string `yaml:"server" json:"server"`
-			} `yaml:"cluster" json:"cluster"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Clusters: []clusterEntry{
 			{Name: "test-cluster"},
 		},
-		Contexts: []struct {
-			Context struct {
-				Cluster string `yaml:"cluster" json:"cluster"`
-				User    string `yaml:"user" json:"user"`
-			} `yaml:"context" json:"context"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Contexts: []contextEntry{
 			{Name: "test-context"},
 		},
-		Users: []struct {
-			User any    `yaml:"user" json:"user"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Users: []userEntry{
 			{Name: "user1"},
 			{Name: "user2"}, // Multiple users
 		},
 	}
 
-	_, err := mergeKubeConfigs(config1, config2)
-	if err == nil {
-		t.Error("Expected error for multiple users in config2, got nil")
+	merged, err := mergeKubeConfigs(config1, config2)
+	if err != nil {
+		t.Fatalf("Unexpected error for multiple users in config2: %v", err)
 	}
-	if !errorx.IsOfType(err, errorx.InternalError) {
-		t.Errorf("Expected InternalError, got %T", err)
+	if len(merged.Users) != 2 {
+		t.Errorf("Expected 2 users, got %d", len(merged.Users))
 	}
 }
 
@@ -745,36 +668,18 @@ func TestMergeKubeConfigs_SuccessfulMerge(t *testing.T) {
 	config1 := &KubeConfig{
 		ApiVersion: "v1",
 		Kind:       "Config",
-		Clusters: []struct {
-			Cluster struct {
-				CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-				Server                   string `yaml:"server" json:"server"`
-			} `yaml:"cluster" json:"cluster"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Clusters: []clusterEntry{
 			{
-				Cluster: struct {
-					CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-					Server                   string `yaml:"server" json:"server"`
-				}{
+				Cluster: clusterInfo{
 					CertificateAuthorityData: "Y29uZmlnMQ==",
 					Server:                   "https://config1.example.com",
 				},
 				Name: "config1-cluster",
 			},
 		},
-		Contexts: []struct {
-			Context struct {
-				Cluster string `yaml:"cluster" json:"cluster"`
-				User    string `yaml:"user" json:"user"`
-			} `yaml:"context" json:"context"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Contexts: []contextEntry{
 			{
-				Context: struct {
-					Cluster string `yaml:"cluster" json:"cluster"`
-					User    string `yaml:"user" json:"user"`
-				}{
+				Context: contextInfo{
 					Cluster: "config1-cluster",
 					User:    "config1-user",
 				},
@@ -782,10 +687,7 @@ func TestMergeKubeConfigs_SuccessfulMerge(t *testing.T) {
 			},
 		},
 		CurrentContext: "config1-context",
-		Users: []struct {
-			User any    `yaml:"user" json:"user"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Users: []userEntry{
 			{
 				Name: "config1-user",
 				User: map[string]interface{}{"token": "config1-token"},
@@ -796,36 +698,18 @@ func TestMergeKubeConfigs_SuccessfulMerge(t *testing.T) {
 	config2 := &KubeConfig{
 		ApiVersion: "v1",
 		Kind:       "Config",
-		Clusters: []struct {
-			Cluster struct {
-				CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-				Server                   string `yaml:"server" json:"server"`
-			} `yaml:"cluster" json:"cluster"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Clusters: []clusterEntry{
 			{
-				Cluster: struct {
-					CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-					Server                   string `yaml:"server" json:"server"`
-				}{
+				Cluster: clusterInfo{
 					CertificateAuthorityData: "Y29uZmlnMg==",
 					Server:                   "https://config2.example.com",
 				},
 				Name: "config2-cluster",
 			},
 		},
-		Contexts: []struct {
-			Context struct {
-				Cluster string `yaml:"cluster" json:"cluster"`
-				User    string `yaml:"user" json:"user"`
-			} `yaml:"context" json:"context"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Contexts: []contextEntry{
 			{
-				Context: struct {
-					Cluster string `yaml:"cluster" json:"cluster"`
-					User    string `yaml:"user" json:"user"`
-				}{
+				Context: contextInfo{
 					Cluster: "config2-cluster",
 					User:    "config2-user",
 				},
@@ -833,10 +717,7 @@ func TestMergeKubeConfigs_SuccessfulMerge(t *testing.T) {
 			},
 		},
 		CurrentContext: "config2-context",
-		Users: []struct {
-			User any    `yaml:"user" json:"user"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Users: []userEntry{
 			{
 				Name: "config2-user",
 				User: map[string]interface{}{"token": "config2-token"},
@@ -1055,36 +936,18 @@ func BenchmarkMergeKubeConfigs(b *testing.B) {
 	config1 := &KubeConfig{
 		ApiVersion: "v1",
 		Kind:       "Config",
-		Clusters: []struct {
-			Cluster struct {
-				CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-				Server                   string `yaml:"server" json:"server"`
-			} `yaml:"cluster" json:"cluster"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Clusters: []clusterEntry{
 			{
-				Cluster: struct {
-					CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-					Server                   string `yaml:"server" json:"server"`
-				}{
+				Cluster: clusterInfo{
 					CertificateAuthorityData: "Y29uZmlnMQ==",
 					Server:                   "https://config1.example.com",
 				},
 				Name: "config1-cluster",
 			},
 		},
-		Contexts: []struct {
-			Context struct {
-				Cluster string `yaml:"cluster" json:"cluster"`
-				User    string `yaml:"user" json:"user"`
-			} `yaml:"context" json:"context"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Contexts: []contextEntry{
 			{
-				Context: struct {
-					Cluster string `yaml:"cluster" json:"cluster"`
-					User    string `yaml:"user" json:"user"`
-				}{
+				Context: contextInfo{
 					Cluster: "config1-cluster",
 					User:    "config1-user",
 				},
@@ -1092,10 +955,7 @@ func BenchmarkMergeKubeConfigs(b *testing.B) {
 			},
 		},
 		CurrentContext: "config1-context",
-		Users: []struct {
-			User any    `yaml:"user" json:"user"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Users: []userEntry{
 			{
 				Name: "config1-user",
 				User: map[string]interface{}{"token": "config1-token"},
@@ -1106,36 +966,18 @@ func BenchmarkMergeKubeConfigs(b *testing.B) {
 	config2 := &KubeConfig{
 		ApiVersion: "v1",
 		Kind:       "Config",
-		Clusters: []struct {
-			Cluster struct {
-				CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-				Server                   string `yaml:"server" json:"server"`
-			} `yaml:"cluster" json:"cluster"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Clusters: []clusterEntry{
 			{
-				Cluster: struct {
-					CertificateAuthorityData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
-					Server                   string `yaml:"server" json:"server"`
-				}{
+				Cluster: clusterInfo{
 					CertificateAuthorityData: "Y29uZmlnMg==",
 					Server:                   "https://config2.example.com",
 				},
 				Name: "config2-cluster",
 			},
 		},
-		Contexts: []struct {
-			Context struct {
-				Cluster string `yaml:"cluster" json:"cluster"`
-				User    string `yaml:"user" json:"user"`
-			} `yaml:"context" json:"context"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Contexts: []contextEntry{
 			{
-				Context: struct {
-					Cluster string `yaml:"cluster" json:"cluster"`
-					User    string `yaml:"user" json:"user"`
-				}{
+				Context: contextInfo{
 					Cluster: "config2-cluster",
 					User:    "config2-user",
 				},
@@ -1143,10 +985,7 @@ func BenchmarkMergeKubeConfigs(b *testing.B) {
 			},
 		},
 		CurrentContext: "config2-context",
-		Users: []struct {
-			User any    `yaml:"user" json:"user"`
-			Name string `yaml:"name" json:"name"`
-		}{
+		Users: []userEntry{
 			{
 				Name: "config2-user",
 				User: map[string]interface{}{"token": "config2-token"},
@@ -1162,3 +1001,143 @@ func BenchmarkMergeKubeConfigs(b *testing.B) {
 		}
 	}
 }
+
+// extraFieldsKubeConfig has top-level fields KubeConfig doesn't model
+const extraFieldsKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+  - cluster:
+      certificate-authority-data: dGVzdA==
+      server: https://gke.example.com
+    name: gke-cluster
+contexts:
+  - context:
+      cluster: gke-cluster
+      user: gke-user
+    name: gke-context
+current-context: gke-context
+users:
+  - user:
+      token: gke-token
+    name: gke-user
+extensions:
+  - extension:
+      last-update: "2024-01-01"
+      provider: gke
+    name: gke-metadata
+preferences:
+  colors: true
+`
+
+// TestKubeConfig_ExtraFieldsRoundTrip tests unknown top-level fields are served back unchanged
+func TestKubeConfig_ExtraFieldsRoundTrip(t *testing.T) {
+	kubeConfig, err := parseKubeConfig([]byte(extraFieldsKubeConfig))
+	if err != nil {
+		t.Fatalf("Failed to parse kubeconfig: %v", err)
+	}
+
+	t.Run("yaml", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := createYAMLEncoder(&buf).Encode(kubeConfig); err != nil {
+			t.Fatalf("Failed to encode kubeconfig: %v", err)
+		}
+		if buf.String() != extraFieldsKubeConfig {
+			t.Errorf("Expected kubeconfig to round-trip unchanged, got:\n%s", buf.String())
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(kubeConfig)
+		if err != nil {
+			t.Fatalf("Failed to encode kubeconfig: %v", err)
+		}
+		var decoded KubeConfig
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to decode kubeconfig: %v", err)
+		}
+		preferences, ok := decoded.Extra["preferences"].(map[string]any)
+		if !ok || preferences["colors"] != true || decoded.Extra["extensions"] == nil {
+			t.Errorf("Expected extra fields to round-trip, got %v", decoded.Extra)
+		}
+		if decoded.CurrentContext != "gke-context" || len(decoded.Clusters) != 1 {
+			t.Errorf("Expected known fields to round-trip, got %+v", decoded)
+		}
+	})
+}
+
+// TestMergeKubeConfigs_ExtraFields tests extra fields of later configs win when merging
+func TestMergeKubeConfigs_ExtraFields(t *testing.T) {
+	config1 := &KubeConfig{}
+	if err := yaml.Unmarshal([]byte(extraFieldsKubeConfig), config1); err != nil {
+		t.Fatalf("Failed to parse kubeconfig: %v", err)
+	}
+	config2 := &KubeConfig{
+		Clusters: []clusterEntry{{Name: "eks-cluster"}},
+		Contexts: []contextEntry{{Name: "eks-context"}},
+		Users:    []userEntry{{Name: "eks-user"}},
+		Extra:    map[string]any{"preferences": map[string]any{"colors": false}},
+	}
+
+	merged, err := mergeKubeConfigs(config1, config2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if merged.Extra["extensions"] == nil {
+		t.Error("Expected extensions of the first config to be kept")
+	}
+	if preferences := merged.Extra["preferences"].(map[string]any); preferences["colors"] != false {
+		t.Errorf("Expected preferences of the last config to win, got %v", preferences)
+	}
+	if config1.Extra["preferences"].(map[string]any)["colors"] != true {
+		t.Error("Expected merging not to modify the first config")
+	}
+}
+
+// TestKubeConfig_renameConflicts tests only conflicting names are prefixed and references follow them
+func TestKubeConfig_renameConflicts(t *testing.T) {
+	existing := &KubeConfig{
+		Clusters: []clusterEntry{{Name: "shared-cluster"}},
+		Contexts: []contextEntry{{Name: "main"}},
+		Users:    []userEntry{{Name: "admin"}},
+	}
+	other := &KubeConfig{
+		Clusters: []clusterEntry{{Name: "shared-cluster"}, {Name: "prod-only"}},
+		Contexts: []contextEntry{
+			{Name: "main", Context: contextInfo{Cluster: "shared-cluster", User: "admin"}},
+			{Name: "secondary", Context: contextInfo{Cluster: "prod-only", User: "admin"}},
+		},
+		CurrentContext: "main",
+		Users:          []userEntry{{Name: "admin"}},
+	}
+
+	renamed, names := existing.renameConflicts(other, "prod")
+
+	if len(names) != 3 {
+		t.Errorf("Expected 3 renamed entries, got %v", names)
+	}
+	if renamed.Clusters[0].Name != "prod-shared-cluster" || renamed.Clusters[1].Name != "prod-only" {
+		t.Errorf("Expected only the conflicting cluster renamed, got %+v", renamed.Clusters)
+	}
+	if renamed.Users[0].Name != "prod-admin" {
+		t.Errorf("Expected conflicting user renamed, got %+v", renamed.Users)
+	}
+	expected := []contextEntry{
+		{Name: "prod-main", Context: contextInfo{Cluster: "prod-shared-cluster", User: "prod-admin"}},
+		{Name: "secondary", Context: contextInfo{Cluster: "prod-only", User: "prod-admin"}},
+	}
+	for i := range expected {
+		if renamed.Contexts[i] != expected[i] {
+			t.Errorf("Expected context %+v, got %+v", expected[i], renamed.Contexts[i])
+		}
+	}
+	if renamed.CurrentContext != "prod-main" {
+		t.Errorf("Expected current context prod-main, got %s", renamed.CurrentContext)
+	}
+	if other.Clusters[0].Name != "shared-cluster" || other.Contexts[0].Context.User != "admin" {
+		t.Error("Expected the original config to be left unchanged")
+	}
+
+	if _, err := mergeKubeConfigs(existing, renamed); err != nil {
+		t.Errorf("Expected renamed config to merge: %v", err)
+	}
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	"gopkg.in/yaml.v3"
+)
+
+// labelsFileSuffix is the suffix of companion files holding labels of a config,
+// e.g. dev.labels.yaml holds labels of dev.yaml
+const labelsFileSuffix = ".labels"
+
+// labelsCommentPrefix starts a comment in the leading comments of a config file holding its
+// labels, e.g. "# labels: env=prod,team=payments"
+const labelsCommentPrefix = "labels:"
+
+// ConfigMeta holds metadata of a loaded config
+type ConfigMeta struct {
+	Labels map[string]string `yaml:"labels" json:"labels"`
+	Path   string            `yaml:"-"      json:"-"` // File the config was loaded from
+}
+
+// selectorOperator is an operator of a label selector requirement
+type selectorOperator string
+
+const (
+	selectorEquals       selectorOperator = "="
+	selectorNotEquals    selectorOperator = "!="
+	selectorIn           selectorOperator = "in"
+	selectorNotIn        selectorOperator = "notin"
+	selectorExists       selectorOperator = "exists"
+	selectorDoesNotExist selectorOperator = "!"
+)
+
+// selectorRequirement is a single requirement of a label selector
+type selectorRequirement struct {
+	key      string
+	operator selectorOperator
+	values   []string
+}
+
+// labelSelector is a parsed label selector, all requirements must match
+type labelSelector []selectorRequirement
+
+// isLabelsFile reports whether the file holds labels of another config
+func isLabelsFile(fileName string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(fileName, filepath.Ext(fileName)), labelsFileSuffix)
+}
+
+// loadConfigLabels loads labels from the companion labels file of a config file if it exists
+func loadConfigLabels(filePath string) (map[string]string, error) {
+	base := strings.TrimSuffix(filePath, filepath.Ext(filePath))
+	for _, ext := range []string{".yaml", ".yml"} {
+		data, err := os.ReadFile(base + labelsFileSuffix + ext)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errorx.Decorate(err, "can't read labels file")
+		}
+		labels := map[string]string{}
+		if err := yaml.Unmarshal(data, &labels); err != nil {
+			return nil, errorx.Decorate(err, "can't parse labels file")
+		}
+		return labels, nil
+	}
+	return nil, nil
+}
+
+// parseLabelsComment parses labels from a "# labels:" comment among the leading comments of
+// a config file, nil is returned if there is none
+func parseLabelsComment(data []byte) (map[string]string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		comment, isComment := strings.CutPrefix(line, "#")
+		if !isComment {
+			return nil, nil
+		}
+		list, found := strings.CutPrefix(strings.TrimSpace(comment), labelsCommentPrefix)
+		if !found {
+			continue
+		}
+
+		labels := map[string]string{}
+		for _, pair := range strings.Split(list, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			key, value, found := strings.Cut(pair, "=")
+			key = strings.TrimSpace(key)
+			if !found || key == "" {
+				return nil, errorx.IllegalArgument.New("invalid label %q, expected key=value", pair)
+			}
+			labels[key] = strings.TrimSpace(value)
+		}
+		return labels, nil
+	}
+	return nil, nil
+}
+
+// mergeLabels merges labels of a config file comment with its companion labels file,
+// labels of the companion file win
+func mergeLabels(commentLabels, fileLabels map[string]string) map[string]string {
+	if commentLabels == nil {
+		return fileLabels
+	}
+	for key, value := range fileLabels {
+		commentLabels[key] = value
+	}
+	return commentLabels
+}
+
+// requestSelector builds a label selector from the selector query parameter and every label
+// query parameter, e.g. label=env=prod. The second value reports whether any was given
+func requestSelector(r *http.Request) (labelSelector, bool, error) {
+	query := r.URL.Query()
+	exprs := slices.Clone(query["label"])
+	if expr := query.Get("selector"); expr != "" {
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 0 {
+		return nil, false, nil
+	}
+
+	var selector labelSelector
+	for _, expr := range exprs {
+		parsed, err := parseLabelSelector(expr)
+		if err != nil {
+			return nil, false, err
+		}
+		selector = append(selector, parsed...)
+	}
+	return selector, true, nil
+}
+
+// parseLabelSelector parses a selector like "env in (prod,staging),team!=legacy",
+// supported operators are =, ==, !=, in, notin, key existence and !key
+func parseLabelSelector(expr string) (labelSelector, error) {
+	var selector labelSelector
+	for _, part := range splitSelector(expr) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		requirement, err := parseSelectorRequirement(part)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, requirement)
+	}
+	return selector, nil
+}
+
+// splitSelector splits a selector by commas that are not inside parentheses
+func splitSelector(expr string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range expr {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, expr[start:])
+}
+
+// parseSelectorRequirement parses a single selector requirement
+func parseSelectorRequirement(part string) (selectorRequirement, error) {
+	if key, value, found := strings.Cut(part, "!="); found {
+		return newSelectorRequirement(key, selectorNotEquals, value)
+	}
+	if key, value, found := strings.Cut(part, "=="); found {
+		return newSelectorRequirement(key, selectorEquals, value)
+	}
+	if key, value, found := strings.Cut(part, "="); found {
+		return newSelectorRequirement(key, selectorEquals, value)
+	}
+
+	fields := strings.Fields(part)
+	if len(fields) >= 2 && (fields[1] == string(selectorIn) || fields[1] == string(selectorNotIn)) {
+		set := strings.TrimSpace(strings.Join(fields[2:], " "))
+		if !strings.HasPrefix(set, "(") || !strings.HasSuffix(set, ")") {
+			return selectorRequirement{}, errorx.IllegalArgument.New(
+				"invalid label selector %q: values must be in parentheses", part)
+		}
+		values := strings.Split(strings.Trim(set, "()"), ",")
+		for i := range values {
+			values[i] = strings.TrimSpace(values[i])
+		}
+		return newSelectorRequirement(fields[0], selectorOperator(fields[1]), values...)
+	}
+
+	if len(fields) == 1 {
+		if key, found := strings.CutPrefix(fields[0], "!"); found {
+			return newSelectorRequirement(key, selectorDoesNotExist)
+		}
+		return newSelectorRequirement(fields[0], selectorExists)
+	}
+
+	return selectorRequirement{}, errorx.IllegalArgument.New("invalid label selector %q", part)
+}
+
+// newSelectorRequirement creates a selector requirement validating its key
+func newSelectorRequirement(
+	key string,
+	operator selectorOperator,
+	values ...string,
+) (selectorRequirement, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return selectorRequirement{}, errorx.IllegalArgument.New("label selector key can't be empty")
+	}
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return selectorRequirement{key: key, operator: operator, values: values}, nil
+}
+
+// Matches reports whether the labels satisfy all selector requirements
+func (ls labelSelector) Matches(labels map[string]string) bool {
+	for _, requirement := range ls {
+		value, exists := labels[requirement.key]
+		switch requirement.operator {
+		case selectorEquals:
+			if !exists || value != requirement.values[0] {
+				return false
+			}
+		case selectorNotEquals:
+			if exists && value == requirement.values[0] {
+				return false
+			}
+		case selectorIn:
+			if !exists || !slices.Contains(requirement.values, value) {
+				return false
+			}
+		case selectorNotIn:
+			if exists && slices.Contains(requirement.values, value) {
+				return false
+			}
+		case selectorExists:
+			if !exists {
+				return false
+			}
+		case selectorDoesNotExist:
+			if exists {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// filterConfigsBySelector returns the config names whose labels match the selector
+func (s *Server) filterConfigsBySelector(names []string, selector labelSelector) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	filtered := make([]string, 0, len(names))
+	for _, name := range names {
+		var labels map[string]string
+		if meta, exists := s.ConfigMeta[name]; exists {
+			labels = meta.Labels
+		}
+		if selector.Matches(labels) {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+// createLabeledConfigsDir creates a configs directory with labeled configs
+func createLabeledConfigsDir(t *testing.T) string {
+	tempDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, tempDir, map[string]string{
+		"dev.yaml":        "dev.yaml",
+		"prod.yaml":       "prod.yaml",
+		"valid-test.yaml": "valid-test.yaml",
+	})
+
+	labels := map[string]string{
+		"dev.labels.yaml":        "env: dev\nteam: payments\n",
+		"prod.labels.yaml":       "env: prod\nteam: legacy\n",
+		"valid-test.labels.yaml": "env: staging\nteam: core\n",
+	}
+	for fileName, content := range labels {
+		if err := os.WriteFile(filepath.Join(tempDir, fileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write labels file %s: %v", fileName, err)
+		}
+	}
+	return tempDir
+}
+
+func TestParseLabelSelector(t *testing.T) {
+	labels := map[string]string{"env": "prod", "team": "payments"}
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected bool
+		wantErr  bool
+	}{
+		{name: "equals", expr: "env=prod", expected: true},
+		{name: "double equals", expr: "env==staging", expected: false},
+		{name: "not equals", expr: "team!=legacy", expected: true},
+		{name: "in", expr: "env in (prod,staging)", expected: true},
+		{name: "in without match", expr: "env in (dev, staging)", expected: false},
+		{name: "notin", expr: "team notin (payments)", expected: false},
+		{name: "exists", expr: "team", expected: true},
+		{name: "does not exist", expr: "!owner", expected: true},
+		{name: "combined", expr: "env in (prod,staging),team!=legacy", expected: true},
+		{name: "missing parentheses", expr: "env in prod", wantErr: true},
+		{name: "empty key", expr: "=prod", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := parseLabelSelector(tt.expr)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if selector.Matches(labels) != tt.expected {
+				t.Errorf("Expected selector %q to match %v: %v", tt.expr, labels, tt.expected)
+			}
+		})
+	}
+}
+
+func TestServer_HandleListConfigs_Selector(t *testing.T) {
+	server, _ := createTestServerWithConfigs(t, createLabeledConfigsDir(t))
+
+	tests := []struct {
+		name           string
+		selector       string
+		expected       []string
+		expectedStatus int
+	}{
+		{name: "no selector", selector: "", expected: []string{"dev", "prod", "valid-test"}, expectedStatus: http.StatusOK},
+		{name: "in", selector: "env in (prod,staging)", expected: []string{"prod", "valid-test"}, expectedStatus: http.StatusOK},
+		{name: "notin", selector: "env notin (prod,staging)", expected: []string{"dev"}, expectedStatus: http.StatusOK},
+		{name: "not equals", selector: "team!=legacy", expected: []string{"dev", "valid-test"}, expectedStatus: http.StatusOK},
+		{name: "combined", selector: "env in (prod,staging),team!=legacy", expected: []string{"valid-test"}, expectedStatus: http.StatusOK},
+		{name: "invalid", selector: "env in prod", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/json/list?selector="+url.QueryEscape(tt.selector), nil)
+			w := httptest.NewRecorder()
+			server.HandleListConfigsJson(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var configs []string
+			if err := json.Unmarshal(w.Body.Bytes(), &configs); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			if !slices.Equal(configs, tt.expected) {
+				t.Errorf("Expected configs %v, got %v", tt.expected, configs)
+			}
+		})
+	}
+}
+
+func TestParseLabelsComment(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "labels comment",
+			data:     "# labels: env=prod, team=payments\napiVersion: v1\n",
+			expected: map[string]string{"env": "prod", "team": "payments"},
+		},
+		{
+			name:     "after other comments",
+			data:     "# Production cluster\n\n#labels: env=prod\napiVersion: v1\n",
+			expected: map[string]string{"env": "prod"},
+		},
+		{
+			name: "not a leading comment",
+			data: "apiVersion: v1\n# labels: env=prod\n",
+		},
+		{
+			name: "no comment",
+			data: "apiVersion: v1\n",
+		},
+		{
+			name:    "invalid label",
+			data:    "# labels: env\napiVersion: v1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, err := parseLabelsComment([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !maps.Equal(labels, tt.expected) {
+				t.Errorf("Expected labels %v, got %v", tt.expected, labels)
+			}
+		})
+	}
+}
+
+func TestServer_LabelsComment(t *testing.T) {
+	tempDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, tempDir, map[string]string{"prod.yaml": "prod.yaml"})
+	data := append([]byte("# labels: env=prod,team=payments\n"), testutil.LoadTestData(t, "kubeconfigs/dev.yaml")...)
+	if err := os.WriteFile(filepath.Join(tempDir, "dev.yaml"), data, 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	// The companion file wins over the comment
+	if err := os.WriteFile(filepath.Join(tempDir, "dev.labels.yaml"), []byte("env: dev\n"), 0644); err != nil {
+		t.Fatalf("Failed to write labels file: %v", err)
+	}
+
+	server, _ := createTestServerWithConfigs(t, tempDir)
+	expected := map[string]string{"env": "dev", "team": "payments"}
+	if labels := server.ConfigMeta["dev"].Labels; !maps.Equal(labels, expected) {
+		t.Errorf("Expected labels %v, got %v", expected, labels)
+	}
+}
+
+func TestServer_HandleListConfigs_Label(t *testing.T) {
+	server, _ := createTestServerWithConfigs(t, createLabeledConfigsDir(t))
+
+	tests := []struct {
+		name           string
+		query          string
+		expected       []string
+		expectedStatus int
+	}{
+		{name: "label", query: "label=env=prod", expected: []string{"prod"}, expectedStatus: http.StatusOK},
+		{name: "repeated labels", query: "label=team!=legacy&label=env=dev", expected: []string{"dev"}, expectedStatus: http.StatusOK},
+		{
+			name:           "label and selector",
+			query:          "label=team!=legacy&selector=" + url.QueryEscape("env in (prod,staging)"),
+			expected:       []string{"valid-test"},
+			expectedStatus: http.StatusOK,
+		},
+		{name: "invalid", query: "label==prod", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/json/list?"+tt.query, nil)
+			w := httptest.NewRecorder()
+			server.HandleListConfigsJson(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var configs []string
+			if err := json.Unmarshal(w.Body.Bytes(), &configs); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			if !slices.Equal(configs, tt.expected) {
+				t.Errorf("Expected configs %v, got %v", tt.expected, configs)
+			}
+		})
+	}
+}
+
+func TestServer_HandleGetKubeConfigs_Selector(t *testing.T) {
+	server, _ := createTestServerWithConfigs(t, createLabeledConfigsDir(t))
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedContexts []string
+		expectedStatus   int
+	}{
+		{
+			name:             "label",
+			query:            "label=env=prod",
+			expectedContexts: []string{"prod-context"},
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:             "selector",
+			query:            "selector=" + url.QueryEscape("env in (prod,staging)"),
+			expectedContexts: []string{"prod-context", "test-context"},
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:             "selector with name",
+			query:            "name=dev&label=env=prod",
+			expectedContexts: []string{"dev-context", "prod-context"},
+			expectedStatus:   http.StatusOK,
+		},
+		{name: "no match", query: "label=env=qa", expectedStatus: http.StatusNotFound},
+		{name: "invalid", query: "label==prod", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/json/get?"+tt.query, nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsJson(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var config KubeConfig
+			if err := json.Unmarshal(w.Body.Bytes(), &config); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			var contexts []string
+			for _, context := range config.Contexts {
+				contexts = append(contexts, context.Name)
+			}
+			slices.Sort(contexts)
+			if !slices.Equal(contexts, tt.expectedContexts) {
+				t.Errorf("Expected contexts %v, got %v", tt.expectedContexts, contexts)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/joomcode/errorx"
+	"gopkg.in/yaml.v3"
+)
+
+// parseKubeConfigDocuments parses every document of a multi-document YAML body as a
+// kubeconfig, empty documents are skipped
+func parseKubeConfigDocuments(data []byte) ([]*KubeConfig, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var configs []*KubeConfig
+	for i := 1; ; i++ {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			return configs, nil
+		}
+		if err != nil {
+			return nil, errorx.Decorate(err, "can't parse document %d", i)
+		}
+		if len(node.Content) == 0 {
+			continue
+		}
+
+		kubeConfig := &KubeConfig{}
+		if err := node.Decode(kubeConfig); err != nil {
+			return nil, errorx.Decorate(err, "can't parse document %d", i)
+		}
+		if err := kubeConfig.Validate(); err != nil {
+			return nil, errorx.Decorate(err, "invalid kubeconfig in document %d", i)
+		}
+		configs = append(configs, kubeConfig)
+	}
+}
+
+// HandleMergeBody merges the kubeconfigs posted as a multi-document YAML body and returns
+// the result without touching the stored configs, using the server as a merge engine only.
+// Names conflicting between the documents return 409
+func (s *Server) HandleMergeBody(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUploadSize))
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	configs, err := parseKubeConfigDocuments(data)
+	if err != nil {
+		s.handleHTTPError(w, err, "Invalid kubeconfigs", http.StatusBadRequest)
+		return
+	}
+	if len(configs) == 0 {
+		s.handleHTTPError(w, nil, "At least one kubeconfig document is required", http.StatusBadRequest)
+		return
+	}
+
+	merged, err := NewKubeConfig("", s.Logger)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to create empty kubeconfig", http.StatusInternalServerError)
+		return
+	}
+	for i, config := range configs {
+		merged, err = mergeKubeConfigs(merged, config)
+		if err != nil {
+			s.handleHTTPError(w, errorx.Decorate(err, "document %d", i+1),
+				"Kubeconfigs conflict", http.StatusConflict)
+			return
+		}
+	}
+	merged.resolveCurrentContext(s.DefaultCurrentContext)
+	s.prepareServedConfig(merged)
+
+	s.Logger.Info("Merged posted kubeconfigs", "count", len(configs))
+	err = createJSONEncoder(w).Encode(merged)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to encode kubeconfig", http.StatusInternalServerError)
+		return
+	}
+}
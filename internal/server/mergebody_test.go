@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+func TestServer_HandleMergeBody(t *testing.T) {
+	server, _ := createTestServerValid(t)
+	dev := string(testutil.LoadTestData(t, "kubeconfigs/dev.yaml"))
+	prod := string(testutil.LoadTestData(t, "kubeconfigs/prod.yaml"))
+	test := string(testutil.LoadTestData(t, "kubeconfigs/valid-test.yaml"))
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedStatus   int
+		expectedContexts []string
+	}{
+		{
+			name:             "three documents",
+			body:             "---\n" + dev + "---\n" + prod + "---\n" + test,
+			expectedStatus:   http.StatusOK,
+			expectedContexts: []string{"dev-context", "prod-context", "test-context"},
+		},
+		{name: "conflict", body: dev + "---\n" + dev, expectedStatus: http.StatusConflict},
+		{name: "invalid document", body: dev + "---\nclusters: []\n", expectedStatus: http.StatusBadRequest},
+		{name: "malformed", body: "clusters: [unclosed", expectedStatus: http.StatusBadRequest},
+		{name: "empty", body: "", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/json/merge-body", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var merged KubeConfig
+			if err := json.Unmarshal(w.Body.Bytes(), &merged); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			var contexts []string
+			for _, context := range merged.Contexts {
+				contexts = append(contexts, context.Name)
+			}
+			if !slices.Equal(contexts, tt.expectedContexts) {
+				t.Errorf("Expected contexts %v, got %v", tt.expectedContexts, contexts)
+			}
+			if len(merged.Clusters) != 3 || len(merged.Users) != 3 {
+				t.Errorf("Expected 3 clusters and users, got %d and %d", len(merged.Clusters), len(merged.Users))
+			}
+			if merged.CurrentContext != "dev-context" {
+				t.Errorf("Expected current context of the first document, got %q", merged.CurrentContext)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// mergedCache is the merged config of all configs encoded in every served format. Entries
+// are never modified, a change of the loaded configs replaces the whole entry
+type mergedCache struct {
+	bodies map[string][]byte // Encoded merged config by format
+}
+
+// invalidateMergedCache drops the merged config of all configs, the caller must hold s.mu
+// for writing. Merges computed before the call are not stored afterwards
+func (s *Server) invalidateMergedCache() {
+	s.mergedAll = nil
+	s.generation++
+}
+
+// isAllConfigsRequest reports whether the request gets the merged config of all configs
+// without any parameter changing the result, so it can be served from the merged cache
+func isAllConfigsRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet && r.URL.RawQuery == ""
+}
+
+// mergedAllConfigs returns the merged config of all configs in every served format,
+// merging and encoding it once after each change of the loaded configs. It returns false
+// when there are no configs or the merge fails, so the caller handles the request as usual
+func (s *Server) mergedAllConfigs() (*mergedCache, bool) {
+	s.mu.RLock()
+	cached, generation := s.mergedAll, s.generation
+	s.mu.RUnlock()
+	if cached != nil {
+		return cached, true
+	}
+
+	names, err := s.listConfigs()
+	if err != nil || len(names) == 0 {
+		return nil, false
+	}
+	kubeConfig, _, err := s.mergeConfigs(names, mergeOptions{})
+	if err != nil {
+		return nil, false
+	}
+
+	encoders := map[string]func(io.Writer) Encoder{
+		formatJSON: createJSONEncoder,
+		formatYAML: createYAMLEncoder,
+	}
+	cached = &mergedCache{bodies: make(map[string][]byte, len(encoders))}
+	for format, encoder := range encoders {
+		var buf bytes.Buffer
+		if err := encoder(&buf).Encode(kubeConfig); err != nil {
+			return nil, false
+		}
+		cached.bodies[format] = buf.Bytes()
+	}
+
+	s.mu.Lock()
+	// Configs changed while merging, the result may already be stale
+	if s.generation == generation {
+		s.mergedAll = cached
+	}
+	s.mu.Unlock()
+	return cached, true
+}
+
+// serveCachedAllConfigs writes the merged config of all configs from the merged cache and
+// reports whether it did. Other requests, and responses beyond MaxResponseSize, are left to
+// the regular handlers
+func (s *Server) serveCachedAllConfigs(w http.ResponseWriter, r *http.Request, format string) bool {
+	if !isAllConfigsRequest(r) || s.RequireName {
+		return false
+	}
+	cached, ok := s.mergedAllConfigs()
+	if !ok {
+		return false
+	}
+	body := cached.bodies[format]
+	if s.MaxResponseSize > 0 && len(body) > s.MaxResponseSize {
+		return false
+	}
+
+	if err := writeBodyWithETag(w, r, body); err != nil {
+		s.handleHTTPError(w, err, "Failed to serialize kubeconfig", http.StatusInternalServerError)
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+func TestServer_MergedCache(t *testing.T) {
+	tempDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, tempDir, map[string]string{"dev.yaml": "dev.yaml"})
+	serverConfig, _ := createTestServerRaw(t, tempDir)
+	server, err := NewServer(serverConfig)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	getContexts := func(url string) []string {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		server.HandleGetKubeConfigsJson(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var served KubeConfig
+		if err := json.Unmarshal(w.Body.Bytes(), &served); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		var contexts []string
+		for _, context := range served.Contexts {
+			contexts = append(contexts, context.Name)
+		}
+		return contexts
+	}
+
+	if contexts := getContexts("/json/get"); len(contexts) != 1 {
+		t.Fatalf("Expected 1 context, got %v", contexts)
+	}
+	server.mu.RLock()
+	cached := server.mergedAll != nil
+	server.mu.RUnlock()
+	if !cached {
+		t.Fatal("Expected the merged config of all configs to be cached")
+	}
+
+	// Requests with parameters bypass the cache
+	if contexts := getContexts("/json/get?name=dev"); len(contexts) != 1 {
+		t.Errorf("Expected 1 context, got %v", contexts)
+	}
+
+	testutil.CopyTestKubeConfigs(t, tempDir, map[string]string{"prod.yaml": "prod.yaml"})
+	if _, err := server.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if contexts := getContexts("/json/get"); len(contexts) != 2 {
+		t.Errorf("Expected reload to bust the cache and serve 2 contexts, got %v", contexts)
+	}
+
+	// The YAML endpoint serves the same cached merge
+	req := httptest.NewRequest("GET", "/yaml/get", nil)
+	w := httptest.NewRecorder()
+	server.HandleGetKubeConfigsYaml(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	served, err := parseKubeConfig(w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to parse YAML response: %v", err)
+	}
+	if len(served.Contexts) != 2 {
+		t.Errorf("Expected 2 contexts in YAML, got %d", len(served.Contexts))
+	}
+}
+
+// benchmarkGetAllConfigs benchmarks getting the merged config of all configs, with the
+// merged cache busted before every request unless cached is set
+func benchmarkGetAllConfigs(b *testing.B, cached bool) {
+	server, _ := createTestServerValid(&testing.T{})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !cached {
+			server.mu.Lock()
+			server.invalidateMergedCache()
+			server.mu.Unlock()
+		}
+		req := httptest.NewRequest("GET", "/json/get", nil)
+		w := httptest.NewRecorder()
+		server.HandleGetKubeConfigsJson(w, req)
+
+		if w.Code != http.StatusOK {
+			b.Fatalf("Benchmark failed with status %d", w.Code)
+		}
+	}
+}
+
+func BenchmarkServer_HandleGetKubeConfigsJson_AllCached(b *testing.B) {
+	benchmarkGetAllConfigs(b, true)
+}
+
+func BenchmarkServer_HandleGetKubeConfigsJson_AllUncached(b *testing.B) {
+	benchmarkGetAllConfigs(b, false)
+}
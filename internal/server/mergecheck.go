@@ -0,0 +1,96 @@
+package server
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// MergeConflict is an entry name defined by more than one config
+type MergeConflict struct {
+	Type    string   `json:"type"    yaml:"type"`
+	Name    string   `json:"name"    yaml:"name"`
+	Sources []string `json:"sources" yaml:"sources"`
+}
+
+// MergeCheck reports whether configs can be merged and every name they conflict on
+type MergeCheck struct {
+	Mergeable bool            `json:"mergeable" yaml:"mergeable"`
+	Conflicts []MergeConflict `json:"conflicts" yaml:"conflicts"`
+}
+
+// checkMerge finds every cluster, context and user name defined by more than one of the
+// configs. Unlike merging it doesn't stop at the first conflict. Configs with conflicts are
+// still mergeable when MergeConflictStrategy renames them
+func (s *Server) checkMerge(names []string) (*MergeCheck, error) {
+	sources := map[string]map[string][]string{
+		entryKindCluster: {},
+		entryKindContext: {},
+		entryKindUser:    {},
+	}
+	addSource := func(kind, entryName, configName string) {
+		if !slices.Contains(sources[kind][entryName], configName) {
+			sources[kind][entryName] = append(sources[kind][entryName], configName)
+		}
+	}
+
+	for _, name := range names {
+		kubeConfig, err := s.lookupConfig(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, cluster := range kubeConfig.Clusters {
+			addSource(entryKindCluster, cluster.Name, name)
+		}
+		for _, context := range kubeConfig.Contexts {
+			addSource(entryKindContext, context.Name, name)
+		}
+		for _, user := range kubeConfig.Users {
+			addSource(entryKindUser, user.Name, name)
+		}
+	}
+
+	check := &MergeCheck{Conflicts: []MergeConflict{}}
+	for kind, entries := range sources {
+		for entryName, configs := range entries {
+			if len(configs) > 1 {
+				check.Conflicts = append(check.Conflicts, MergeConflict{Type: kind, Name: entryName, Sources: configs})
+			}
+		}
+	}
+	slices.SortFunc(check.Conflicts, func(a, b MergeConflict) int {
+		return cmp.Or(strings.Compare(a.Type, b.Type), strings.Compare(a.Name, b.Name))
+	})
+	check.Mergeable = len(check.Conflicts) == 0 || s.MergeConflictStrategy == MergeConflictRename
+	return check, nil
+}
+
+// HandleMergeCheck reports whether all configs, or the requested ones, can be merged and
+// lists every conflicting name with the configs defining it, without returning credentials
+func (s *Server) HandleMergeCheck(w http.ResponseWriter, r *http.Request) {
+	configNames, err := s.listConfigs()
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to read configs directory", http.StatusInternalServerError)
+		return
+	}
+	requestedNames, err := s.getRequestedConfigNames(r, configNames)
+	if err != nil {
+		s.handleError(w, err, "Failed to resolve requested configs")
+		return
+	}
+
+	check, err := s.checkMerge(requestedNames)
+	if err != nil {
+		s.handleError(w, err, "Failed to check merge")
+		return
+	}
+
+	s.Logger.Info("Checked merge", "configs", len(requestedNames), "mergeable", check.Mergeable,
+		"conflicts", len(check.Conflicts))
+	err = createJSONEncoder(w).Encode(check)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to encode merge check", http.StatusInternalServerError)
+		return
+	}
+}
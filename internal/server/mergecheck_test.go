@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+func TestServer_HandleMergeCheck(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{
+		"dev.yaml":      "dev.yaml",
+		"dev-copy.yaml": "dev.yaml",
+		"prod.yaml":     "prod.yaml",
+	})
+	serverConfig, _ := createTestServerRaw(t, configsDir)
+	// dev-copy conflicts with dev, so the configs can't all be merged together
+	serverConfig.SkipMergeValidation = true
+	server, err := NewServer(serverConfig)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	devConflicts := []MergeConflict{
+		{Type: entryKindCluster, Name: "dev-cluster", Sources: []string{"dev", "dev-copy"}},
+		{Type: entryKindContext, Name: "dev-context", Sources: []string{"dev", "dev-copy"}},
+		{Type: entryKindUser, Name: "dev-user", Sources: []string{"dev", "dev-copy"}},
+	}
+
+	tests := []struct {
+		name              string
+		url               string
+		strategy          string
+		expectedStatus    int
+		expectedMergeable bool
+		expectedConflicts []MergeConflict
+	}{
+		{
+			name:              "all configs",
+			url:               "/json/mergecheck",
+			expectedStatus:    http.StatusOK,
+			expectedConflicts: devConflicts,
+		},
+		{
+			name:              "selected configs",
+			url:               "/json/mergecheck?name=dev&name=prod",
+			expectedStatus:    http.StatusOK,
+			expectedMergeable: true,
+			expectedConflicts: []MergeConflict{},
+		},
+		{
+			name:              "renamed conflicts",
+			url:               "/json/mergecheck?name=dev&name=dev-copy",
+			strategy:          MergeConflictRename,
+			expectedStatus:    http.StatusOK,
+			expectedMergeable: true,
+			expectedConflicts: devConflicts,
+		},
+		{name: "unknown config", url: "/json/mergecheck?name=missing", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.MergeConflictStrategy = tt.strategy
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			server.HandleMergeCheck(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var check MergeCheck
+			if err := json.Unmarshal(w.Body.Bytes(), &check); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			if check.Mergeable != tt.expectedMergeable {
+				t.Errorf("Expected mergeable %v, got %v", tt.expectedMergeable, check.Mergeable)
+			}
+			if !slices.EqualFunc(check.Conflicts, tt.expectedConflicts, func(a, b MergeConflict) bool {
+				return a.Type == b.Type && a.Name == b.Name && slices.Equal(a.Sources, b.Sources)
+			}) {
+				t.Errorf("Expected conflicts %+v, got %+v", tt.expectedConflicts, check.Conflicts)
+			}
+		})
+	}
+}
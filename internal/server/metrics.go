@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sync/atomic"
+)
+
+// reloadMetrics counts configs added and removed when loaded configs are replaced
+type reloadMetrics struct {
+	added   atomic.Int64
+	removed atomic.Int64
+}
+
+// configChanges returns the names of configs in next but not in previous and the other way around
+func configChanges(previous, next map[string]*KubeConfig) (added, removed []string) {
+	for name := range next {
+		if _, exists := previous[name]; !exists {
+			added = append(added, name)
+		}
+	}
+	for name := range previous {
+		if _, exists := next[name]; !exists {
+			removed = append(removed, name)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+	return added, removed
+}
+
+// recordConfigChanges updates the reload metrics and warns when more configs than
+// ConfigDropWarnThreshold disappeared at once, e.g. after a bad ConfigMap update
+func (s *Server) recordConfigChanges(added, removed []string, previousCount, count int) {
+	s.metrics.added.Add(int64(len(added)))
+	s.metrics.removed.Add(int64(len(removed)))
+
+	if len(added) > 0 || len(removed) > 0 {
+		s.Logger.Info("Configs changed", "added", added, "removed", removed)
+	}
+	if s.ConfigDropWarnThreshold > 0 && previousCount-count > s.ConfigDropWarnThreshold {
+		s.Logger.Warn("Config count dropped beyond threshold",
+			"previous", previousCount, "current", count,
+			"threshold", s.ConfigDropWarnThreshold, "removed", removed)
+	}
+}
+
+// HandleMetrics exposes config metrics in the Prometheus text format
+func (s *Server) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	count := len(s.LoadedConfigs)
+	invalid := len(s.invalid)
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics := []struct {
+		name  string
+		kind  string
+		help  string
+		value int64
+	}{
+		{"kubedepot_configs", "gauge", "Number of loaded configs.", int64(count)},
+		{"kubedepot_invalid_configs", "gauge", "Number of configs skipped because they failed to load.", int64(invalid)},
+		{"kubedepot_configs_added_total", "counter", "Configs added by loads and reloads.", s.metrics.added.Load()},
+		{"kubedepot_configs_removed_total", "counter", "Configs removed by reloads.", s.metrics.removed.Load()},
+	}
+	for _, metric := range metrics {
+		_, err := fmt.Fprintf(w, "# HELP %[1]s %[2]s\n# TYPE %[1]s %[3]s\n%[1]s %[4]d\n",
+			metric.name, metric.help, metric.kind, metric.value)
+		if err != nil {
+			s.Logger.Error("Failed to write metrics", "error", err)
+			return
+		}
+	}
+}
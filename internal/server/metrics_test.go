@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/log"
+)
+
+func TestServer_ReloadMetrics(t *testing.T) {
+	server := createArchiveTestServer(t, 5)
+	server.ConfigDropWarnThreshold = 2
+	var logs bytes.Buffer
+	server.Logger = log.New(&logs)
+	server.Logger.SetLevel(log.WarnLevel)
+
+	for i := 2; i < 5; i++ {
+		if err := os.Remove(filepath.Join(server.ConfigsDir, fmt.Sprintf("config-%04d.yaml", i))); err != nil {
+			t.Fatalf("Failed to remove config: %v", err)
+		}
+	}
+	if _, err := server.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+
+	if got := server.metrics.removed.Load(); got != 3 {
+		t.Errorf("Expected 3 removed configs, got %d", got)
+	}
+	if !strings.Contains(logs.String(), "Config count dropped beyond threshold") {
+		t.Errorf("Expected a warning about dropped configs, got %q", logs.String())
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	server.HandleMetrics(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	for _, line := range []string{
+		"kubedepot_configs 2\n",
+		"kubedepot_configs_added_total 5\n",
+		"kubedepot_configs_removed_total 3\n",
+		"# TYPE kubedepot_configs_removed_total counter\n",
+	} {
+		if !strings.Contains(w.Body.String(), line) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, w.Body.String())
+		}
+	}
+}
+
+func TestServer_ReloadMetrics_BelowThreshold(t *testing.T) {
+	server := createArchiveTestServer(t, 5)
+	server.ConfigDropWarnThreshold = 3
+	var logs bytes.Buffer
+	server.Logger = log.New(&logs)
+	server.Logger.SetLevel(log.WarnLevel)
+
+	if err := os.Remove(filepath.Join(server.ConfigsDir, "config-0000.yaml")); err != nil {
+		t.Fatalf("Failed to remove config: %v", err)
+	}
+	if _, err := server.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+
+	if got := server.metrics.removed.Load(); got != 1 {
+		t.Errorf("Expected 1 removed config, got %d", got)
+	}
+	if strings.Contains(logs.String(), "Config count dropped") {
+		t.Errorf("Expected no warning below the threshold, got %q", logs.String())
+	}
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultRequestIDHeader is the header carrying the request ID when none is configured
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// Handler returns the server routes wrapped with all middleware
+func (s *Server) Handler() http.Handler {
+	var handler http.Handler = s.setupRoutes()
+	handler = s.withCompression(handler)
+	handler = s.withMaintenance(handler)
+	handler = s.withAuth(handler)
+	handler = s.withCORS(handler)
+	handler = s.withResponseDelay(handler)
+	handler = s.withQueryLogging(handler)
+	handler = s.withAccessLog(handler)
+	handler = s.withRequestID(handler)
+	handler = s.withSecurityHeaders(handler)
+	handler = s.withInstanceHeader(handler)
+	handler = s.withServerHeader(handler)
+	return handler
+}
+
+// DefaultSecurityHeaders are set on every response unless overridden by SecurityHeaders
+var DefaultSecurityHeaders = map[string]string{
+	"X-Content-Type-Options": "nosniff",
+	"X-Frame-Options":        "DENY",
+	"Referrer-Policy":        "no-referrer",
+}
+
+// hstsHeaderValue is the Strict-Transport-Security header set when serving HTTPS
+const hstsHeaderValue = "max-age=31536000"
+
+// credentialPaths are request paths of responses carrying credentials, they must not be
+// stored by browsers or proxies. Paths ending with / match as prefixes
+var credentialPaths = []string{"/json/get", "/yaml/get", "/get/", "/download", "/archive", "/json/merge-body", "/raw", "/get"}
+
+// isCredentialPath reports whether responses of the request path carry credentials
+func isCredentialPath(path string) bool {
+	for _, credentialPath := range credentialPaths {
+		if path == credentialPath || (strings.HasSuffix(credentialPath, "/") && strings.HasPrefix(path, credentialPath)) {
+			return true
+		}
+	}
+	return false
+}
+
+// securityHeaders returns the security headers of responses to the request path
+func (s *Server) securityHeaders(path string) map[string]string {
+	headers := maps.Clone(DefaultSecurityHeaders)
+	if s.tlsEnabled() {
+		headers["Strict-Transport-Security"] = hstsHeaderValue
+	}
+	if isCredentialPath(path) {
+		headers["Cache-Control"] = "no-store"
+	}
+	for name, value := range s.SecurityHeaders {
+		if value == "" {
+			delete(headers, http.CanonicalHeaderKey(name))
+			continue
+		}
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+	return headers
+}
+
+// withSecurityHeaders sets the security headers on every response
+func (s *Server) withSecurityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range s.securityHeaders(r.URL.Path) {
+			w.Header().Set(name, value)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// DefaultRedactQueryParams are the query parameters redacted when none are configured
+var DefaultRedactQueryParams = []string{"token", "access_token", "password", "secret"}
+
+// redactedValue replaces values of redacted query parameters in logs
+const redactedValue = "REDACTED"
+
+// redactedQuery returns the query parameters with the values of RedactQueryParams replaced,
+// parameter names are compared case-insensitively
+func (s *Server) redactedQuery(query url.Values) map[string][]string {
+	redact := s.RedactQueryParams
+	if redact == nil {
+		redact = DefaultRedactQueryParams
+	}
+	params := make(map[string][]string, len(query))
+	for name, values := range query {
+		if slices.ContainsFunc(redact, func(r string) bool { return strings.EqualFold(r, name) }) {
+			values = slices.Repeat([]string{redactedValue}, len(values))
+		}
+		params[name] = values
+	}
+	return params
+}
+
+// withQueryLogging logs the query parameters of requests when LogQueryParams is enabled
+func (s *Server) withQueryLogging(next http.Handler) http.Handler {
+	if !s.LogQueryParams {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if query := r.URL.Query(); len(query) > 0 {
+			s.Logger.Info("Request query", "path", r.URL.Path, "requestID", RequestID(r.Context()),
+				"params", s.redactedQuery(query))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// instanceHeader identifies the instance that served a response
+const instanceHeader = "X-Kubedepot-Instance"
+
+// instanceID returns the hostname and version of the running instance, e.g. kubedepot-7f9c/v1.2.0
+func (s *Server) instanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	version := s.Version
+	if version == "" {
+		version = "dev"
+	}
+	return hostname + "/" + version
+}
+
+// withInstanceHeader sets the instance header on all responses when ExposeInstanceHeader is
+// enabled, to tell which instance behind a load balancer served a request
+func (s *Server) withInstanceHeader(next http.Handler) http.Handler {
+	if !s.ExposeInstanceHeader {
+		return next
+	}
+	instance := s.instanceID()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(instanceHeader, instance)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withServerHeader sets the Server response header to ServerHeader, it does nothing when
+// ServerHeader is empty
+func (s *Server) withServerHeader(next http.Handler) http.Handler {
+	if s.ServerHeader == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", s.ServerHeader)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withResponseDelay delays every response by ResponseDelay in debug mode to help testing
+// client timeouts. Requests canceled by the client while delayed aren't served
+func (s *Server) withResponseDelay(next http.Handler) http.Handler {
+	if !s.Debug || s.ResponseDelay <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Logger.Debug("Delaying response", "delay", s.ResponseDelay)
+		select {
+		case <-time.After(s.ResponseDelay):
+		case <-r.Context().Done():
+			s.Logger.Debug("Request canceled while delayed", "error", r.Context().Err())
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestIDHeader returns the configured request ID header name
+func (s *Server) requestIDHeader() string {
+	if s.RequestIDHeader == "" {
+		return DefaultRequestIDHeader
+	}
+	return s.RequestIDHeader
+}
+
+// withRequestID reads the request ID from the request or generates a new one,
+// echoes it in the response and stores it in the request context
+func (s *Server) withRequestID(next http.Handler) http.Handler {
+	header := s.requestIDHeader()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(header)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set(header, requestID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID)))
+	})
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestID returns the request ID stored in the context
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
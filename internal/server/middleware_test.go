@@ -0,0 +1,300 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+func TestServer_ResponseDelay(t *testing.T) {
+	tests := []struct {
+		name        string
+		debug       bool
+		delay       time.Duration
+		expectDelay bool
+	}{
+		{name: "delay applied when configured", debug: true, delay: 200 * time.Millisecond, expectDelay: true},
+		{name: "no delay by default", debug: true, delay: 0, expectDelay: false},
+		{name: "no delay without debug", debug: false, delay: 200 * time.Millisecond, expectDelay: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.Debug = tt.debug
+			server.ResponseDelay = tt.delay
+
+			req := httptest.NewRequest("GET", "/json/list", nil)
+			w := httptest.NewRecorder()
+
+			start := time.Now()
+			server.Handler().ServeHTTP(w, req)
+			elapsed := time.Since(start)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+			if tt.expectDelay && elapsed < tt.delay {
+				t.Errorf("Expected response to be delayed by at least %v, took %v", tt.delay, elapsed)
+			}
+			if !tt.expectDelay && elapsed >= 200*time.Millisecond {
+				t.Errorf("Expected no delay, took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestServer_ResponseDelay_Canceled(t *testing.T) {
+	server, _ := createTestServerValid(t)
+	server.Debug = true
+	server.ResponseDelay = 5 * time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("GET", "/json/list", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	server.Handler().ServeHTTP(w, req)
+
+	if elapsed := time.Since(start); elapsed >= server.ResponseDelay {
+		t.Errorf("Expected canceled request to return before the delay, took %v", elapsed)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected canceled request not to be served, got %q", w.Body.String())
+	}
+}
+
+func TestServer_RequestID(t *testing.T) {
+	tests := []struct {
+		name           string
+		configured     string
+		expectedHeader string
+		requestID      string
+	}{
+		{name: "default header echoed", configured: "", expectedHeader: "X-Request-ID", requestID: "abc-123"},
+		{name: "custom header echoed", configured: "X-Correlation-ID", expectedHeader: "X-Correlation-ID", requestID: "corr-456"},
+		{name: "generated when missing", configured: "X-Correlation-ID", expectedHeader: "X-Correlation-ID", requestID: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.RequestIDHeader = tt.configured
+
+			var seen string
+			handler := server.withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestID(r.Context())
+			}))
+
+			req := httptest.NewRequest("GET", "/json/list", nil)
+			if tt.requestID != "" {
+				req.Header.Set(tt.expectedHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			got := w.Header().Get(tt.expectedHeader)
+			if got == "" {
+				t.Fatalf("Expected %s header to be set", tt.expectedHeader)
+			}
+			if tt.requestID != "" && got != tt.requestID {
+				t.Errorf("Expected request ID %q, got %q", tt.requestID, got)
+			}
+			if seen != got {
+				t.Errorf("Expected request ID %q in context, got %q", got, seen)
+			}
+			if tt.configured != "" && w.Header().Get(DefaultRequestIDHeader) != "" {
+				t.Errorf("Expected no %s header with custom header configured", DefaultRequestIDHeader)
+			}
+		})
+	}
+}
+
+func TestServer_SecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name            string
+		url             string
+		tls             bool
+		securityHeaders map[string]string
+		expected        map[string]string
+	}{
+		{
+			name: "defaults on list",
+			url:  "/json/list",
+			expected: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "DENY",
+				"Referrer-Policy":           "no-referrer",
+				"Cache-Control":             "",
+				"Strict-Transport-Security": "",
+			},
+		},
+		{
+			name:     "no-store on get",
+			url:      "/json/get?name=dev",
+			expected: map[string]string{"Cache-Control": "no-store", "X-Content-Type-Options": "nosniff"},
+		},
+		{
+			name:     "no-store on file-style get",
+			url:      "/get/dev.yaml",
+			expected: map[string]string{"Cache-Control": "no-store"},
+		},
+		{
+			name:     "HSTS with TLS",
+			url:      "/json/list",
+			tls:      true,
+			expected: map[string]string{"Strict-Transport-Security": "max-age=31536000"},
+		},
+		{
+			name:            "overridden and removed",
+			url:             "/json/get?name=dev",
+			securityHeaders: map[string]string{"x-frame-options": "SAMEORIGIN", "Cache-Control": "", "Content-Security-Policy": "default-src 'none'"},
+			expected: map[string]string{
+				"X-Frame-Options":         "SAMEORIGIN",
+				"Cache-Control":           "",
+				"Content-Security-Policy": "default-src 'none'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.SecurityHeaders = tt.securityHeaders
+			if tt.tls {
+				server.TLSCertFile, server.TLSKeyFile = "cert.pem", "key.pem"
+			}
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			for name, value := range tt.expected {
+				if got := w.Header().Get(name); got != value {
+					t.Errorf("Expected header %s %q, got %q", name, value, got)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_QueryLogging(t *testing.T) {
+	tests := []struct {
+		name        string
+		enabled     bool
+		redact      []string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "default denylist",
+			enabled:     true,
+			contains:    []string{"Request query", "name:[dev]", "Token:[REDACTED]", "current-context:[dev-context]"},
+			notContains: []string{"s3cr3t"},
+		},
+		{
+			name:        "custom denylist",
+			enabled:     true,
+			redact:      []string{"current-context"},
+			contains:    []string{"Token:[s3cr3t]", "current-context:[REDACTED]"},
+			notContains: []string{"dev-context"},
+		},
+		{
+			name:        "disabled",
+			enabled:     false,
+			notContains: []string{"Request query"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.LogQueryParams = tt.enabled
+			server.RedactQueryParams = tt.redact
+			var logs bytes.Buffer
+			server.Logger = log.New(&logs)
+
+			req := httptest.NewRequest("GET", "/json/get?name=dev&Token=s3cr3t&current-context=dev-context", nil)
+			server.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+			for _, s := range tt.contains {
+				if !strings.Contains(logs.String(), s) {
+					t.Errorf("Expected logs to contain %q, got %q", s, logs.String())
+				}
+			}
+			for _, s := range tt.notContains {
+				if strings.Contains(logs.String(), s) {
+					t.Errorf("Expected logs not to contain %q, got %q", s, logs.String())
+				}
+			}
+		})
+	}
+}
+
+func TestServer_InstanceHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+	}{
+		{name: "enabled", enabled: true},
+		{name: "disabled", enabled: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.ExposeInstanceHeader = tt.enabled
+			server.Version = "v1.2.3"
+
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/ping", nil))
+
+			instance := w.Header().Get("X-Kubedepot-Instance")
+			if !tt.enabled {
+				if instance != "" {
+					t.Errorf("Expected no instance header, got %q", instance)
+				}
+				return
+			}
+			hostname, _ := os.Hostname()
+			if instance != hostname+"/v1.2.3" {
+				t.Errorf("Expected instance header %q, got %q", hostname+"/v1.2.3", instance)
+			}
+		})
+	}
+}
+
+func TestServer_ServerHeader(t *testing.T) {
+	tests := []struct {
+		name         string
+		serverHeader string
+	}{
+		{name: "set", serverHeader: "kubedepot"},
+		{name: "unset", serverHeader: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.ServerHeader = tt.serverHeader
+
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/ping", nil))
+
+			if _, exists := w.Header()["Server"]; exists != (tt.serverHeader != "") {
+				t.Errorf("Expected Server header present %v, got %v", tt.serverHeader != "", w.Header())
+			}
+			if got := w.Header().Get("Server"); got != tt.serverHeader {
+				t.Errorf("Expected Server header %q, got %q", tt.serverHeader, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/joomcode/errorx"
+)
+
+// rawContentType returns the content type of a config file by its extension
+func rawContentType(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return "application/json"
+	}
+	return "application/yaml"
+}
+
+// HandleRawConfig returns a single config as loaded, without the merge rewriting apiVersion,
+// kind or current-context, so fields the server doesn't model are kept. Configs loaded from
+// files are served with their original bytes, configs without a source file, e.g. from
+// secrets, as YAML of the loaded config
+func (s *Server) HandleRawConfig(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		s.handleHTTPError(w, errorx.IllegalArgument.New("name is required"), "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	kubeConfig, err := s.lookupConfig(name)
+	if err != nil {
+		s.handleError(w, err, "Failed to get raw config")
+		return
+	}
+
+	s.mu.RLock()
+	meta := s.ConfigMeta[name]
+	s.mu.RUnlock()
+
+	if meta == nil || meta.Path == "" {
+		var buf bytes.Buffer
+		if err := createYAMLEncoder(&buf).Encode(kubeConfig); err != nil {
+			s.handleHTTPError(w, err, "Failed to encode kubeconfig", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		if err := writeBodyWithETag(w, r, buf.Bytes()); err != nil {
+			s.handleHTTPError(w, err, "Failed to write raw config", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	raw, err := s.readRawConfig(name)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to read raw config", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", rawContentType(meta.Path))
+	if err := writeBodyWithETag(w, r, raw.data); err != nil {
+		s.handleHTTPError(w, err, "Failed to write raw config", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+func TestServer_HandleRawConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	data, err := os.ReadFile(filepath.Join(testutil.GetValidKubeConfigsDir(t), "dev.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	// Fields the server doesn't model and comments must survive
+	devData := append([]byte("# dev cluster\n"), data...)
+	devData = append(devData, []byte("preferences:\n  colors: true\n")...)
+	if err := os.WriteFile(filepath.Join(tempDir, "dev.yaml"), devData, 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	for cacheName, maxRawCache := range map[string]int{"from disk": 0, "from cache": 1 << 20} {
+		serverConfig, _ := createTestServerRaw(t, tempDir)
+		serverConfig.MaxRawCache = maxRawCache
+		server, err := NewServer(serverConfig)
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		handler := server.Handler()
+
+		tests := []struct {
+			name           string
+			url            string
+			expectedStatus int
+		}{
+			{name: "original bytes", url: "/raw?name=dev", expectedStatus: http.StatusOK},
+			{name: "unknown name", url: "/raw?name=missing", expectedStatus: http.StatusNotFound},
+			{name: "missing name", url: "/raw", expectedStatus: http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(cacheName+"/"+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest("GET", tt.url, nil)
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				if w.Code != tt.expectedStatus {
+					t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+				}
+				if tt.expectedStatus != http.StatusOK {
+					return
+				}
+				if w.Body.String() != string(devData) {
+					t.Errorf("Expected the original bytes %q, got %q", devData, w.Body.String())
+				}
+				if contentType := w.Header().Get("Content-Type"); contentType != "application/yaml" {
+					t.Errorf("Expected content type application/yaml, got %s", contentType)
+				}
+				if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "no-store" {
+					t.Errorf("Expected Cache-Control no-store, got %q", cacheControl)
+				}
+			})
+		}
+	}
+
+	t.Run("loaded without source file", func(t *testing.T) {
+		server, _ := createTestServerValid(t)
+		server.ConfigMeta["dev"].Path = ""
+
+		req := httptest.NewRequest("GET", "/raw?name=dev", nil)
+		w := httptest.NewRecorder()
+		server.HandleRawConfig(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), "current-context: dev-context") {
+			t.Errorf("Expected the loaded config as YAML, got %s", w.Body.String())
+		}
+	})
+}
+
+func TestRawContentType(t *testing.T) {
+	for path, expected := range map[string]string{
+		"dev.yaml": "application/yaml",
+		"dev.yml":  "application/yaml",
+		"dev.json": "application/json",
+		"DEV.JSON": "application/json",
+		"config":   "application/yaml",
+	} {
+		if got := rawContentType(path); got != expected {
+			t.Errorf("rawContentType(%q) = %q, want %q", path, got, expected)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/joomcode/errorx"
+)
+
+// rawConfig is the original content of a config file
+type rawConfig struct {
+	data    []byte
+	modTime time.Time
+}
+
+// rawFileInfo describes a cached config file to archive writers
+type rawFileInfo struct {
+	name string
+	raw  *rawConfig
+}
+
+func (i rawFileInfo) Name() string       { return i.name }
+func (i rawFileInfo) Size() int64        { return int64(len(i.raw.data)) }
+func (i rawFileInfo) Mode() fs.FileMode  { return 0o644 }
+func (i rawFileInfo) ModTime() time.Time { return i.raw.modTime }
+func (i rawFileInfo) IsDir() bool        { return false }
+func (i rawFileInfo) Sys() any           { return nil }
+
+// rawCacheEntry is a cached config file together with when it was cached
+type rawCacheEntry struct {
+	raw *rawConfig
+	seq uint64
+}
+
+// rawCache keeps original config file bytes in memory up to a total size. When a new file
+// doesn't fit, the largest cached files are evicted first, the oldest of equally large ones
+type rawCache struct {
+	mu      sync.Mutex
+	limit   int
+	size    int
+	seq     uint64
+	entries map[string]rawCacheEntry
+}
+
+// newRawCache creates a cache holding at most limit bytes, nil if limit disables caching
+func newRawCache(limit int) *rawCache {
+	if limit <= 0 {
+		return nil
+	}
+	return &rawCache{limit: limit, entries: make(map[string]rawCacheEntry)}
+}
+
+// get returns the cached file of a config
+func (c *rawCache) get(name string) (*rawConfig, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, exists := c.entries[name]
+	return entry.raw, exists
+}
+
+// put caches the file of a config, files larger than the whole cache are not cached
+func (c *rawCache) put(name string, raw *rawConfig) {
+	if c == nil || raw == nil || len(raw.data) > c.limit {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeLocked(name)
+	for c.size+len(raw.data) > c.limit {
+		c.removeLocked(c.evictionCandidateLocked())
+	}
+	c.seq++
+	c.entries[name] = rawCacheEntry{raw: raw, seq: c.seq}
+	c.size += len(raw.data)
+}
+
+// remove drops the cached file of a config
+func (c *rawCache) remove(name string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(name)
+}
+
+func (c *rawCache) removeLocked(name string) {
+	if entry, exists := c.entries[name]; exists {
+		c.size -= len(entry.raw.data)
+		delete(c.entries, name)
+	}
+}
+
+// evictionCandidateLocked returns the largest cached config, the oldest among equally large ones
+func (c *rawCache) evictionCandidateLocked() string {
+	var candidate string
+	var largest rawCacheEntry
+	for name, entry := range c.entries {
+		if largest.raw == nil || len(entry.raw.data) > len(largest.raw.data) ||
+			(len(entry.raw.data) == len(largest.raw.data) && entry.seq < largest.seq) {
+			candidate, largest = name, entry
+		}
+	}
+	return candidate
+}
+
+// readRawConfig returns the original content of a config file, from the cache when possible.
+// Files missing from the cache are read from disk and cached
+func (s *Server) readRawConfig(name string) (*rawConfig, error) {
+	s.mu.RLock()
+	cache := s.rawCache
+	meta := s.ConfigMeta[name]
+	s.mu.RUnlock()
+
+	if raw, exists := cache.get(name); exists {
+		return raw, nil
+	}
+	if meta == nil || meta.Path == "" {
+		return nil, errorx.InternalError.New("kubeconfig has no source file: %s", name)
+	}
+
+	data, err := os.ReadFile(meta.Path)
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to read kubeconfig file: %s", meta.Path)
+	}
+	info, err := os.Stat(meta.Path)
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to stat kubeconfig file: %s", meta.Path)
+	}
+	raw := &rawConfig{data: data, modTime: info.ModTime()}
+	cache.put(name, raw)
+	return raw, nil
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+func TestRawCache_Eviction(t *testing.T) {
+	raw := func(size int) *rawConfig {
+		return &rawConfig{data: bytes.Repeat([]byte("x"), size)}
+	}
+
+	cache := newRawCache(10)
+	cache.put("small", raw(2))
+	cache.put("large-old", raw(4))
+	cache.put("large-new", raw(4))
+
+	// The largest file is evicted first, the oldest of equally large ones
+	cache.put("medium", raw(3))
+	if _, exists := cache.get("large-old"); exists {
+		t.Error("Expected the oldest largest file to be evicted")
+	}
+	for _, name := range []string{"small", "large-new", "medium"} {
+		if _, exists := cache.get(name); !exists {
+			t.Errorf("Expected %s to stay cached", name)
+		}
+	}
+	if cache.size != 9 {
+		t.Errorf("Expected cache size 9, got %d", cache.size)
+	}
+
+	// Files larger than the whole cache are never cached
+	cache.put("huge", raw(11))
+	if _, exists := cache.get("huge"); exists {
+		t.Error("Expected a file larger than the cache not to be cached")
+	}
+
+	cache.remove("small")
+	if _, exists := cache.get("small"); exists || cache.size != 7 {
+		t.Errorf("Expected small to be removed, cache size %d", cache.size)
+	}
+
+	// A disabled cache caches nothing
+	disabled := newRawCache(0)
+	disabled.put("small", raw(2))
+	if _, exists := disabled.get("small"); exists {
+		t.Error("Expected disabled cache to cache nothing")
+	}
+}
+
+// archivedFile returns the content of the only file of a tar archive of a config
+func archivedFile(t *testing.T, server *Server, name string) []byte {
+	req := httptest.NewRequest("GET", "/archive?format=tar&name="+name, nil)
+	w := httptest.NewRecorder()
+	server.HandleArchive(w, req)
+
+	archive := tar.NewReader(w.Body)
+	if _, err := archive.Next(); err != nil {
+		t.Fatalf("Failed to read tar archive: %v: %s", err, w.Body.String())
+	}
+	data, err := io.ReadAll(archive)
+	if err != nil {
+		t.Fatalf("Failed to read archived file: %v", err)
+	}
+	return data
+}
+
+func TestServer_RawCache(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml"})
+	server, _ := createTestServerWithConfigs(t, configsDir)
+	server.MaxRawCache = 1 << 20
+	if _, err := server.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	original := testutil.LoadTestData(t, "kubeconfigs/dev.yaml")
+
+	// Change the file behind the server's back, the cached original is still served
+	path := filepath.Join(configsDir, "dev.yaml")
+	changed := strings.ReplaceAll(string(original), "dev-token", "rotated-token")
+	if err := os.WriteFile(path, []byte(changed), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if got := archivedFile(t, server, "dev"); !bytes.Equal(got, original) {
+		t.Errorf("Expected cached bytes to be served, got:\n%s", got)
+	}
+
+	// Reloading invalidates the cache
+	if _, err := server.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if got := archivedFile(t, server, "dev"); string(got) != changed {
+		t.Errorf("Expected reloaded bytes to be served, got:\n%s", got)
+	}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/joomcode/errorx"
+)
+
+// sizeLimitedWriter buffers a response and fails once it grows beyond the limit
+type sizeLimitedWriter struct {
+	buf      bytes.Buffer
+	limit    int
+	written  int
+	exceeded bool
+}
+
+// Write counts written bytes and buffers them while under the limit
+func (l *sizeLimitedWriter) Write(p []byte) (int, error) {
+	l.written += len(p)
+	if l.written > l.limit {
+		l.exceeded = true
+		return 0, errorx.IllegalState.New("response exceeds %d bytes", l.limit)
+	}
+	return l.buf.Write(p)
+}
+
+// writeEncoded encodes the value into the response with an ETag, see writeBodyWithETag. When
+// MaxResponseSize is set the response is rejected with 413 instead of sending a body beyond the limit
+func (s *Server) writeEncoded(
+	w http.ResponseWriter,
+	r *http.Request,
+	encoder func(io.Writer) Encoder,
+	v interface{},
+) error {
+	if s.MaxResponseSize <= 0 {
+		return writeWithETag(w, r, encoder, v)
+	}
+
+	limited := &sizeLimitedWriter{limit: s.MaxResponseSize}
+	err := encoder(limited).Encode(v)
+	if limited.exceeded {
+		// The error must not be saved as an attachment
+		w.Header().Del("Content-Disposition")
+		s.handleHTTPError(
+			w,
+			nil,
+			"Response exceeds maximum size of "+strconv.Itoa(s.MaxResponseSize)+" bytes, request fewer configs",
+			http.StatusRequestEntityTooLarge,
+		)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeBodyWithETag(w, r, limited.buf.Bytes())
+}
+
+// writeWithETag encodes the value into the response with an ETag, see writeBodyWithETag
+func writeWithETag(
+	w http.ResponseWriter,
+	r *http.Request,
+	encoder func(io.Writer) Encoder,
+	v interface{},
+) error {
+	var buf bytes.Buffer
+	if err := encoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	return writeBodyWithETag(w, r, buf.Bytes())
+}
+
+// writeBodyWithETag sets an ETag computed over the body and writes the body, or only
+// 304 Not Modified when the request's If-None-Match already has the ETag. The ETag is weak
+// since compression may change the bytes on the wire
+func writeBodyWithETag(w http.ResponseWriter, r *http.Request, body []byte) error {
+	sum := sha256.Sum256(body)
+	if setETag(w, r, sum[:]) {
+		return nil
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// writeStreamWithETag writes the body produced by write with an ETag like writeBodyWithETag,
+// without holding the body in memory: write is called once to compute the ETag and again
+// to send the body, so it must produce the same output both times
+func writeStreamWithETag(w http.ResponseWriter, r *http.Request, write func(io.Writer) error) error {
+	hash := sha256.New()
+	if err := write(hash); err != nil {
+		return err
+	}
+	if setETag(w, r, hash.Sum(nil)) {
+		return nil
+	}
+	return write(w)
+}
+
+// setETag sets the weak ETag for a body hash and reports whether the request's
+// If-None-Match already has it, in which case only 304 Not Modified is written
+func setETag(w http.ResponseWriter, r *http.Request, sum []byte) bool {
+	etag := `W/"` + hex.EncodeToString(sum) + `"`
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// writeJSONStringArray writes the strings as a JSON array one element at a time, with the
+// same output as the JSON encoder, so large lists aren't encoded in memory at once
+func writeJSONStringArray(w io.Writer, values []string) error {
+	buffered := bufio.NewWriter(w)
+	buffered.WriteByte('[')
+	for i, value := range values {
+		if i > 0 {
+			buffered.WriteByte(',')
+		}
+		element, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		buffered.Write(element)
+	}
+	buffered.WriteString("]\n")
+	return buffered.Flush()
+}
+
+// etagMatches reports whether an If-None-Match header matches the ETag using weak comparison
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
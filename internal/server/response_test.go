@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+func TestServer_MaxResponseSize(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxResponseSize int
+		endpoint        string
+		expectedStatus  int
+	}{
+		{name: "unlimited by default", maxResponseSize: 0, endpoint: "/json/get", expectedStatus: http.StatusOK},
+		{name: "tiny cap on json", maxResponseSize: 64, endpoint: "/json/get", expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "tiny cap on yaml", maxResponseSize: 64, endpoint: "/yaml/get", expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "tiny cap on download", maxResponseSize: 64, endpoint: "/download", expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "large cap", maxResponseSize: 1 << 20, endpoint: "/yaml/get", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.MaxResponseSize = tt.maxResponseSize
+
+			req := httptest.NewRequest("GET", tt.endpoint, nil)
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusRequestEntityTooLarge {
+				if !strings.Contains(w.Body.String(), "exceeds maximum size") {
+					t.Errorf("Expected clear error message, got %q", w.Body.String())
+				}
+				if w.Header().Get("Content-Disposition") != "" {
+					t.Error("Expected no Content-Disposition header on error")
+				}
+			}
+		})
+	}
+}
+
+func TestServer_ETag(t *testing.T) {
+	server := createUploadTestServer(t)
+	handler := server.Handler()
+
+	get := func(url, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, url := range []string{"/json/list", "/yaml/list", "/json/get", "/yaml/get?name=dev", "/get/dev.json"} {
+		t.Run(url, func(t *testing.T) {
+			first := get(url, "")
+			etag := first.Header().Get("ETag")
+			if first.Code != http.StatusOK || etag == "" {
+				t.Fatalf("Expected 200 with an ETag, got %d and %q", first.Code, etag)
+			}
+
+			second := get(url, etag)
+			if second.Code != http.StatusNotModified {
+				t.Errorf("Expected status code %d, got %d", http.StatusNotModified, second.Code)
+			}
+			if second.Body.Len() != 0 {
+				t.Errorf("Expected empty body, got %q", second.Body.String())
+			}
+
+			if other := get(url, `W/"other"`); other.Code != http.StatusOK {
+				t.Errorf("Expected status code %d for a stale ETag, got %d", http.StatusOK, other.Code)
+			}
+		})
+	}
+
+	t.Run("reload invalidates", func(t *testing.T) {
+		etag := get("/json/list", "").Header().Get("ETag")
+
+		testutil.CopyTestKubeConfigs(t, server.ConfigsDir, map[string]string{"prod.yaml": "prod.yaml"})
+		if _, err := server.Reload(); err != nil {
+			t.Fatalf("Failed to reload: %v", err)
+		}
+
+		w := get("/json/list", etag)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d after reload, got %d", http.StatusOK, w.Code)
+		}
+		if w.Header().Get("ETag") == etag {
+			t.Error("Expected ETag to change after reload")
+		}
+	})
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{"", false},
+		{`W/"abc"`, true},
+		{`"abc"`, true},
+		{`"other", W/"abc"`, true},
+		{"*", true},
+		{`"other"`, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.ifNoneMatch, `W/"abc"`); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.ifNoneMatch, got, tt.want)
+		}
+	}
+}
+
+func TestWriteJSONStringArray(t *testing.T) {
+	for _, values := range [][]string{
+		{},
+		{"dev"},
+		{"dev", "prod", "staging"},
+		{`quo"te`, "back\\slash", "<html>&", "ünïcode", "new\nline"},
+		{"\x00\x1f\b\f\r\t\x7f", "bad\xffutf8", "line\u2028sep\u2029", "emoji 🚀", "\xe2\x82"},
+	} {
+		var expected, got bytes.Buffer
+		if err := json.NewEncoder(&expected).Encode(values); err != nil {
+			t.Fatalf("Failed to encode: %v", err)
+		}
+		if err := writeJSONStringArray(&got, values); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		if got.String() != expected.String() {
+			t.Errorf("Expected %q, got %q", expected.String(), got.String())
+		}
+	}
+}
+
+// largeNameSet returns many config names to compare list encodings
+func largeNameSet() []string {
+	names := make([]string, 10000)
+	for i := range names {
+		names[i] = fmt.Sprintf("cluster-%05d", i)
+	}
+	return names
+}
+
+func BenchmarkListConfigsJSON_Encoder(b *testing.B) {
+	names := largeNameSet()
+	req := httptest.NewRequest("GET", "/json/list", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		w.Body = nil
+		if err := writeWithETag(w, req, createJSONEncoder, names); err != nil {
+			b.Fatalf("Benchmark failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkListConfigsJSON_Stream(b *testing.B) {
+	names := largeNameSet()
+	req := httptest.NewRequest("GET", "/json/list", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		w.Body = nil
+		err := writeStreamWithETag(w, req, func(out io.Writer) error {
+			return writeJSONStringArray(out, names)
+		})
+		if err != nil {
+			b.Fatalf("Benchmark failed: %v", err)
+		}
+	}
+}
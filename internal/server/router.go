@@ -1,28 +1,205 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
 	"net/http"
+	"os"
+	"strings"
 
 	"github.com/joomcode/errorx"
+	"github.com/rgeraskin/kubedepot/internal/config"
 )
 
 // setupRoutes configures all HTTP routes for the server
-func (s *Server) setupRoutes() {
-	http.HandleFunc("/json/list", s.HandleListConfigsJson)
-	http.HandleFunc("/yaml/list", s.HandleListConfigsYaml)
-	http.HandleFunc("/json/get", s.HandleGetKubeConfigsJson)
-	http.HandleFunc("/yaml/get", s.HandleGetKubeConfigsYaml)
-	http.HandleFunc("/", s.HandleIndex)
+func (s *Server) setupRoutes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json/list", s.HandleListConfigsJson)
+	mux.HandleFunc("/yaml/list", s.HandleListConfigsYaml)
+	mux.HandleFunc("GET /json/list/contexts", s.HandleListConfigContexts)
+	mux.HandleFunc("GET /json/configs", s.HandleConfigsDetail)
+	mux.HandleFunc("GET /search", s.HandleSearch)
+	mux.HandleFunc("/json/get", s.HandleGetKubeConfigsJson)
+	mux.HandleFunc("/yaml/get", s.HandleGetKubeConfigsYaml)
+	mux.HandleFunc("GET /json/get/group/{group}/check", s.HandleCheckGroup)
+	mux.HandleFunc("GET /json/mergecheck", s.HandleMergeCheck)
+	mux.HandleFunc("GET /get/{file}", s.HandleGetKubeConfigByPath)
+	mux.HandleFunc("POST /get", s.HandleGetKubeConfigsPost)
+	mux.HandleFunc("/download", s.HandleDownloadKubeConfig)
+	mux.HandleFunc("GET /archive", s.HandleArchive)
+	mux.HandleFunc("GET /raw", s.HandleRawConfig)
+	mux.HandleFunc("POST /json/diff", s.HandleDiffConfig)
+	mux.HandleFunc("POST /json/merge-body", s.HandleMergeBody)
+	mux.HandleFunc("GET /json/users", s.HandleListUsers)
+	mux.HandleFunc("GET /json/orphans", s.HandleListOrphans)
+	mux.HandleFunc("POST /upload", s.HandleUploadConfig)
+	mux.HandleFunc("POST /validate", s.HandleValidate)
+	mux.HandleFunc("DELETE /config", s.HandleDeleteConfig)
+	mux.HandleFunc("POST /reload", s.HandleReload)
+	mux.HandleFunc("GET /ping", s.HandlePing)
+	mux.HandleFunc("GET /healthz", s.HandleHealthz)
+	mux.HandleFunc("GET /readyz", s.HandleReadyz)
+	mux.HandleFunc("GET /metrics", s.HandleMetrics)
+	mux.HandleFunc("GET /admin/maintenance", s.HandleMaintenance)
+	mux.HandleFunc("POST /admin/maintenance", s.HandleMaintenance)
+	mux.HandleFunc("/", s.HandleIndex)
+	return mux
 }
 
-// Start starts the HTTP server
+// listen creates the server listener, a Unix socket if ListenSocket is set or TCP port otherwise
+func (s *Server) listen(port string) (net.Listener, error) {
+	if s.ListenSocket == "" {
+		return net.Listen("tcp", net.JoinHostPort(strings.Trim(s.BindAddress, "[]"), port))
+	}
+
+	// Remove a stale socket file left by a previous run, but never any other kind of file
+	// a mistyped path may point at
+	info, err := os.Lstat(s.ListenSocket)
+	switch {
+	case err == nil && info.Mode()&fs.ModeSocket == 0:
+		return nil, errorx.IllegalState.New("listen socket path exists and is not a socket: %s", s.ListenSocket)
+	case err == nil:
+		if err := os.Remove(s.ListenSocket); err != nil && !os.IsNotExist(err) {
+			return nil, errorx.Decorate(err, "failed to remove stale socket file")
+		}
+	case !os.IsNotExist(err):
+		return nil, errorx.Decorate(err, "failed to check listen socket path")
+	}
+	return net.Listen("unix", s.ListenSocket)
+}
+
+// Start starts the HTTP server and blocks until it fails or Shutdown is called.
+// An empty port means config.DefaultPort, use "0" for an ephemeral port
 func (s *Server) Start(port string) error {
-	s.setupRoutes()
+	if port == "" {
+		port = config.DefaultPort
+	}
+	handler := s.Handler()
+
+	if s.AsyncLoad {
+		stop := make(chan struct{})
+		defer close(stop)
+		go s.loadUntilReady(stop)
+	}
+
+	if s.Watch && s.SecretSource {
+		s.Logger.Warn("Ignoring WATCH_CONFIGS in secret source mode, use POST /reload to pick up changed secrets")
+	} else if s.Watch {
+		stopWatching, err := s.startWatching()
+		if err != nil {
+			return errorx.Decorate(err, "failed to watch configs directory")
+		}
+		defer stopWatching()
+	}
 
-	s.Logger.Info("Server starting", "port", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	listener, err := s.listen(port)
+	if err != nil {
 		return errorx.Decorate(err, "failed to start server")
 	}
+	// Closing a Unix listener also removes its socket file
+	defer listener.Close()
+
+	httpServer := &http.Server{Handler: handler, IdleTimeout: s.IdleTimeout}
+	httpServer.SetKeepAlivesEnabled(!s.DisableKeepAlive)
+	s.srvMu.Lock()
+	// A shutdown requested while starting, e.g. a signal right after launch, found no
+	// server to stop, so don't start serving
+	if s.shuttingDown {
+		s.srvMu.Unlock()
+		s.Logger.Info("Shutdown requested before serving, not starting server")
+		return nil
+	}
+	s.httpServer = httpServer
+	s.listener = listener
+	s.srvMu.Unlock()
+
+	if s.ListenSocket != "" {
+		s.Logger.Info("Server starting", "socket", s.ListenSocket, "tls", s.tlsEnabled())
+	} else {
+		s.Logger.Info("Server starting", "address", listener.Addr(), "tls", s.tlsEnabled())
+	}
+	if s.tlsEnabled() {
+		err = httpServer.ServeTLS(listener, s.TLSCertFile, s.TLSKeyFile)
+	} else {
+		err = httpServer.Serve(listener)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errorx.Decorate(err, "failed to start server")
+	}
+
+	return nil
+}
+
+// validateBindAddress checks BindAddress is empty, an IP address or a host name, without
+// a port since that is given to Start
+func (s *Server) validateBindAddress() error {
+	address := s.BindAddress
+	if address == "" {
+		return nil
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return errorx.IllegalArgument.New("bind address must not include a port, got %q, use %q", address, host)
+	}
+	if net.ParseIP(strings.Trim(address, "[]")) != nil {
+		return nil
+	}
+	if strings.ContainsAny(address, ":/[] ") {
+		return errorx.IllegalArgument.New("invalid bind address: %q", address)
+	}
+	return nil
+}
+
+// tlsEnabled reports whether the server serves HTTPS
+func (s *Server) tlsEnabled() bool {
+	return s.TLSCertFile != "" && s.TLSKeyFile != ""
+}
+
+// validateTLSFiles checks that either both or none of the TLS files are set
+// and that the set files are readable
+func (s *Server) validateTLSFiles() error {
+	if (s.TLSCertFile == "") != (s.TLSKeyFile == "") {
+		return errorx.IllegalArgument.New("both TLS certificate and key files must be set, got certificate %q and key %q",
+			s.TLSCertFile, s.TLSKeyFile)
+	}
+	for _, file := range []string{s.TLSCertFile, s.TLSKeyFile} {
+		if file == "" {
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return errorx.Decorate(err, "can't read TLS file: %s", file)
+		}
+		f.Close()
+	}
+	return nil
+}
+
+// Addr returns the address the server listens on, nil if it hasn't started yet
+func (s *Server) Addr() net.Addr {
+	s.srvMu.Lock()
+	defer s.srvMu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
 
+// Shutdown stops accepting new connections and waits for in-flight requests to finish
+// or ctx to be done. If the server hasn't started serving yet, Start returns without serving
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.srvMu.Lock()
+	s.shuttingDown = true
+	httpServer := s.httpServer
+	s.srvMu.Unlock()
+	if httpServer == nil {
+		return nil
+	}
+
+	s.Logger.Info("Shutting down server")
+	if err := httpServer.Shutdown(ctx); err != nil {
+		return errorx.Decorate(err, "failed to shut down server")
+	}
 	return nil
 }
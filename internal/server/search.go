@@ -0,0 +1,135 @@
+package server
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// searchMatch is a config name matching a search query at a position, or at an edit
+// distance for suggestions
+type searchMatch struct {
+	name     string
+	position int
+}
+
+// matchPosition returns where the query is found in the name case-insensitively, -1 if it
+// isn't. In fuzzy mode the query characters only have to appear in order, e.g. "prd"
+// matches "prod", and the position is where the first of them is found
+func matchPosition(name, query string, fuzzy bool) int {
+	name, query = strings.ToLower(name), strings.ToLower(query)
+	if !fuzzy {
+		return strings.Index(name, query)
+	}
+
+	position, next := -1, 0
+	queryRunes := []rune(query)
+	for i, c := range name {
+		if next == len(queryRunes) {
+			break
+		}
+		if c == queryRunes[next] {
+			if next == 0 {
+				position = i
+			}
+			next++
+		}
+	}
+	if next < len(queryRunes) {
+		return -1
+	}
+	return max(position, 0)
+}
+
+// searchConfigNames returns the names matching the query sorted by match position and
+// then by name, all names are returned for an empty query
+func searchConfigNames(names []string, query string, fuzzy bool) []string {
+	if query == "" {
+		return names
+	}
+
+	var matches []searchMatch
+	for _, name := range names {
+		if position := matchPosition(name, query, fuzzy); position >= 0 {
+			matches = append(matches, searchMatch{name: name, position: position})
+		}
+	}
+	slices.SortFunc(matches, func(a, b searchMatch) int {
+		return cmp.Or(cmp.Compare(a.position, b.position), strings.Compare(a.name, b.name))
+	})
+
+	found := make([]string, 0, len(matches))
+	for _, match := range matches {
+		found = append(found, match.name)
+	}
+	return found
+}
+
+// HandleSearch returns the config names containing the q query parameter, e.g. for a
+// type-ahead. With fuzzy=true the query characters only have to appear in order
+func (s *Server) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	fuzzy := r.URL.Query().Get("fuzzy") == "true"
+	s.Logger.Info("HandleSearch", "query", query, "fuzzy", fuzzy)
+
+	names, err := s.listConfigs()
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to list configs", http.StatusInternalServerError)
+		return
+	}
+
+	err = createJSONEncoder(w).Encode(searchConfigNames(names, query, fuzzy))
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to encode search results", http.StatusInternalServerError)
+		return
+	}
+}
+
+// maxSuggestions is how many similar config names are suggested for a missing one
+const maxSuggestions = 3
+
+// levenshteinDistance returns the number of single character edits turning a into b
+func levenshteinDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(br)]
+}
+
+// similarConfigNames returns the names closest to a missing name by Levenshtein distance,
+// compared case-insensitively. Names needing more edits than a third of the name, but at
+// least two, aren't similar
+func similarConfigNames(names []string, missing string) []string {
+	maxDistance := max(2, len([]rune(missing))/3)
+	var matches []searchMatch
+	for _, name := range names {
+		distance := levenshteinDistance(strings.ToLower(name), strings.ToLower(missing))
+		if distance <= maxDistance {
+			matches = append(matches, searchMatch{name: name, position: distance})
+		}
+	}
+	slices.SortFunc(matches, func(a, b searchMatch) int {
+		return cmp.Or(cmp.Compare(a.position, b.position), strings.Compare(a.name, b.name))
+	})
+
+	suggestions := make([]string, 0, min(len(matches), maxSuggestions))
+	for _, match := range matches[:min(len(matches), maxSuggestions)] {
+		suggestions = append(suggestions, match.name)
+	}
+	return suggestions
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestSearchConfigNames(t *testing.T) {
+	names := []string{"dev", "payments-prod", "prod", "prod-eu", "staging"}
+
+	tests := []struct {
+		name     string
+		query    string
+		fuzzy    bool
+		expected []string
+	}{
+		{name: "empty query", query: "", expected: names},
+		{name: "substring", query: "prod", expected: []string{"prod", "prod-eu", "payments-prod"}},
+		{name: "case insensitive", query: "PROD-E", expected: []string{"prod-eu"}},
+		{name: "no match", query: "qa", expected: []string{}},
+		{name: "subsequence without fuzzy", query: "prd", expected: []string{}},
+		{name: "fuzzy", query: "prd", fuzzy: true, expected: []string{"payments-prod", "prod", "prod-eu"}},
+		{name: "fuzzy in order only", query: "dp", fuzzy: true, expected: []string{}},
+		{name: "fuzzy across words", query: "stg", fuzzy: true, expected: []string{"staging"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := searchConfigNames(names, tt.query, tt.fuzzy)
+			if !slices.Equal(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestServer_HandleSearch(t *testing.T) {
+	server, _ := createTestServerWithConfigs(t, createLabeledConfigsDir(t))
+
+	tests := []struct {
+		name     string
+		url      string
+		expected []string
+	}{
+		{name: "all", url: "/search", expected: []string{"dev", "prod", "valid-test"}},
+		{name: "substring", url: "/search?q=ev", expected: []string{"dev"}},
+		{name: "fuzzy", url: "/search?q=vt&fuzzy=true", expected: []string{"valid-test"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			server.HandleSearch(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+			var names []string
+			if err := json.Unmarshal(w.Body.Bytes(), &names); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			if !slices.Equal(names, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestSimilarConfigNames(t *testing.T) {
+	names := []string{"dev", "prod", "prod-eu", "staging", "stage"}
+
+	tests := []struct {
+		missing  string
+		expected []string
+	}{
+		{missing: "prrod", expected: []string{"prod"}},
+		{missing: "prod-us", expected: []string{"prod-eu"}},
+		{missing: "stagin", expected: []string{"staging", "stage"}},
+		{missing: "kubernetes", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.missing, func(t *testing.T) {
+			suggestions := similarConfigNames(names, tt.missing)
+			if !slices.Equal(suggestions, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, suggestions)
+			}
+		})
+	}
+}
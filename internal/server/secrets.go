@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// secretConfigKey is the Secret data key holding the kubeconfig
+	secretConfigKey = "config"
+
+	// serviceAccountNamespaceFile holds the namespace of the pod when running in-cluster
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// secretsClient returns SecretClient or creates one from in-cluster credentials
+func (s *Server) secretsClient() (kubernetes.Interface, error) {
+	if s.SecretClient != nil {
+		return s.SecretClient, nil
+	}
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to get in-cluster credentials")
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to create Kubernetes client")
+	}
+	s.SecretClient = client
+	return client, nil
+}
+
+// secretsNamespace returns SecretNamespace or the namespace of the pod the server runs in
+func (s *Server) secretsNamespace() (string, error) {
+	if s.SecretNamespace != "" {
+		return s.SecretNamespace, nil
+	}
+	data, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return "", errorx.Decorate(err, "failed to detect namespace, set SECRET_NAMESPACE")
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// readSecretConfigs loads the kubeconfigs stored in the config key of Secrets matching
+// SecretLabelSelector into a fresh config set. Configs are named after their Secrets and
+// get the Secret labels
+func (s *Server) readSecretConfigs() (*configSet, error) {
+	client, err := s.secretsClient()
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := s.secretsNamespace()
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, err := client.CoreV1().Secrets(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: s.SecretLabelSelector,
+	})
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to list secrets in namespace: %s", namespace)
+	}
+
+	set := newConfigSet(len(secrets.Items))
+	for _, secret := range secrets.Items {
+		loaded, err := s.loadSecretConfig(secret.Name, secret.Labels, secret.Data)
+		if err != nil && s.SkipInvalidConfigs {
+			s.Logger.Error("Skipping invalid config secret", "secret", secret.Name, "error", err)
+			set.invalid[secret.Name] = err.Error()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if loaded != nil {
+			if err := set.add(loaded); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return set, nil
+}
+
+// loadSecretConfig parses the kubeconfig of a single Secret, nil is returned for Secrets
+// without a config key
+func (s *Server) loadSecretConfig(
+	name string,
+	labels map[string]string,
+	data map[string][]byte,
+) (*loadedConfig, error) {
+	if !s.isIncluded(name) {
+		s.Logger.Debug("Skipping secret not matching include patterns", "secret", name)
+		return nil, nil
+	}
+
+	raw, exists := data[secretConfigKey]
+	if !exists {
+		s.Logger.Warn("Skipping secret without kubeconfig", "secret", name, "key", secretConfigKey)
+		return nil, nil
+	}
+
+	s.Logger.Debug("Loading config secret", "secret", name)
+
+	kubeConfig, err := parseKubeConfig(raw)
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to load kubeconfig of secret: %s", name)
+	}
+
+	if s.Normalize {
+		kubeConfig, err = normalizeKubeConfig(kubeConfig)
+		if err != nil {
+			return nil, errorx.Decorate(err, "failed to normalize kubeconfig of secret: %s", name)
+		}
+	}
+
+	if s.PrefixWithConfigName {
+		kubeConfig = kubeConfig.prefixAllNames(name)
+	}
+
+	if err := s.validateContextNames(kubeConfig); err != nil {
+		return nil, errorx.Decorate(err, "invalid kubeconfig of secret: %s", name)
+	}
+	if err := s.checkServerURLs(kubeConfig, name); err != nil {
+		return nil, errorx.Decorate(err, "invalid kubeconfig of secret: %s", name)
+	}
+
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	return &loadedConfig{
+		name:       name,
+		kubeConfig: kubeConfig,
+		meta:       &ConfigMeta{Labels: labels},
+	}, nil
+}
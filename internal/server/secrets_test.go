@@ -0,0 +1,134 @@
+package server
+
+import (
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// configSecret creates a Secret holding a kubeconfig in its config key
+func configSecret(name, namespace string, labels map[string]string, config []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Data:       map[string][]byte{secretConfigKey: config},
+	}
+}
+
+func TestServer_SecretSource(t *testing.T) {
+	selected := map[string]string{"kubedepot/kubeconfig": "true", "env": "dev"}
+	client := fake.NewClientset(
+		configSecret("dev", "configs", selected, testutil.LoadTestData(t, "kubeconfigs/dev.yaml")),
+		configSecret("prod", "configs", map[string]string{"kubedepot/kubeconfig": "true"},
+			testutil.LoadTestData(t, "kubeconfigs/prod.yaml")),
+		// Not selected: wrong label or namespace
+		configSecret("unlabeled", "configs", nil, testutil.LoadTestData(t, "kubeconfigs/prod.yaml")),
+		configSecret("other", "other", selected, testutil.LoadTestData(t, "kubeconfigs/dev.yaml")),
+		// Selected but without a config key
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "configs", Labels: selected}},
+	)
+
+	logger := log.New(os.Stderr)
+	logger.SetLevel(log.ErrorLevel)
+	server, err := NewServer(&Server{
+		ConfigsDir:          "/nonexistent",
+		WebDir:              testutil.GetTestDataDir(t),
+		Logger:              logger,
+		SecretSource:        true,
+		SecretNamespace:     "configs",
+		SecretLabelSelector: "kubedepot/kubeconfig=true",
+		SecretClient:        client,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	names := server.getAllConfigNames()
+	if !slices.Equal(names, []string{"dev", "prod"}) {
+		t.Errorf("Expected configs dev and prod loaded from secrets, got %v", names)
+	}
+	if got := server.LoadedConfigs["dev"].CurrentContext; got != "dev-context" {
+		t.Errorf("Expected dev current context dev-context, got %q", got)
+	}
+	if got := server.ConfigMeta["dev"].Labels["env"]; got != "dev" {
+		t.Errorf("Expected secret labels to be config labels, got %v", server.ConfigMeta["dev"].Labels)
+	}
+
+	t.Run("invalid secret", func(t *testing.T) {
+		server.SecretClient = fake.NewClientset(
+			configSecret("broken", "configs", selected, []byte("clusters: [unclosed")),
+		)
+		if _, err := server.Reload(); err == nil {
+			t.Error("Expected reload to fail on an invalid secret")
+		}
+
+		server.SkipInvalidConfigs = true
+		if _, err := server.Reload(); err != nil {
+			t.Fatalf("Expected invalid secret to be skipped: %v", err)
+		}
+		invalid := server.InvalidConfigs()
+		if len(invalid) != 1 || invalid[0].Name != "broken" {
+			t.Errorf("Expected broken secret to be reported invalid, got %v", invalid)
+		}
+	})
+}
+
+func TestServer_Reload_SingleFlight(t *testing.T) {
+	client := fake.NewClientset(
+		configSecret("dev", "configs", nil, testutil.LoadTestData(t, "kubeconfigs/dev.yaml")),
+	)
+	logger := log.New(os.Stderr)
+	logger.SetLevel(log.ErrorLevel)
+	server := &Server{Logger: logger, SecretSource: true, SecretNamespace: "configs", SecretClient: client}
+
+	// Count loads and hold them until all reloads are fired
+	var loads atomic.Int32
+	release := make(chan struct{})
+	client.PrependReactor("list", "secrets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		loads.Add(1)
+		<-release
+		return false, nil, nil
+	})
+
+	const reloads = 20
+	var wg sync.WaitGroup
+	counts := make([]int, reloads)
+	errs := make([]error, reloads)
+	for i := range reloads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counts[i], errs[i] = server.Reload()
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := loads.Load(); got != 1 {
+		t.Errorf("Expected a single load for concurrent reloads, got %d", got)
+	}
+	for i := range reloads {
+		if errs[i] != nil || counts[i] != 1 {
+			t.Errorf("Expected every reload to share the result, got count %d and error %v", counts[i], errs[i])
+		}
+	}
+
+	// A later reload loads again
+	if _, err := server.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if got := loads.Load(); got != 2 {
+		t.Errorf("Expected a new load after the previous reload finished, got %d", got)
+	}
+}
@@ -3,45 +3,250 @@ package server
 import (
 	"embed"
 	"encoding/json"
+	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
+	"maps"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/joomcode/errorx"
 	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/kubernetes"
 )
 
 // Server represents the API server
 type Server struct {
-	ConfigsDir    string
-	WebDir        string
-	Logger        *log.Logger
-	LoadedConfigs map[string]*KubeConfig // Pre-loaded configs to avoid file system changes affecting runtime
-	EmbeddedFiles *embed.FS              // Optional embedded files for container deployment
+	mu           sync.RWMutex        // Guards LoadedConfigs, ConfigMeta, groups, invalid, rawCache and the merged cache
+	maintenance  atomic.Bool         // Whether config routes currently return 503
+	ready        atomic.Bool         // Whether configs are loaded and valid, reported by /readyz
+	srvMu        sync.Mutex          // Guards httpServer, listener and shuttingDown
+	shuttingDown bool                // Set by Shutdown, a Start that hasn't begun serving returns instead
+	httpServer   *http.Server        // Set by Start, used by Shutdown
+	listener     net.Listener        // Set by Start, used by Addr
+	groups       map[string][]string // Config names of each group loaded from GroupsFile
+	invalid      map[string]string   // Error of each config that failed to load and was skipped
+	reloadMu     sync.Mutex          // Guards reloading
+	reloading    *reloadCall         // In-flight reload shared by concurrent callers
+	contextName  *regexp.Regexp      // Compiled ContextNamePattern, nil if disabled
+	metrics      reloadMetrics       // Configs added and removed by reloads
+	rawCache     *rawCache           // Original bytes of config files, nil if MaxRawCache is 0
+	mergedAll    *mergedCache        // Merged config of all configs, nil until requested or after changes
+	generation   uint64              // Incremented whenever LoadedConfigs changes
+
+	ConfigsDir              string
+	WebDir                  string
+	Logger                  *log.Logger
+	LoadedConfigs           map[string]*KubeConfig // Pre-loaded configs to avoid file system changes affecting runtime
+	ConfigMeta              map[string]*ConfigMeta // Metadata of loaded configs, e.g. labels
+	EmbeddedFiles           *embed.FS              // Optional embedded files for container deployment
+	MaxScanDepth            int                    // How many levels of subdirectories to scan, 0 means top level only
+	ListenSocket            string                 // Optional Unix socket path to listen on instead of a TCP port
+	Debug                   bool                   // Debug mode, debug-only options like ResponseDelay are ignored without it
+	ResponseDelay           time.Duration          // Artificial delay added to every response in debug mode, for testing clients only
+	SkipMergeValidation     bool                   // Don't check at startup that all configs can be merged together
+	RequestIDHeader         string                 // Header to read and echo the request ID, X-Request-ID by default
+	DefaultCurrentContext   string                 // Preferred current context of merged configs
+	MaxResponseSize         int                    // Maximum size of get responses in bytes, 0 means unlimited
+	Normalize               bool                   // Trim trailing whitespace of all values when loading configs
+	SingleFile              bool                   // Accept a single kubeconfig file as ConfigsDir
+	Watch                   bool                   // Watch ConfigsDir and reload configs on change
+	CompressPaths           []string               // Request paths to gzip responses of, DefaultCompressPaths if nil
+	Maintenance             bool                   // Start in maintenance mode, config routes return 503
+	AdminToken              string                 // Bearer token required by admin endpoints, disabled if empty
+	RequireName             bool                   // Reject get requests without names instead of merging all configs
+	GroupsFile              string                 // Optional YAML file mapping group names to lists of config names
+	TLSCertFile             string                 // Certificate file to serve HTTPS with, requires TLSKeyFile
+	TLSKeyFile              string                 // Private key file to serve HTTPS with, requires TLSCertFile
+	IdleTimeout             time.Duration          // How long idle keep-alive connections are kept open, 0 means the default
+	DisableKeepAlive        bool                   // Close connections after every response, for load balancers managing connections
+	AuthToken               string                 // Bearer token required by all endpoints except health checks, disabled if empty
+	ForceSecure             bool                   // Clear insecure-skip-tls-verify of all clusters in served configs
+	TemplateLeftDelim       string                 // Left action delimiter of the index template, {{ if empty
+	TemplateRightDelim      string                 // Right action delimiter of the index template, }} if empty
+	SkipInvalidConfigs      bool                   // Log and skip configs that fail to load instead of failing startup
+	CurrentContextPriority  []string               // Config names whose current context wins when merging, in priority order
+	SecretSource            bool                   // Load configs from Kubernetes Secrets instead of ConfigsDir
+	SecretNamespace         string                 // Namespace of config Secrets, the pod namespace if empty
+	SecretLabelSelector     string                 // Label selector of config Secrets
+	SecretClient            kubernetes.Interface   // Optional Kubernetes client, created from in-cluster credentials if nil
+	MergeConflictStrategy   string                 // How names conflicting between merged configs are handled, error or rename
+	PrefixWithConfigName    bool                   // Prefix cluster, context and user names of every config with its config name
+	ContextNamePattern      string                 // Regular expression all context names must match, disabled if empty
+	ConfigDropWarnThreshold int                    // Warn when a reload removes more than this many configs, 0 disables the warning
+	MaxRawCache             int                    // Maximum total size in bytes of original config files cached in memory, 0 disables the cache
+	RecursiveConfigs        bool                   // Scan all levels of subdirectories, overriding MaxScanDepth
+	SecurityHeaders         map[string]string      // Response headers overriding DefaultSecurityHeaders, an empty value removes a header
+	AllowedExtensions       []string               // File extensions loaded as configs, DefaultAllowedExtensions if nil, all if empty
+	LogQueryParams          bool                   // Log the query parameters of every request, RedactQueryParams are redacted
+	RedactQueryParams       []string               // Query parameters redacted when logged, DefaultRedactQueryParams if nil
+	AsyncLoad               bool                   // Load configs in the background on Start instead of in NewServer, /readyz returns 503 until loaded
+	AccessLog               bool                   // Log every request as a JSON line with its status, duration and size
+	BindAddress             string                 // Interface address to listen on, all interfaces if empty
+	ExposeInstanceHeader    bool                   // Set X-Kubedepot-Instance with the hostname and version on all responses
+	Version                 string                 // Version of the running build, reported by the instance header
+	OutputApiVersion        string                 // apiVersion of served configs, v1 if empty
+	Include                 []string               // Globs of config names to load, all configs if empty
+	CORSAllowedOrigins      []string               // Origins allowed to call the API from browsers, "*" for any, CORS is disabled if empty
+	ServerHeader            string                 // Value of the Server response header, omitted if empty
+	GetEmptyStatus          int                    // Status of get responses when there are no configs to merge, 200 or 404, 200 if 0
+	NameCase                string                 // Case of config names derived from file names, preserve, lower or upper
+	DefaultFormat           string                 // Format the index page fetches and downloads configs in, yaml or json
+	CurrentContextOnMissing string                 // What to do when the merged current-context has no context, clear or error
+	StrictServerURLs        bool                   // Fail configs with malformed, non-https or loopback server URLs instead of warning
+	ConvertCAFiles          bool                   // Inline certificate-authority files of configs as certificate-authority-data
+	LoadConcurrency         int                    // Config files loaded in parallel, GOMAXPROCS if 0
+	ResolveServerHosts      bool                   // Also check cluster server hosts resolve when loading configs
 }
 
-// NewServer creates a new server instance
+// NewServer creates a new server instance and loads its configs, or defers loading them
+// until Start with AsyncLoad
 func NewServer(appConfig *Server) (*Server, error) {
+	server, err := newServer(appConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if server.AsyncLoad {
+		server.Logger.Info("Deferring config loading until the server starts")
+	} else if err := server.loadStartupConfigs(); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// NewValidationServer creates a server like NewServer without loading its configs, so they
+// can be checked with ValidateConfigs without serving them
+func NewValidationServer(appConfig *Server) (*Server, error) {
+	return newServer(appConfig)
+}
+
+// newServer creates a new server instance from its settings, validating them and loading
+// groups, but not configs
+func newServer(appConfig *Server) (*Server, error) {
 	server := &Server{
-		ConfigsDir:    appConfig.ConfigsDir,
-		WebDir:        appConfig.WebDir,
-		Logger:        appConfig.Logger,
-		LoadedConfigs: make(map[string]*KubeConfig),
-		EmbeddedFiles: appConfig.EmbeddedFiles,
+		ConfigsDir:              appConfig.ConfigsDir,
+		WebDir:                  appConfig.WebDir,
+		Logger:                  appConfig.Logger,
+		LoadedConfigs:           make(map[string]*KubeConfig),
+		ConfigMeta:              make(map[string]*ConfigMeta),
+		EmbeddedFiles:           appConfig.EmbeddedFiles,
+		MaxScanDepth:            appConfig.MaxScanDepth,
+		ListenSocket:            appConfig.ListenSocket,
+		Debug:                   appConfig.Debug,
+		ResponseDelay:           appConfig.ResponseDelay,
+		SkipMergeValidation:     appConfig.SkipMergeValidation,
+		RequestIDHeader:         appConfig.RequestIDHeader,
+		DefaultCurrentContext:   appConfig.DefaultCurrentContext,
+		MaxResponseSize:         appConfig.MaxResponseSize,
+		Normalize:               appConfig.Normalize,
+		SingleFile:              appConfig.SingleFile,
+		Watch:                   appConfig.Watch,
+		CompressPaths:           appConfig.CompressPaths,
+		Maintenance:             appConfig.Maintenance,
+		AdminToken:              appConfig.AdminToken,
+		RequireName:             appConfig.RequireName,
+		GroupsFile:              appConfig.GroupsFile,
+		TLSCertFile:             appConfig.TLSCertFile,
+		TLSKeyFile:              appConfig.TLSKeyFile,
+		IdleTimeout:             appConfig.IdleTimeout,
+		DisableKeepAlive:        appConfig.DisableKeepAlive,
+		AuthToken:               appConfig.AuthToken,
+		ForceSecure:             appConfig.ForceSecure,
+		TemplateLeftDelim:       appConfig.TemplateLeftDelim,
+		TemplateRightDelim:      appConfig.TemplateRightDelim,
+		SkipInvalidConfigs:      appConfig.SkipInvalidConfigs,
+		CurrentContextPriority:  appConfig.CurrentContextPriority,
+		SecretSource:            appConfig.SecretSource,
+		SecretNamespace:         appConfig.SecretNamespace,
+		SecretLabelSelector:     appConfig.SecretLabelSelector,
+		SecretClient:            appConfig.SecretClient,
+		MergeConflictStrategy:   appConfig.MergeConflictStrategy,
+		PrefixWithConfigName:    appConfig.PrefixWithConfigName,
+		ContextNamePattern:      appConfig.ContextNamePattern,
+		ConfigDropWarnThreshold: appConfig.ConfigDropWarnThreshold,
+		MaxRawCache:             appConfig.MaxRawCache,
+		RecursiveConfigs:        appConfig.RecursiveConfigs,
+		SecurityHeaders:         appConfig.SecurityHeaders,
+		AllowedExtensions:       appConfig.AllowedExtensions,
+		LogQueryParams:          appConfig.LogQueryParams,
+		RedactQueryParams:       appConfig.RedactQueryParams,
+		AsyncLoad:               appConfig.AsyncLoad,
+		AccessLog:               appConfig.AccessLog,
+		BindAddress:             appConfig.BindAddress,
+		ExposeInstanceHeader:    appConfig.ExposeInstanceHeader,
+		Version:                 appConfig.Version,
+		OutputApiVersion:        appConfig.OutputApiVersion,
+		Include:                 appConfig.Include,
+		CORSAllowedOrigins:      appConfig.CORSAllowedOrigins,
+		ServerHeader:            appConfig.ServerHeader,
+		GetEmptyStatus:          appConfig.GetEmptyStatus,
+		NameCase:                appConfig.NameCase,
+		DefaultFormat:           appConfig.DefaultFormat,
+		CurrentContextOnMissing: appConfig.CurrentContextOnMissing,
+		StrictServerURLs:        appConfig.StrictServerURLs,
+		ConvertCAFiles:          appConfig.ConvertCAFiles,
+		LoadConcurrency:         appConfig.LoadConcurrency,
+		ResolveServerHosts:      appConfig.ResolveServerHosts,
 	}
+	server.maintenance.Store(appConfig.Maintenance)
 
-	// Load all configs on startup
-	if err := server.loadAllConfigs(); err != nil {
-		return nil, errorx.Decorate(err, "failed to load configs on startup")
+	if err := server.validateMergeConflictStrategy(); err != nil {
+		return nil, errorx.Decorate(err, "invalid merge configuration")
+	}
+	if err := server.validateGetEmptyStatus(); err != nil {
+		return nil, errorx.Decorate(err, "invalid merge configuration")
+	}
+	if err := server.validateNameCase(); err != nil {
+		return nil, errorx.Decorate(err, "invalid naming configuration")
+	}
+	if err := server.validateDefaultFormat(); err != nil {
+		return nil, errorx.Decorate(err, "invalid index configuration")
+	}
+	if err := server.validateCurrentContextOnMissing(); err != nil {
+		return nil, errorx.Decorate(err, "invalid merge configuration")
 	}
 
-	// Test that all configs can be merged together
-	if err := server.validateAllConfigsMergeable(); err != nil {
-		return nil, errorx.Decorate(err, "configs cannot be merged together")
+	if server.ContextNamePattern != "" {
+		pattern, err := regexp.Compile(server.ContextNamePattern)
+		if err != nil {
+			return nil, errorx.Decorate(err, "invalid context name pattern")
+		}
+		server.contextName = pattern
+	}
+
+	for _, pattern := range server.Include {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errorx.IllegalArgument.New("invalid include pattern: %q", pattern)
+		}
+	}
+
+	if err := server.validateBindAddress(); err != nil {
+		return nil, errorx.Decorate(err, "invalid listen configuration")
+	}
+
+	// Fail fast on incomplete or unreadable TLS settings
+	if err := server.validateTLSFiles(); err != nil {
+		return nil, errorx.Decorate(err, "invalid TLS configuration")
+	}
+
+	// Load config groups, they are checked once configs are loaded
+	if err := server.loadGroups(); err != nil {
+		return nil, errorx.Decorate(err, "failed to load groups on startup")
 	}
 
 	// Check that index can be generated
@@ -54,6 +259,77 @@ func NewServer(appConfig *Server) (*Server, error) {
 
 // Note: Start method moved to router.go for better separation of concerns
 
+// loadStartupConfigs loads all configs, checks the groups only refer to loaded configs and
+// that all configs can be merged together, then marks the server ready
+func (s *Server) loadStartupConfigs() error {
+	if err := s.loadAllConfigs(); err != nil {
+		return errorx.Decorate(err, "failed to load configs on startup")
+	}
+	if err := s.validateGroups(); err != nil {
+		return errorx.Decorate(err, "failed to validate groups on startup")
+	}
+
+	if s.SkipMergeValidation {
+		s.Logger.Info("Skipping validation that all configs can be merged together")
+	} else if err := s.validateAllConfigsMergeable(); err != nil {
+		return errorx.Decorate(err, "configs cannot be merged together")
+	}
+	s.ready.Store(true)
+	return nil
+}
+
+// ValidateConfigs loads all configs and checks them the way startup does without serving
+// them, e.g. to validate a configs directory in CI. Unlike startup, configs skipped as
+// invalid fail the validation. The number of valid configs is returned
+func (s *Server) ValidateConfigs() (int, error) {
+	if err := s.loadStartupConfigs(); err != nil {
+		return 0, err
+	}
+	if invalid := s.InvalidConfigs(); len(invalid) > 0 {
+		reasons := make([]string, 0, len(invalid))
+		for _, config := range invalid {
+			reasons = append(reasons, config.Name+": "+config.Error)
+		}
+		return 0, errorx.IllegalArgument.New("%d configs are invalid: %s", len(invalid), strings.Join(reasons, "; "))
+	}
+	return len(s.getAllConfigNames()), nil
+}
+
+// asyncLoadRetryInterval is how long to wait before retrying a failed initial load in
+// async mode
+var asyncLoadRetryInterval = 5 * time.Second
+
+// loadUntilReady loads configs in the background for AsyncLoad, retrying until a load
+// succeeds or stop is closed. /readyz reports 503 until then
+func (s *Server) loadUntilReady(stop <-chan struct{}) {
+	for {
+		err := s.loadStartupConfigs()
+		if err == nil {
+			s.Logger.Info("Initial configs loaded, server is ready")
+			return
+		}
+		s.Logger.Error("Failed to load configs, retrying", "error", err, "retryIn", asyncLoadRetryInterval)
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(asyncLoadRetryInterval):
+		}
+	}
+}
+
+// templateFuncs are the functions available to the index template
+var templateFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"join":  strings.Join,
+}
+
+// newIndexTemplate creates the index template with the configured delimiters and functions
+func (s *Server) newIndexTemplate() *template.Template {
+	return template.New("index.html").Delims(s.TemplateLeftDelim, s.TemplateRightDelim).Funcs(templateFuncs)
+}
+
 func (s *Server) TemplateIndex(w http.ResponseWriter) error {
 	var tmpl *template.Template
 	var err error
@@ -61,7 +337,7 @@ func (s *Server) TemplateIndex(w http.ResponseWriter) error {
 	// Try to load from WebDir first (for development)
 	templatePath := filepath.Join(s.WebDir, "index.html")
 	if _, err := os.Stat(templatePath); err == nil {
-		tmpl, err = template.ParseFiles(templatePath)
+		tmpl, err = s.newIndexTemplate().ParseFiles(templatePath)
 		if err != nil {
 			return errorx.Decorate(err, "failed to parse index template file from WebDir")
 		}
@@ -71,7 +347,7 @@ func (s *Server) TemplateIndex(w http.ResponseWriter) error {
 		if err != nil {
 			return errorx.Decorate(err, "failed to read embedded index template")
 		}
-		tmpl, err = template.New("index.html").Parse(string(templateContent))
+		tmpl, err = s.newIndexTemplate().Parse(string(templateContent))
 		if err != nil {
 			return errorx.Decorate(err, "failed to parse embedded index template")
 		}
@@ -79,12 +355,9 @@ func (s *Server) TemplateIndex(w http.ResponseWriter) error {
 		return errorx.InternalError.New("neither WebDir nor EmbeddedFiles available for template")
 	}
 
-	names, err := s.listConfigs()
+	vals, err := s.indexData()
 	if err != nil {
-		return errorx.Decorate(err, "failed to list configs in dir")
-	}
-	vals := map[string][]string{
-		"names": names,
+		return err
 	}
 
 	// Only execute the template if the writer is not nil
@@ -98,6 +371,42 @@ func (s *Server) TemplateIndex(w http.ResponseWriter) error {
 	return nil
 }
 
+// Formats the index page can fetch configs in
+const (
+	formatYAML = "yaml"
+	formatJSON = "json"
+)
+
+// indexFormat returns the format the index page fetches configs in, YAML by default
+func (s *Server) indexFormat() string {
+	if s.DefaultFormat == "" {
+		return formatYAML
+	}
+	return s.DefaultFormat
+}
+
+// validateDefaultFormat checks DefaultFormat is a format the index page can fetch
+func (s *Server) validateDefaultFormat() error {
+	switch s.DefaultFormat {
+	case "", formatYAML, formatJSON:
+		return nil
+	default:
+		return errorx.IllegalArgument.New("default format must be yaml or json, got %q", s.DefaultFormat)
+	}
+}
+
+// indexData returns the values the index template is rendered with
+func (s *Server) indexData() (map[string]any, error) {
+	names, err := s.listConfigs()
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to list configs in dir")
+	}
+	return map[string]any{
+		"names":         names,
+		"defaultFormat": s.indexFormat(),
+	}, nil
+}
+
 // Index handles the root route
 func (s *Server) HandleIndex(w http.ResponseWriter, r *http.Request) {
 	err := s.TemplateIndex(w)
@@ -113,17 +422,60 @@ func (s *Server) HandleListConfigsYaml(w http.ResponseWriter, r *http.Request) {
 
 // ListConfigsJson lists all available kubeconfigs in JSON format
 func (s *Server) HandleListConfigsJson(w http.ResponseWriter, r *http.Request) {
-	s.HandleListConfigs(w, r, createJSONEncoder)
+	s.handleListConfigs(w, r, createJSONEncoder, true)
 }
 
 // GetKubeConfigsYaml returns a merged kubeconfig in YAML format
 func (s *Server) HandleGetKubeConfigsYaml(w http.ResponseWriter, r *http.Request) {
+	if s.serveCachedAllConfigs(w, r, formatYAML) {
+		return
+	}
 	s.HandleGetKubeConfigs(w, r, createYAMLEncoder)
 }
 
 // GetKubeConfigsJson returns a merged kubeconfig in JSON format
 func (s *Server) HandleGetKubeConfigsJson(w http.ResponseWriter, r *http.Request) {
-	s.HandleGetKubeConfigs(w, r, createJSONEncoder)
+	if s.serveCachedAllConfigs(w, r, formatJSON) {
+		return
+	}
+	s.getKubeConfigs(w, r, createJSONEncoder, true)
+}
+
+// pathFormatEncoders maps file extensions to encoders for extension-style URLs
+var pathFormatEncoders = map[string]func(io.Writer) Encoder{
+	".yaml": createYAMLEncoder,
+	".yml":  createYAMLEncoder,
+	".json": createJSONEncoder,
+}
+
+// pathSelectionParams are query parameters selecting configs besides name, rejected by
+// extension-style URLs that name a single config
+var pathSelectionParams = []string{"group", "label", "selector"}
+
+// HandleGetKubeConfigByPath returns a kubeconfig for URLs like /get/dev.yaml,
+// the extension chooses the format and the basename is the config name
+func (s *Server) HandleGetKubeConfigByPath(w http.ResponseWriter, r *http.Request) {
+	file := r.PathValue("file")
+	ext := filepath.Ext(file)
+	encoder, ok := pathFormatEncoders[ext]
+	if !ok {
+		s.handleHTTPError(w, nil, "Unsupported format extension: "+ext, http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	for _, param := range pathSelectionParams {
+		if query.Has(param) {
+			s.handleHTTPError(w, nil, "Parameter not supported with a config path: "+param, http.StatusBadRequest)
+			return
+		}
+	}
+
+	r = r.Clone(r.Context())
+	query.Set("name", strings.TrimSuffix(file, ext))
+	r.URL.RawQuery = query.Encode()
+
+	s.getKubeConfigs(w, r, encoder, ext == ".json")
 }
 
 // Define an Encoder interface
@@ -133,25 +485,30 @@ type Encoder interface {
 
 // Note: handleHTTPError moved to errors.go for better organization
 
-// createYAMLEncoder creates a YAML encoder with consistent formatting
+// createYAMLEncoder creates a YAML encoder with consistent formatting. Strings that would
+// otherwise be read as other types, e.g. a token 0123 or a name yes, are always quoted
 func createYAMLEncoder(w io.Writer) Encoder {
 	enc := yaml.NewEncoder(w)
 	enc.SetIndent(2)
 	return enc
 }
 
-// createJSONEncoder creates a JSON encoder
+// createJSONEncoder creates a JSON encoder. Output is deterministic: struct fields are
+// emitted in declaration order and encoding/json sorts map keys, e.g. of user credentials
 func createJSONEncoder(w io.Writer) Encoder {
 	return json.NewEncoder(w)
 }
 
-// listConfigs returns all available config names from the loaded configs
+// listConfigs returns all available config names from the loaded configs, sorted by name
 func (s *Server) listConfigs() ([]string, error) {
 	s.Logger.Info("Listing configs")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	configNames := make([]string, 0, len(s.LoadedConfigs))
 	for name := range s.LoadedConfigs {
 		configNames = append(configNames, name)
 	}
+	slices.Sort(configNames)
 	return configNames, nil
 }
 
@@ -160,16 +517,53 @@ func (s *Server) HandleListConfigs(
 	w http.ResponseWriter,
 	r *http.Request,
 	encoder func(io.Writer) Encoder,
+) {
+	s.handleListConfigs(w, r, encoder, false)
+}
+
+// handleListConfigs returns all available kubeconfigs. With stream the names are written
+// as a JSON array one at a time instead of with the encoder, see writeJSONStringArray
+func (s *Server) handleListConfigs(
+	w http.ResponseWriter,
+	r *http.Request,
+	encoder func(io.Writer) Encoder,
+	stream bool,
 ) {
 	s.Logger.Info("HandleListConfigs")
+	if r.URL.Query().Get("invalid") == "true" {
+		s.handleListInvalidConfigs(w, r, encoder)
+		return
+	}
+
 	names, err := s.listConfigs()
 	if err != nil {
 		s.handleHTTPError(w, err, "Failed to list configs in dir", http.StatusInternalServerError)
 		return
 	}
 
+	selector, hasSelector, err := requestSelector(r)
+	if err != nil {
+		s.handleHTTPError(w, err, "Invalid label selector", http.StatusBadRequest)
+		return
+	}
+	if hasSelector {
+		names = s.filterConfigsBySelector(names, selector)
+	}
+
+	// Let clients get the count cheaply with a HEAD request
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(names)))
+	if r.Method == http.MethodHead {
+		return
+	}
+
 	// w.Header().Set("Content-Type", "application/json")
-	err = encoder(w).Encode(names)
+	if stream {
+		err = writeStreamWithETag(w, r, func(out io.Writer) error {
+			return writeJSONStringArray(out, names)
+		})
+	} else {
+		err = writeWithETag(w, r, encoder, names)
+	}
 	if err != nil {
 		s.handleHTTPError(w, err, "Failed to encode configs list", http.StatusInternalServerError)
 		return
@@ -178,60 +572,497 @@ func (s *Server) HandleListConfigs(
 	s.Logger.Debug("Listed configs", "names", names)
 }
 
-// getRequestedConfigNames extracts requested config names from query parameters
-func (s *Server) getRequestedConfigNames(r *http.Request, allConfigNames []string) []string {
+// InvalidConfig is a config that failed to load and was skipped
+type InvalidConfig struct {
+	Name  string `json:"name"  yaml:"name"`
+	Error string `json:"error" yaml:"error"`
+}
+
+// InvalidConfigs returns the configs that failed to load and were skipped, sorted by name
+func (s *Server) InvalidConfigs() []InvalidConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	invalid := make([]InvalidConfig, 0, len(s.invalid))
+	for name, err := range s.invalid {
+		invalid = append(invalid, InvalidConfig{Name: name, Error: err})
+	}
+	slices.SortFunc(invalid, func(a, b InvalidConfig) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return invalid
+}
+
+// handleListInvalidConfigs returns the configs that failed to load and why
+func (s *Server) handleListInvalidConfigs(
+	w http.ResponseWriter,
+	r *http.Request,
+	encoder func(io.Writer) Encoder,
+) {
+	invalid := s.InvalidConfigs()
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(invalid)))
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	err := encoder(w).Encode(invalid)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to encode invalid configs list", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ConfigContext is a config name with the current context declared in its file
+type ConfigContext struct {
+	Name           string `json:"name"            yaml:"name"`
+	CurrentContext string `json:"current-context" yaml:"current-context"`
+}
+
+// listConfigContexts returns all loaded configs with their declared current contexts,
+// sorted by name
+func (s *Server) listConfigContexts() []ConfigContext {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	contexts := make([]ConfigContext, 0, len(s.LoadedConfigs))
+	for name, kubeConfig := range s.LoadedConfigs {
+		contexts = append(contexts, ConfigContext{Name: name, CurrentContext: kubeConfig.CurrentContext})
+	}
+	slices.SortFunc(contexts, func(a, b ConfigContext) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return contexts
+}
+
+// HandleListConfigContexts returns all configs with their declared current contexts,
+// an empty string is returned for configs without one
+func (s *Server) HandleListConfigContexts(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info("HandleListConfigContexts")
+	contexts := s.listConfigContexts()
+
+	err := createJSONEncoder(w).Encode(contexts)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to encode configs list", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ConfigDetail describes a config for a frontend table, without credentials
+type ConfigDetail struct {
+	Name           string `json:"name"           yaml:"name"`
+	ClusterName    string `json:"clusterName"    yaml:"clusterName"`
+	Server         string `json:"server"         yaml:"server"`
+	CurrentContext string `json:"currentContext" yaml:"currentContext"`
+	Contexts       int    `json:"contexts"       yaml:"contexts"`
+}
+
+// detail describes the config by the cluster its current context refers to, the first
+// cluster if it has no current context
+func (k *KubeConfig) detail(name string) ConfigDetail {
+	detail := ConfigDetail{Name: name, CurrentContext: k.CurrentContext, Contexts: len(k.Contexts)}
+	var clusterName string
+	for _, context := range k.Contexts {
+		if context.Name == k.CurrentContext {
+			clusterName = context.Context.Cluster
+		}
+	}
+	for _, cluster := range k.Clusters {
+		if clusterName == "" || cluster.Name == clusterName {
+			detail.ClusterName, detail.Server = cluster.Name, cluster.Cluster.Server
+			break
+		}
+	}
+	return detail
+}
+
+// listConfigDetails returns the details of all loaded configs sorted by name
+func (s *Server) listConfigDetails() []ConfigDetail {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	details := make([]ConfigDetail, 0, len(s.LoadedConfigs))
+	for name, kubeConfig := range s.LoadedConfigs {
+		details = append(details, kubeConfig.detail(name))
+	}
+	slices.SortFunc(details, func(a, b ConfigDetail) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return details
+}
+
+// HandleConfigsDetail returns all configs with their cluster, server URL, current context
+// and number of contexts, so a frontend can show more than the plain names list
+func (s *Server) HandleConfigsDetail(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info("HandleConfigsDetail")
+	details := s.listConfigDetails()
+
+	err := createJSONEncoder(w).Encode(details)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to encode configs list", http.StatusInternalServerError)
+		return
+	}
+}
+
+// getRequestedConfigNames extracts requested config names from name and group query parameters,
+// configs matching the selector and label query parameters are requested as well
+func (s *Server) getRequestedConfigNames(r *http.Request, allConfigNames []string) ([]string, error) {
 	names := r.URL.Query()["name"]
-	if len(names) == 0 {
+	groups := r.URL.Query()["group"]
+	selector, hasSelector, err := requestSelector(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 && len(groups) == 0 && !hasSelector {
 		s.Logger.Info("No config names provided, getting all configs")
-		return allConfigNames
+		return allConfigNames, nil
+	}
+
+	names, err = s.expandGroups(names, groups)
+	if err != nil {
+		return nil, err
+	}
+	if hasSelector {
+		matching := s.filterConfigsBySelector(allConfigNames, selector)
+		if len(matching) == 0 && len(names) == 0 {
+			return nil, NotFound.New("no kubeconfigs match label selector")
+		}
+		for _, name := range matching {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
 	}
 	s.Logger.Info("Getting configs", "names", names)
-	return names
+	return names, nil
+}
+
+// requireRequestedNames writes a 400 response if RequireName is set and the request names
+// no configs, so callers must be explicit instead of implicitly getting all configs
+func (s *Server) requireRequestedNames(w http.ResponseWriter, r *http.Request) bool {
+	query := r.URL.Query()
+	if s.RequireName && len(query["name"]) == 0 && len(query["group"]) == 0 &&
+		len(query["label"]) == 0 && query.Get("selector") == "" {
+		http.Error(w, "At least one name query parameter is required", http.StatusBadRequest)
+		return false
+	}
+	return true
 }
 
 // validateConfigExists checks if a config name exists in the loaded configs
 func (s *Server) validateConfigExists(name string) error {
-	if _, exists := s.LoadedConfigs[name]; !exists {
-		return errorx.InternalError.New("kubeconfig not found: %s", name)
+	_, err := s.lookupConfig(name)
+	return err
+}
+
+// lookupConfig returns a loaded config by name
+func (s *Server) lookupConfig(name string) (*KubeConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	kubeConfig, exists := s.LoadedConfigs[name]
+	if !exists {
+		suggestions := similarConfigNames(slices.Collect(maps.Keys(s.LoadedConfigs)), name)
+		return nil, NotFound.New("kubeconfig not found: %s", name).WithProperty(SuggestionsProperty, suggestions)
+	}
+	return kubeConfig, nil
+}
+
+const (
+	mergeModeError = "error"
+	mergeModeSkip  = "skip"
+)
+
+// mergeOptions controls how missing and conflicting configs are handled when merging
+type mergeOptions struct {
+	skipMissing    bool   // Skip requested configs that don't exist instead of failing
+	skipConflicts  bool   // Skip configs with names already merged from other configs instead of failing
+	currentFrom    string // Config whose current context becomes the merged one, if set
+	currentContext string // Context that becomes the merged current one, if set
+}
+
+// MergeWarning describes a requested config that was skipped while merging
+type MergeWarning struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// MergeSummary is a merged config together with the configs skipped to build it
+type MergeSummary struct {
+	Config   *KubeConfig    `json:"config"`
+	Warnings []MergeWarning `json:"warnings"`
+}
+
+// parseMergeMode parses an on-missing or on-conflict query parameter, true means skip
+func parseMergeMode(r *http.Request, param string) (bool, error) {
+	switch mode := r.URL.Query().Get(param); mode {
+	case "", mergeModeError:
+		return false, nil
+	case mergeModeSkip:
+		return true, nil
+	default:
+		return false, errorx.IllegalArgument.New("unsupported %s mode: %s", param, mode)
+	}
+}
+
+// parseMergeOptions reads merge options from the on-missing, on-conflict and current-from
+// query parameters
+func parseMergeOptions(r *http.Request) (mergeOptions, error) {
+	skipMissing, err := parseMergeMode(r, "on-missing")
+	if err != nil {
+		return mergeOptions{}, err
+	}
+	skipConflicts, err := parseMergeMode(r, "on-conflict")
+	if err != nil {
+		return mergeOptions{}, err
+	}
+	return mergeOptions{
+		skipMissing:    skipMissing,
+		skipConflicts:  skipConflicts,
+		currentFrom:    r.URL.Query().Get("current-from"),
+		currentContext: r.URL.Query().Get("current-context"),
+	}, nil
+}
+
+// validateContextNames checks all context names of a config match ContextNamePattern
+func (s *Server) validateContextNames(kubeConfig *KubeConfig) error {
+	if s.contextName == nil {
+		return nil
+	}
+	for _, context := range kubeConfig.Contexts {
+		if !s.contextName.MatchString(context.Name) {
+			return errorx.IllegalArgument.New(
+				"context name %q doesn't match pattern %s", context.Name, s.contextName)
+		}
+	}
+	return nil
+}
+
+// checkServerURLs logs a warning for each suspicious cluster server URL of a loaded config,
+// see serverURLProblems, and with ResolveServerHosts for each host that doesn't resolve.
+// With StrictServerURLs they fail the config instead
+func (s *Server) checkServerURLs(kubeConfig *KubeConfig, name string) error {
+	problems := kubeConfig.serverURLProblems()
+	if s.ResolveServerHosts {
+		problems = append(problems, kubeConfig.unresolvableServerHosts()...)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	if s.StrictServerURLs {
+		return errorx.DecorateMany("invalid server URLs", problems...)
+	}
+	for _, problem := range problems {
+		s.Logger.Warn("Suspicious server URL", "config", name, "problem", problem)
+	}
+	return nil
+}
+
+// validateServerURL checks a server override is an absolute http(s) URL
+func validateServerURL(server string) error {
+	parsed, err := url.Parse(server)
+	if err != nil {
+		return err
+	}
+	if (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return errorx.IllegalArgument.New("server must be an absolute http(s) URL: %s", server)
+	}
+	return nil
+}
+
+// resolveConflicts applies MergeConflictStrategy to a config about to be merged into merged.
+// With the rename strategy conflicting names are prefixed with the config name, otherwise
+// the config is returned as is and the merge fails on conflicts
+func (s *Server) resolveConflicts(merged *KubeConfig, config *KubeConfig, name string) *KubeConfig {
+	if s.MergeConflictStrategy != MergeConflictRename {
+		return config
+	}
+	renamedConfig, renamed := merged.renameConflicts(config, name)
+	if len(renamed) > 0 {
+		s.Logger.Debug("Renamed conflicting entries", "config", name, "names", renamed)
+	}
+	return renamedConfig
+}
+
+// validateMergeConflictStrategy checks MergeConflictStrategy is a known strategy
+func (s *Server) validateMergeConflictStrategy() error {
+	switch s.MergeConflictStrategy {
+	case "", MergeConflictError, MergeConflictRename:
+		return nil
+	default:
+		return errorx.IllegalArgument.New("unknown merge conflict strategy: %s", s.MergeConflictStrategy)
+	}
+}
+
+// validateGetEmptyStatus checks GetEmptyStatus is a supported status code
+func (s *Server) validateGetEmptyStatus() error {
+	switch s.GetEmptyStatus {
+	case 0, http.StatusOK, http.StatusNotFound:
+		return nil
+	default:
+		return errorx.IllegalArgument.New("get empty status must be 200 or 404, got %d", s.GetEmptyStatus)
+	}
+}
+
+// Ways of handling a merged current-context that has no context, see CurrentContextOnMissing
+const (
+	CurrentContextOnMissingClear = "clear" // Serve the config without a current context
+	CurrentContextOnMissingError = "error" // Fail the merge
+)
+
+// validateCurrentContextOnMissing checks CurrentContextOnMissing is a known handling
+func (s *Server) validateCurrentContextOnMissing() error {
+	switch s.CurrentContextOnMissing {
+	case "", CurrentContextOnMissingClear, CurrentContextOnMissingError:
+		return nil
+	default:
+		return errorx.IllegalArgument.New("unknown current context on missing handling: %s", s.CurrentContextOnMissing)
 	}
+}
+
+// checkCurrentContext handles a current-context of a merged config that doesn't match any
+// of its contexts, e.g. one of a merged config that doesn't define it or taken with
+// current-from. It's cleared or the merge fails according to CurrentContextOnMissing
+func (s *Server) checkCurrentContext(kubeConfig *KubeConfig) error {
+	if kubeConfig.CurrentContext == "" || kubeConfig.hasContext(kubeConfig.CurrentContext) {
+		return nil
+	}
+	if s.CurrentContextOnMissing == CurrentContextOnMissingError {
+		return errorx.IllegalArgument.New(
+			"current-context isn't among the merged contexts: %s", kubeConfig.CurrentContext)
+	}
+	s.Logger.Warn("Clearing current-context missing from the merged contexts", "context", kubeConfig.CurrentContext)
+	kubeConfig.CurrentContext = ""
 	return nil
 }
 
-// loadAndMergeConfigs loads and merges multiple kubeconfigs from pre-loaded configs
+// loadAndMergeConfigs loads and merges multiple kubeconfigs from pre-loaded configs,
+// failing on any missing or conflicting config
 func (s *Server) loadAndMergeConfigs(names []string) (interface{}, error) {
+	kubeConfig, _, err := s.mergeConfigs(names, mergeOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return kubeConfig, nil
+}
+
+// mergeConfigs merges pre-loaded configs and returns warnings for the configs skipped
+// according to opts
+func (s *Server) mergeConfigs(names []string, opts mergeOptions) (*KubeConfig, []MergeWarning, error) {
 	// Create empty kubeconfig
 	kubeConfig, err := NewKubeConfig("", s.Logger)
 	if err != nil {
-		return nil, errorx.Decorate(err, "failed to create empty kubeconfig")
+		return nil, nil, errorx.Decorate(err, "failed to create empty kubeconfig")
 	}
 
 	s.Logger.Debug("Empty kubeconfig", "kubeconfig", kubeConfig)
 
 	// For each requested config
+	warnings := []MergeWarning{}
+	merged := make(map[string]*KubeConfig, len(names))
 	for _, name := range names {
 		// Validate config exists
-		if err := s.validateConfigExists(name); err != nil {
-			return nil, err
+		kubeConfigNew, err := s.lookupConfig(name)
+		if err != nil && opts.skipMissing {
+			s.Logger.Warn("Skipping missing config", "name", name)
+			warnings = append(warnings, MergeWarning{Name: name, Reason: err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if opts.skipConflicts {
+			if err := kubeConfig.HasDuplicateNames(kubeConfigNew); err != nil {
+				s.Logger.Warn("Skipping conflicting config", "name", name, "error", err)
+				warnings = append(warnings, MergeWarning{Name: name, Reason: err.Error()})
+				continue
+			}
+		} else {
+			kubeConfigNew = s.resolveConflicts(kubeConfig, kubeConfigNew, name)
 		}
+		merged[name] = kubeConfigNew
 
 		s.Logger.Debug("Using pre-loaded kubeconfig", "name", name)
-		kubeConfigNew := s.LoadedConfigs[name]
 
 		kubeConfig, err = mergeKubeConfigs(kubeConfig, kubeConfigNew)
 		if err != nil {
-			return nil, errorx.Decorate(err, "failed to merge kubeconfig: %s", name)
+			return nil, nil, errorx.Decorate(err, "failed to merge kubeconfig: %s", name)
 		}
 	}
 
-	return kubeConfig, nil
+	// Prefer the current context of the first merged config in the priority list, the
+	// default context only applies when the priority list chose nothing
+	prioritized := false
+	for _, name := range s.CurrentContextPriority {
+		if config, ok := merged[name]; ok && config.CurrentContext != "" {
+			kubeConfig.CurrentContext = config.CurrentContext
+			prioritized = true
+			break
+		}
+	}
+	if !prioritized && s.DefaultCurrentContext != "" && kubeConfig.hasContext(s.DefaultCurrentContext) {
+		kubeConfig.CurrentContext = s.DefaultCurrentContext
+	}
+	// Only an unset current context falls back to the first context, one that isn't among
+	// the merged contexts is left to checkCurrentContext
+	if kubeConfig.CurrentContext == "" && len(kubeConfig.Contexts) > 0 {
+		kubeConfig.CurrentContext = kubeConfig.Contexts[0].Name
+	}
+
+	// A config explicitly requested by the client wins over the server defaults
+	if opts.currentFrom != "" {
+		config, ok := merged[opts.currentFrom]
+		if !ok {
+			return nil, nil, errorx.IllegalArgument.New(
+				"current-from config isn't among the merged configs: %s", opts.currentFrom)
+		}
+		if config.CurrentContext == "" {
+			return nil, nil, errorx.IllegalArgument.New(
+				"current-from config has no current context: %s", opts.currentFrom)
+		}
+		kubeConfig.CurrentContext = config.CurrentContext
+	}
+	if opts.currentContext != "" {
+		if !kubeConfig.hasContext(opts.currentContext) {
+			return nil, nil, errorx.IllegalArgument.New(
+				"current-context isn't among the merged contexts: %s", opts.currentContext)
+		}
+		kubeConfig.CurrentContext = opts.currentContext
+	}
+	if err := s.checkCurrentContext(kubeConfig); err != nil {
+		return nil, nil, err
+	}
+
+	s.prepareServedConfig(kubeConfig)
+	return kubeConfig, warnings, nil
 }
 
-// GetKubeConfigs returns multiple kubeconfigs
-func (s *Server) HandleGetKubeConfigs(
+// prepareServedConfig applies the output settings of the server to a merged config
+func (s *Server) prepareServedConfig(kubeConfig *KubeConfig) {
+	// Compatibility shim for clients expecting another apiVersion
+	if s.OutputApiVersion != "" {
+		kubeConfig.ApiVersion = s.OutputApiVersion
+	}
+
+	if s.ForceSecure {
+		if clusters := kubeConfig.clearInsecureSkipTLSVerify(); len(clusters) > 0 {
+			s.Logger.Warn("Cleared insecure-skip-tls-verify of served clusters", "clusters", clusters)
+		}
+	}
+}
+
+// mergeRequestedConfigs merges the configs requested by query parameters,
+// on failure the error response is written and false is returned
+func (s *Server) mergeRequestedConfigs(
 	w http.ResponseWriter,
 	r *http.Request,
-	encoder func(io.Writer) Encoder,
-) {
+) (*KubeConfig, []MergeWarning, bool) {
+	if !s.requireRequestedNames(w, r) {
+		return nil, nil, false
+	}
+
+	opts, err := parseMergeOptions(r)
+	if err != nil {
+		s.handleHTTPError(w, err, "Invalid merge options", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
 	// Get all available config names
 	configNames, err := s.listConfigs()
 	if err != nil {
@@ -241,21 +1072,110 @@ func (s *Server) HandleGetKubeConfigs(
 			"Failed to read configs directory",
 			http.StatusInternalServerError,
 		)
-		return
+		return nil, nil, false
 	}
 
 	// Get requested config names from query parameters
-	requestedNames := s.getRequestedConfigNames(r, configNames)
+	requestedNames, err := s.getRequestedConfigNames(r, configNames)
+	if err != nil {
+		s.handleError(w, err, "Failed to resolve requested configs")
+		return nil, nil, false
+	}
+
+	// A reload may have removed all configs
+	if len(requestedNames) == 0 && s.GetEmptyStatus == http.StatusNotFound {
+		s.handleError(w, NotFound.New("no kubeconfigs to merge"), "")
+		return nil, nil, false
+	}
 
 	// Load and merge the requested configs
-	kubeConfig, err := s.loadAndMergeConfigs(requestedNames)
+	kubeConfig, warnings, err := s.mergeConfigs(requestedNames, opts)
 	if err != nil {
 		s.handleError(w, err, "Failed to load and merge configs")
+		return nil, nil, false
+	}
+
+	// Point the cluster at a client-chosen address, e.g. a port-forwarded localhost
+	if server := r.URL.Query().Get("server"); server != "" {
+		if err := validateServerURL(server); err != nil {
+			s.handleHTTPError(w, err, "Invalid server", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		if err := kubeConfig.overrideServer(server); err != nil {
+			s.handleHTTPError(w, err, "Failed to override server", http.StatusBadRequest)
+			return nil, nil, false
+		}
+	}
+	return kubeConfig, warnings, true
+}
+
+// GetKubeConfigs returns multiple kubeconfigs
+func (s *Server) HandleGetKubeConfigs(
+	w http.ResponseWriter,
+	r *http.Request,
+	encoder func(io.Writer) Encoder,
+) {
+	s.getKubeConfigs(w, r, encoder, false)
+}
+
+// getKubeConfigs writes the merged requested configs, wrapped in a MergeSummary when
+// summaryAllowed is set and the request asks for it with summary=true
+func (s *Server) getKubeConfigs(
+	w http.ResponseWriter,
+	r *http.Request,
+	encoder func(io.Writer) Encoder,
+	summaryAllowed bool,
+) {
+	kubeConfig, warnings, ok := s.mergeRequestedConfigs(w, r)
+	if !ok {
 		return
 	}
 
+	var response interface{} = kubeConfig
+	if summaryAllowed && r.URL.Query().Get("summary") == "true" {
+		response = MergeSummary{Config: kubeConfig, Warnings: warnings}
+	}
+
 	// Return the merged config
-	err = encoder(w).Encode(kubeConfig)
+	err := s.writeEncoded(w, r, encoder, response)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to serialize kubeconfig", http.StatusInternalServerError)
+		return
+	}
+}
+
+// defaultDownloadFilename is the file name of downloaded kubeconfigs, the same as ~/.kube/config
+const defaultDownloadFilename = "config"
+
+// sanitizeDownloadFilename strips path separators and quotes from a client-provided file name
+func sanitizeDownloadFilename(filename string) string {
+	filename = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == '"' || r < ' ' {
+			return -1
+		}
+		return r
+	}, filename)
+	if filename == "" || filename == "." || filename == ".." {
+		return defaultDownloadFilename
+	}
+	return filename
+}
+
+// HandleDownloadKubeConfig returns a merged kubeconfig in YAML format as a file attachment
+func (s *Server) HandleDownloadKubeConfig(w http.ResponseWriter, r *http.Request) {
+	kubeConfig, _, ok := s.mergeRequestedConfigs(w, r)
+	if !ok {
+		return
+	}
+
+	filename := defaultDownloadFilename
+	if requested := r.URL.Query().Get("filename"); requested != "" {
+		filename = sanitizeDownloadFilename(requested)
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	err := s.writeEncoded(w, r, createYAMLEncoder, kubeConfig)
 	if err != nil {
 		s.handleHTTPError(w, err, "Failed to serialize kubeconfig", http.StatusInternalServerError)
 		return
@@ -271,36 +1191,202 @@ func (s *Server) validateConfigsDirectory() error {
 	if err != nil {
 		return errorx.Decorate(err, "unexpected error checking config directory")
 	}
-	if !info.IsDir() {
+	if !info.IsDir() && !s.SingleFile {
 		return errorx.InternalError.New("config directory is not a directory: %s", s.ConfigsDir)
 	}
 	return nil
 }
 
-// readConfigFiles reads all files from the configs directory
-func (s *Server) readConfigFiles() ([]os.DirEntry, error) {
-	files, err := os.ReadDir(s.ConfigsDir)
+// DefaultAllowedExtensions are the file extensions loaded as configs when none are configured
+var DefaultAllowedExtensions = []string{".yaml", ".yml", ".json"}
+
+// isAllowedExtension reports whether a file has one of the extensions loaded as configs,
+// extensions are compared case-insensitively and an empty AllowedExtensions allows all
+func (s *Server) isAllowedExtension(fileName string) bool {
+	allowed := s.AllowedExtensions
+	if allowed == nil {
+		allowed = DefaultAllowedExtensions
+	}
+	if len(allowed) == 0 {
+		return true
+	}
+	ext := filepath.Ext(fileName)
+	return slices.ContainsFunc(allowed, func(a string) bool {
+		return strings.EqualFold("."+strings.TrimPrefix(a, "."), ext)
+	})
+}
+
+// isIncluded reports whether a config name matches one of the Include globs,
+// all configs are included if there are none
+func (s *Server) isIncluded(name string) bool {
+	if len(s.Include) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(s.Include, func(pattern string) bool {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	})
+}
+
+// configFile is a file found while scanning the configs directory
+type configFile struct {
+	path  string
+	entry fs.DirEntry
+}
+
+// readConfigFiles walks the configs directory and collects all files,
+// descending at most MaxScanDepth levels of subdirectories or all with RecursiveConfigs
+func (s *Server) readConfigFiles() ([]configFile, error) {
+	// In single file mode ConfigsDir may point to the only config file
+	if s.SingleFile {
+		info, err := os.Stat(s.ConfigsDir)
+		if err != nil {
+			return nil, errorx.Decorate(err, "failed to read config file")
+		}
+		if !info.IsDir() {
+			return []configFile{{path: s.ConfigsDir, entry: fs.FileInfoToDirEntry(info)}}, nil
+		}
+	}
+
+	// WalkDir doesn't follow a symlinked root, e.g. ConfigsDir pointing at the ..data link of
+	// a mounted ConfigMap or Secret, so walk its target and report paths under ConfigsDir
+	root, err := filepath.EvalSymlinks(s.ConfigsDir)
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to resolve configs directory")
+	}
+
+	var files []configFile
+	err = filepath.WalkDir(root, func(walked string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if walked == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, walked)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(s.ConfigsDir, rel)
+
+		if !d.IsDir() {
+			files = append(files, configFile{path: path, entry: d})
+			return nil
+		}
+
+		// Kubernetes ConfigMap metadata directories are never scanned
+		if strings.HasPrefix(d.Name(), "..") {
+			s.Logger.Debug("Skipping Kubernetes ConfigMap metadata directory", "dir", d.Name())
+			return filepath.SkipDir
+		}
+
+		// Symlinked directories aren't descended into, so links can't cause loops
+		if !s.RecursiveConfigs && s.scanDepth(path) > s.MaxScanDepth {
+			if s.MaxScanDepth > 0 {
+				s.Logger.Warn("Skipping directory beyond max scan depth",
+					"dir", path, "maxScanDepth", s.MaxScanDepth)
+			} else {
+				s.Logger.Debug("Skipping directory", "file", d.Name())
+			}
+			return filepath.SkipDir
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, errorx.Decorate(err, "failed to read configs directory")
 	}
 	return files, nil
 }
 
-// loadSingleConfig loads a single config file and stores it in LoadedConfigs
-func (s *Server) loadSingleConfig(file os.DirEntry) error {
+// scanDepth returns how many directories deep the path is relative to the configs directory
+func (s *Server) scanDepth(path string) int {
+	rel, err := filepath.Rel(s.ConfigsDir, path)
+	if err != nil {
+		return 0
+	}
+	return len(strings.Split(rel, string(filepath.Separator)))
+}
+
+// Cases of config names derived from file names
+const (
+	NameCasePreserve = "preserve" // Keep the case of the file name
+	NameCaseLower    = "lower"    // Lowercase, e.g. Prod-EU.yaml becomes prod-eu
+	NameCaseUpper    = "upper"    // Uppercase, e.g. Prod-EU.yaml becomes PROD-EU
+)
+
+// validateNameCase checks NameCase is a known case
+func (s *Server) validateNameCase() error {
+	switch s.NameCase {
+	case "", NameCasePreserve, NameCaseLower, NameCaseUpper:
+		return nil
+	default:
+		return errorx.IllegalArgument.New("unknown name case: %s", s.NameCase)
+	}
+}
+
+// configNameFromPath derives a config name from a file path relative to the configs directory,
+// nested directories are joined with "-" and the name is converted to NameCase
+func (s *Server) configNameFromPath(filePath string) string {
+	rel, err := filepath.Rel(s.ConfigsDir, filePath)
+	if err != nil || rel == "." {
+		rel = filepath.Base(filePath)
+	}
+	rel = strings.TrimSuffix(rel, filepath.Ext(rel))
+	return s.applyNameCase(strings.ReplaceAll(rel, string(filepath.Separator), "-"))
+}
+
+// applyNameCase converts a config name to NameCase
+func (s *Server) applyNameCase(name string) string {
+	switch s.NameCase {
+	case NameCaseLower:
+		return strings.ToLower(name)
+	case NameCaseUpper:
+		return strings.ToUpper(name)
+	default:
+		return name
+	}
+}
+
+// loadedConfig is a config loaded from a file together with its metadata
+type loadedConfig struct {
+	name       string
+	kubeConfig *KubeConfig
+	meta       *ConfigMeta
+	raw        *rawConfig // Original file content, only kept when the raw cache is enabled
+}
+
+// loadConfigFile loads a single config file, nil is returned for skipped files
+func (s *Server) loadConfigFile(filePath string, file fs.DirEntry) (*loadedConfig, error) {
 	// Skip directories
 	if file.IsDir() {
 		s.Logger.Debug("Skipping directory", "file", file.Name())
-		return nil
+		return nil, nil
 	}
 
-	filePath := filepath.Join(s.ConfigsDir, file.Name())
-
 	// Skip hidden files and Kubernetes ConfigMap metadata files
 	fileName := file.Name()
 	if strings.HasPrefix(fileName, "..") {
 		s.Logger.Debug("Skipping Kubernetes ConfigMap metadata file", "file", fileName)
-		return nil
+		return nil, nil
+	}
+
+	// Skip companion labels files, they are loaded along with their configs
+	if isLabelsFile(fileName) {
+		s.Logger.Debug("Skipping labels file", "file", fileName)
+		return nil, nil
+	}
+
+	// Skip the groups file if it's kept next to the configs
+	if s.isGroupsFile(filePath) {
+		s.Logger.Debug("Skipping groups file", "file", fileName)
+		return nil, nil
+	}
+
+	// Skip files that aren't configs, like READMEs dropped in the directory. A single config
+	// file given as ConfigsDir is loaded whatever its name, e.g. ~/.kube/config
+	if filePath != s.ConfigsDir && !s.isAllowedExtension(fileName) {
+		s.Logger.Debug("Skipping file with disallowed extension", "file", fileName)
+		return nil, nil
 	}
 
 	// Additional check: verify the file path is actually a regular file
@@ -308,53 +1394,337 @@ func (s *Server) loadSingleConfig(file os.DirEntry) error {
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
 		s.Logger.Debug("Skipping file due to stat error", "file", fileName, "error", err)
-		return nil
+		return nil, nil
 	}
 	if fileInfo.IsDir() {
 		s.Logger.Debug("Skipping directory", "file", fileName)
-		return nil
+		return nil, nil
 	}
 
-	configName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	configName := s.configNameFromPath(filePath)
+	if !s.isIncluded(configName) {
+		s.Logger.Debug("Skipping config not matching include patterns", "name", configName)
+		return nil, nil
+	}
 
 	s.Logger.Debug("Loading config file", "path", filePath, "name", configName)
 
-	kubeConfig, err := NewKubeConfig(filePath, s.Logger)
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to load kubeconfig: %s", filePath)
+	}
+	loaded, err := s.loadConfigData(configName, filePath, data)
+	if err != nil {
+		return nil, err
+	}
+	// Keep the original bytes for the raw cache so they aren't read again when served
+	if s.MaxRawCache > 0 {
+		loaded.raw = &rawConfig{data: data, modTime: fileInfo.ModTime()}
+	}
+	return loaded, nil
+}
+
+// loadConfigData loads the content of a config file, the same conversions and checks apply
+// to files of the configs directory and uploads written to it
+func (s *Server) loadConfigData(configName, filePath string, data []byte) (*loadedConfig, error) {
+	kubeConfig, err := parseKubeConfigFile(filePath, data)
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to load kubeconfig: %s", filePath)
+	}
+
+	if s.ConvertCAFiles {
+		if err := kubeConfig.inlineCertificateAuthorities(filepath.Dir(filePath)); err != nil {
+			return nil, errorx.Decorate(err, "failed to inline certificate authorities of kubeconfig: %s", filePath)
+		}
+	}
+
+	if s.Normalize {
+		kubeConfig, err = normalizeKubeConfig(kubeConfig)
+		if err != nil {
+			return nil, errorx.Decorate(err, "failed to normalize kubeconfig: %s", filePath)
+		}
+	}
+
+	if s.PrefixWithConfigName {
+		kubeConfig = kubeConfig.prefixAllNames(configName)
+	}
+
+	if err := s.validateContextNames(kubeConfig); err != nil {
+		return nil, errorx.Decorate(err, "invalid kubeconfig: %s", filePath)
+	}
+	if err := s.checkServerURLs(kubeConfig, configName); err != nil {
+		return nil, errorx.Decorate(err, "invalid kubeconfig: %s", filePath)
+	}
+
+	commentLabels, err := parseLabelsComment(data)
 	if err != nil {
-		return errorx.Decorate(err, "failed to load kubeconfig: %s", filePath)
+		return nil, errorx.Decorate(err, "failed to parse labels comment of kubeconfig: %s", filePath)
+	}
+	fileLabels, err := loadConfigLabels(filePath)
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to load labels of kubeconfig: %s", filePath)
+	}
+	labels := mergeLabels(commentLabels, fileLabels)
+
+	s.Logger.Debug("Successfully loaded config", "name", configName, "labels", labels)
+	return &loadedConfig{
+		name:       configName,
+		kubeConfig: kubeConfig,
+		meta:       &ConfigMeta{Labels: labels, Path: filePath},
+	}, nil
+}
+
+// loadSingleConfig loads a single config file and stores it in LoadedConfigs
+func (s *Server) loadSingleConfig(filePath string, file fs.DirEntry) error {
+	loaded, err := s.loadConfigFile(filePath, file)
+	if err != nil || loaded == nil {
+		return err
 	}
 
-	s.LoadedConfigs[configName] = kubeConfig
-	s.Logger.Debug("Successfully loaded config", "name", configName)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.invalidateMergedCache()
+	s.LoadedConfigs[loaded.name] = loaded.kubeConfig
+	if s.ConfigMeta == nil {
+		s.ConfigMeta = make(map[string]*ConfigMeta)
+	}
+	s.ConfigMeta[loaded.name] = loaded.meta
+	s.rawCache.put(loaded.name, loaded.raw)
 	return nil
 }
 
-// loadAllConfigs loads all config files from the configs directory into memory
-func (s *Server) loadAllConfigs() error {
-	s.Logger.Info("Loading all configs on startup", "configsDir", s.ConfigsDir)
+// configSet is the result of loading the configs directory
+type configSet struct {
+	configs map[string]*KubeConfig
+	meta    map[string]*ConfigMeta
+	raw     map[string]*rawConfig // Original file content of configs, for the raw cache
+	invalid map[string]string     // Error of each config that failed to load and was skipped
+}
+
+// newConfigSet creates an empty config set
+func newConfigSet(size int) *configSet {
+	return &configSet{
+		configs: make(map[string]*KubeConfig, size),
+		meta:    make(map[string]*ConfigMeta, size),
+		raw:     make(map[string]*rawConfig),
+		invalid: make(map[string]string),
+	}
+}
+
+// add adds a loaded config to the set. Two files mapping to the same config name, e.g.
+// Dev.yaml and dev.yaml with a lower NameCase, fail instead of one replacing the other
+func (c *configSet) add(loaded *loadedConfig) error {
+	if existing, exists := c.meta[loaded.name]; exists {
+		return errorx.IllegalState.New("config files %s and %s both map to config name %s",
+			existing.Path, loaded.meta.Path, loaded.name)
+	}
+	c.configs[loaded.name] = loaded.kubeConfig
+	c.meta[loaded.name] = loaded.meta
+	if loaded.raw != nil {
+		c.raw[loaded.name] = loaded.raw
+	}
+	return nil
+}
+
+// storeConfigSet makes the config set the served one and records which configs changed.
+// The raw cache is replaced as well, so bytes of previously loaded files are never served
+func (s *Server) storeConfigSet(set *configSet) {
+	cache := newRawCache(s.MaxRawCache)
+	for _, name := range slices.Sorted(maps.Keys(set.raw)) {
+		cache.put(name, set.raw[name])
+	}
+
+	// Changes are computed under the lock, once stored the maps may be modified by uploads
+	s.mu.Lock()
+	previous := s.LoadedConfigs
+	added, removed := configChanges(previous, set.configs)
+	previousCount, count := len(previous), len(set.configs)
+	s.LoadedConfigs = set.configs
+	s.ConfigMeta = set.meta
+	s.invalid = set.invalid
+	s.rawCache = cache
+	s.invalidateMergedCache()
+	s.mu.Unlock()
+
+	s.recordConfigChanges(added, removed, previousCount, count)
+}
+
+// readAllConfigs loads all config files from the configs directory, or all config Secrets
+// in SecretSource mode, into a fresh config set
+func (s *Server) readAllConfigs() (*configSet, error) {
+	if s.SecretSource {
+		return s.readSecretConfigs()
+	}
 
 	// Validate configs directory exists and is a directory
 	if err := s.validateConfigsDirectory(); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Read all files from the configs directory
 	files, err := s.readConfigFiles()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Load each config file
-	for _, file := range files {
-		if err := s.loadSingleConfig(file); err != nil {
-			return err
+	// Load the config files concurrently, then handle the results in the order of the files
+	results := s.loadConfigFiles(files)
+	set := newConfigSet(len(files))
+	for i, file := range files {
+		loaded, err := results[i].loaded, results[i].err
+		if err != nil && s.SkipInvalidConfigs {
+			s.Logger.Error("Skipping invalid config file", "file", file.path, "error", err)
+			set.invalid[s.configNameFromPath(file.path)] = err.Error()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if loaded != nil {
+			if err := set.add(loaded); err != nil {
+				return nil, err
+			}
 		}
 	}
+	return set, nil
+}
+
+// loadResult is the outcome of loading a single config file
+type loadResult struct {
+	loaded *loadedConfig
+	err    error
+}
+
+// loadConfigFiles loads the config files with up to LoadConcurrency workers, GOMAXPROCS if
+// unset, so slow file systems don't serialize startup. Results are in the order of files,
+// so they don't depend on scheduling
+func (s *Server) loadConfigFiles(files []configFile) []loadResult {
+	workers := s.LoadConcurrency
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(files))
+
+	results := make([]loadResult, len(files))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i].loaded, results[i].err = s.loadConfigFile(files[i].path, files[i].entry)
+			}
+		}()
+	}
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// loadAllConfigs loads all config files from the configs directory into memory
+func (s *Server) loadAllConfigs() error {
+	s.Logger.Info("Loading all configs on startup", "configsDir", s.ConfigsDir)
+
+	set, err := s.readAllConfigs()
+	if err != nil {
+		return err
+	}
+	count, invalid := len(set.configs), len(set.invalid)
+	s.storeConfigSet(set)
 
-	s.Logger.Info("Successfully loaded all configs", "count", len(s.LoadedConfigs))
+	s.Logger.Info("Successfully loaded all configs", "count", count, "invalid", invalid)
 	return nil
 }
 
+// reloadCall is a reload whose result is shared by the callers that requested it while it ran
+type reloadCall struct {
+	done  chan struct{}
+	count int
+	err   error
+}
+
+// sharedReload runs reload unless another reload is in flight, in which case it waits for
+// that one and returns its result, so overlapping triggers load the configs only once
+func (s *Server) sharedReload(reload func() (int, error)) (int, error) {
+	s.reloadMu.Lock()
+	if call := s.reloading; call != nil {
+		s.reloadMu.Unlock()
+		s.Logger.Debug("Reload already in progress, sharing its result")
+		<-call.done
+		return call.count, call.err
+	}
+	call := &reloadCall{done: make(chan struct{})}
+	s.reloading = call
+	s.reloadMu.Unlock()
+
+	call.count, call.err = reload()
+
+	s.reloadMu.Lock()
+	s.reloading = nil
+	s.reloadMu.Unlock()
+	close(call.done)
+	return call.count, call.err
+}
+
+// Reload re-reads the configs directory and swaps the loaded configs only if all of them
+// load and can be merged together, otherwise the current configs are kept. Concurrent
+// calls share a single reload
+func (s *Server) Reload() (int, error) {
+	return s.sharedReload(s.reloadAll)
+}
+
+// reloadAll re-reads all configs, see Reload
+func (s *Server) reloadAll() (int, error) {
+	s.Logger.Info("Reloading configs", "configsDir", s.ConfigsDir)
+
+	set, err := s.readAllConfigs()
+	if err != nil {
+		s.ready.Store(false)
+		return 0, err
+	}
+
+	if err := s.validateReloadedGroups(set.configs); err != nil {
+		s.ready.Store(false)
+		return 0, errorx.Decorate(err, "groups refer to configs that are no longer loaded")
+	}
+	if !s.SkipMergeValidation {
+		if err := s.validateConfigsMergeable(set.configs); err != nil {
+			s.ready.Store(false)
+			return 0, errorx.Decorate(err, "configs cannot be merged together")
+		}
+	}
+
+	count, invalid := len(set.configs), len(set.invalid)
+	s.storeConfigSet(set)
+	s.ready.Store(true)
+
+	s.Logger.Info("Successfully reloaded configs", "count", count, "invalid", invalid)
+	return count, nil
+}
+
+// HandleReload re-reads the configs directory without restarting the server
+func (s *Server) HandleReload(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	count, err := s.Reload()
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to reload configs, keeping current configs", http.StatusInternalServerError)
+		return
+	}
+
+	err = createJSONEncoder(w).Encode(map[string]int{"count": count})
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to encode reload result", http.StatusInternalServerError)
+		return
+	}
+}
+
 // createEmptyKubeConfigForValidation creates an empty kubeconfig for merge validation
 func (s *Server) createEmptyKubeConfigForValidation() (*KubeConfig, error) {
 	mergedConfig, err := NewKubeConfig("", s.Logger)
@@ -364,38 +1734,49 @@ func (s *Server) createEmptyKubeConfigForValidation() (*KubeConfig, error) {
 	return mergedConfig, nil
 }
 
-// getAllConfigNames returns a slice of all loaded config names
+// getAllConfigNames returns a sorted slice of all loaded config names
 func (s *Server) getAllConfigNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	configNames := make([]string, 0, len(s.LoadedConfigs))
 	for name := range s.LoadedConfigs {
 		configNames = append(configNames, name)
 	}
+	slices.Sort(configNames)
 	return configNames
 }
 
-// mergeAllConfigsForValidation attempts to merge all loaded configs to test compatibility
+// mergeAllConfigsForValidation attempts to merge all given configs by name to test
+// compatibility. Configs that fail to merge are left out and the merge goes on, so the
+// returned error lists every failing config instead of just the first one
 func (s *Server) mergeAllConfigsForValidation(
 	mergedConfig *KubeConfig,
-	configNames []string,
+	configs map[string]*KubeConfig,
 ) error {
-	s.Logger.Debug("Testing merge of all configs", "configs", configNames)
-
-	for name, config := range s.LoadedConfigs {
+	var failures []string
+	for _, name := range slices.Sorted(maps.Keys(configs)) {
 		s.Logger.Debug("Merging config for validation", "name", name)
-		var err error
-		mergedConfig, err = mergeKubeConfigs(mergedConfig, config)
+		config := s.resolveConflicts(mergedConfig, configs[name], name)
+		merged, err := mergeKubeConfigs(mergedConfig, config)
 		if err != nil {
-			return errorx.Decorate(err, "failed to merge config '%s' during validation", name)
+			s.Logger.Error("Config can't be merged", "name", name, "error", err)
+			failures = append(failures, fmt.Sprintf("'%s': %v", name, err))
+			continue
 		}
+		mergedConfig = merged
+	}
+	if len(failures) > 0 {
+		return errorx.InternalError.New("failed to merge %d configs during validation: %s",
+			len(failures), strings.Join(failures, "; "))
 	}
 	return nil
 }
 
-// validateAllConfigsMergeable tests that all loaded configs can be merged together
-func (s *Server) validateAllConfigsMergeable() error {
+// validateConfigsMergeable tests that all given configs can be merged together
+func (s *Server) validateConfigsMergeable(configs map[string]*KubeConfig) error {
 	s.Logger.Info("Validating that all configs can be merged together")
 
-	if len(s.LoadedConfigs) == 0 {
+	if len(configs) == 0 {
 		s.Logger.Warn("No configs loaded, skipping merge validation")
 		return nil
 	}
@@ -406,14 +1787,19 @@ func (s *Server) validateAllConfigsMergeable() error {
 		return err
 	}
 
-	// Get all config names for logging
-	configNames := s.getAllConfigNames()
-
 	// Try to merge all configs
-	if err := s.mergeAllConfigsForValidation(mergedConfig, configNames); err != nil {
+	s.Logger.Debug("Testing merge of all configs", "count", len(configs))
+	if err := s.mergeAllConfigsForValidation(mergedConfig, configs); err != nil {
 		return err
 	}
 
 	s.Logger.Info("Successfully validated that all configs can be merged together")
 	return nil
 }
+
+// validateAllConfigsMergeable tests that all loaded configs can be merged together
+func (s *Server) validateAllConfigsMergeable() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.validateConfigsMergeable(s.LoadedConfigs)
+}
@@ -1,15 +1,23 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/rgeraskin/kubedepot/internal/config"
 	"github.com/rgeraskin/kubedepot/internal/testutil"
 	"gopkg.in/yaml.v3"
 )
@@ -106,16 +114,12 @@ func TestServer_ListConfigs(t *testing.T) {
 	// Valid configs directory contains 5 configs
 	expected := []string{
 		"dev",
-		"prod",
 		"integration-dev",
 		"integration-prod",
+		"prod",
 		"valid-test",
 	}
 
-	// Sort both slices to ensure consistent comparison
-	slices.Sort(configs)
-	slices.Sort(expected)
-
 	if !slices.Equal(configs, expected) {
 		t.Errorf("Expected configs %v, got %v", expected, configs)
 	}
@@ -155,16 +159,12 @@ func testListConfigsEndpoint(
 	// Valid configs directory contains 5 configs (same as TestServer_ListConfigs)
 	expected := []string{
 		"dev",
-		"prod",
 		"integration-dev",
 		"integration-prod",
+		"prod",
 		"valid-test",
 	}
 
-	// Sort both slices to ensure consistent comparison
-	slices.Sort(configs)
-	slices.Sort(expected)
-
 	if !slices.Equal(configs, expected) {
 		t.Errorf("Expected configs %v, got %v", expected, configs)
 	}
@@ -198,6 +198,32 @@ func TestServer_HandleListConfigs(t *testing.T) {
 	}
 }
 
+// TestServer_HandleListConfigs_TotalCount tests the X-Total-Count header of list endpoints
+func TestServer_HandleListConfigs_TotalCount(t *testing.T) {
+	server, _ := createTestServerValid(t)
+
+	for _, method := range []string{"GET", "HEAD"} {
+		t.Run(method, func(t *testing.T) {
+			req := httptest.NewRequest(method, "/json/list", nil)
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+			if got := w.Header().Get("X-Total-Count"); got != "5" {
+				t.Errorf("Expected X-Total-Count 5, got %q", got)
+			}
+			if method == "HEAD" && w.Body.Len() != 0 {
+				t.Errorf("Expected empty body for HEAD, got %q", w.Body.String())
+			}
+			if method == "GET" && w.Body.Len() == 0 {
+				t.Error("Expected body for GET")
+			}
+		})
+	}
+}
+
 // Helper function to test GetKubeConfigs endpoints for both JSON and YAML
 func testGetKubeConfigsEndpoint(t *testing.T, format string, endpoint string, queryParam string,
 	unmarshal func([]byte, any) error, wantStatus int, wantCount int,
@@ -342,6 +368,230 @@ func TestServer_HandleGetKubeConfigs(t *testing.T) {
 	}
 }
 
+// TestServer_HandleGetKubeConfigs_CurrentContext tests the current context of merged configs
+func TestServer_HandleGetKubeConfigs_CurrentContext(t *testing.T) {
+	tests := []struct {
+		name           string
+		defaultContext string
+		expected       string
+	}{
+		{name: "first config's context by default", defaultContext: "", expected: "dev-context"},
+		{name: "configured default context", defaultContext: "prod-context", expected: "prod-context"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.DefaultCurrentContext = tt.defaultContext
+
+			req := httptest.NewRequest("GET", "/yaml/get?name=dev&name=prod", nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsYaml(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+
+			var kubeConfig KubeConfig
+			if err := yaml.Unmarshal(w.Body.Bytes(), &kubeConfig); err != nil {
+				t.Fatalf("Failed to parse YAML response: %v", err)
+			}
+			if kubeConfig.CurrentContext != tt.expected {
+				t.Errorf("Expected current context %q, got %q", tt.expected, kubeConfig.CurrentContext)
+			}
+			if !kubeConfig.hasContext(kubeConfig.CurrentContext) {
+				t.Errorf("Current context %q doesn't resolve to a returned context", kubeConfig.CurrentContext)
+			}
+		})
+	}
+}
+
+// TestServer_HandleGetKubeConfigs_Deterministic tests that get responses are byte-stable
+func TestServer_HandleGetKubeConfigs_Deterministic(t *testing.T) {
+	configsDir := t.TempDir()
+	// A user with many credential keys decodes into a map
+	userConfig := `apiVersion: v1
+kind: Config
+clusters:
+  - cluster:
+      server: https://exec.example.com
+    name: exec-cluster
+contexts:
+  - context:
+      cluster: exec-cluster
+      user: exec-user
+    name: exec-context
+current-context: exec-context
+users:
+  - name: exec-user
+    user:
+      exec:
+        apiVersion: client.authentication.k8s.io/v1beta1
+        command: aws
+        args: [eks, get-token, --cluster-name, exec]
+        env:
+          - name: AWS_PROFILE
+            value: exec
+      zeta: z
+      alpha: a
+      mu: m
+`
+	if err := os.WriteFile(filepath.Join(configsDir, "exec.yaml"), []byte(userConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml"})
+	server, _ := createTestServerWithConfigs(t, configsDir)
+
+	for _, endpoint := range []string{"/json/get", "/yaml/get"} {
+		t.Run(endpoint, func(t *testing.T) {
+			var responses []string
+			for i := 0; i < 20; i++ {
+				req := httptest.NewRequest("GET", endpoint+"?name=exec&name=dev", nil)
+				w := httptest.NewRecorder()
+				server.Handler().ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+				}
+				responses = append(responses, w.Body.String())
+			}
+
+			for _, response := range responses[1:] {
+				if response != responses[0] {
+					t.Fatalf("Expected byte-identical responses, got:\n%s\nand:\n%s", responses[0], response)
+				}
+			}
+			if strings.Index(responses[0], "alpha") > strings.Index(responses[0], "zeta") {
+				t.Errorf("Expected user keys to be sorted, got %s", responses[0])
+			}
+		})
+	}
+}
+
+// TestServer_HandleDownloadKubeConfig tests downloading merged configs as a file
+func TestServer_HandleDownloadKubeConfig(t *testing.T) {
+	server, _ := createTestServerValid(t)
+
+	tests := []struct {
+		name                string
+		query               string
+		expectedStatus      int
+		expectedDisposition string
+		expectedClusters    int
+	}{
+		{
+			name:                "single config",
+			query:               "?name=dev",
+			expectedStatus:      http.StatusOK,
+			expectedDisposition: `attachment; filename="config"`,
+			expectedClusters:    1,
+		},
+		{
+			name:                "merged configs",
+			query:               "?name=dev&name=prod",
+			expectedStatus:      http.StatusOK,
+			expectedDisposition: `attachment; filename="config"`,
+			expectedClusters:    2,
+		},
+		{
+			name:                "filename override",
+			query:               "?name=dev&filename=dev-config",
+			expectedStatus:      http.StatusOK,
+			expectedDisposition: `attachment; filename="dev-config"`,
+			expectedClusters:    1,
+		},
+		{
+			name:                "filename with path separators",
+			query:               "?name=dev&filename=../../etc/passwd",
+			expectedStatus:      http.StatusOK,
+			expectedDisposition: `attachment; filename="....etcpasswd"`,
+			expectedClusters:    1,
+		},
+		{
+			name:           "unknown config",
+			query:          "?name=nonexistent",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/download"+tt.query, nil)
+			w := httptest.NewRecorder()
+			server.HandleDownloadKubeConfig(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				if w.Header().Get("Content-Disposition") != "" {
+					t.Error("Expected no Content-Disposition header on error")
+				}
+				return
+			}
+
+			if got := w.Header().Get("Content-Disposition"); got != tt.expectedDisposition {
+				t.Errorf("Expected Content-Disposition %q, got %q", tt.expectedDisposition, got)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/yaml" {
+				t.Errorf("Expected Content-Type application/yaml, got %q", got)
+			}
+
+			var kubeConfig KubeConfig
+			if err := yaml.Unmarshal(w.Body.Bytes(), &kubeConfig); err != nil {
+				t.Fatalf("Failed to parse YAML response: %v", err)
+			}
+			if len(kubeConfig.Clusters) != tt.expectedClusters {
+				t.Errorf("Expected %d clusters, got %d", tt.expectedClusters, len(kubeConfig.Clusters))
+			}
+		})
+	}
+}
+
+// TestServer_HandleGetKubeConfigByPath tests extension-style get URLs
+func TestServer_HandleGetKubeConfigByPath(t *testing.T) {
+	server, _ := createTestServerValid(t)
+
+	tests := []struct {
+		name           string
+		path           string
+		unmarshal      func([]byte, any) error
+		expectedStatus int
+	}{
+		{name: "yaml extension", path: "/get/dev.yaml", unmarshal: yaml.Unmarshal, expectedStatus: http.StatusOK},
+		{name: "yml extension", path: "/get/dev.yml", unmarshal: yaml.Unmarshal, expectedStatus: http.StatusOK},
+		{name: "json extension", path: "/get/dev.json", unmarshal: json.Unmarshal, expectedStatus: http.StatusOK},
+		{name: "unknown extension", path: "/get/dev.txt", expectedStatus: http.StatusBadRequest},
+		{name: "no extension", path: "/get/dev", expectedStatus: http.StatusBadRequest},
+		{name: "unknown config", path: "/get/nonexistent.yaml", expectedStatus: http.StatusNotFound},
+		{name: "group rejected", path: "/get/dev.yaml?group=prod", expectedStatus: http.StatusBadRequest},
+		{name: "label rejected", path: "/get/dev.yaml?label=env%3Dprod", expectedStatus: http.StatusBadRequest},
+		{name: "selector rejected", path: "/get/dev.json?selector=env%3Dprod", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.unmarshal == nil {
+				return
+			}
+
+			var kubeConfig KubeConfig
+			if err := tt.unmarshal(w.Body.Bytes(), &kubeConfig); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if len(kubeConfig.Clusters) != 1 || kubeConfig.Clusters[0].Name != "dev-cluster" {
+				t.Errorf("Expected dev-cluster only, got %+v", kubeConfig.Clusters)
+			}
+		})
+	}
+}
+
 func TestServer_HandleIndex(t *testing.T) {
 	server, _ := createTestServerValid(t) // Use valid server for template testing
 
@@ -363,6 +613,52 @@ func TestServer_HandleIndex(t *testing.T) {
 	}
 }
 
+// TestServer_TemplateIndex_Funcs tests the functions available to the index template
+func TestServer_TemplateIndex_Funcs(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"Dev.yaml": "dev.yaml"})
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+		wantErr  bool
+	}{
+		{name: "registered functions", template: `<p>{{ lower (join .names ",") }}</p>`, expected: "<p>dev</p>"},
+		{name: "unknown function", template: `<p>{{ shout .names }}</p>`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(webDir, "index.html"), []byte(tt.template), 0644)
+			if err != nil {
+				t.Fatalf("Failed to write template: %v", err)
+			}
+
+			logger := log.New(os.Stderr)
+			logger.SetLevel(log.FatalLevel)
+			server, err := NewServer(&Server{ConfigsDir: configsDir, WebDir: webDir, Logger: logger})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error for template with unknown function, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			server.HandleIndex(w, httptest.NewRequest("GET", "/", nil))
+
+			if w.Body.String() != tt.expected {
+				t.Errorf("Expected body %q, got %q", tt.expected, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestServer_listConfigs(t *testing.T) {
 	server, _ := createTestServerValid(t) // Use valid configs
 
@@ -374,16 +670,12 @@ func TestServer_listConfigs(t *testing.T) {
 	// Valid configs directory contains 5 configs (same as other valid tests)
 	expected := []string{
 		"dev",
-		"prod",
 		"integration-dev",
 		"integration-prod",
+		"prod",
 		"valid-test",
 	}
 
-	// Sort both slices to ensure consistent comparison
-	slices.Sort(names)
-	slices.Sort(expected)
-
 	if !slices.Equal(names, expected) {
 		t.Errorf("Expected config names %v, got %v", expected, names)
 	}
@@ -414,17 +706,13 @@ func TestServer_ListConfigsWithInvalid(t *testing.T) {
 	// Direct testdata access includes ALL files, including invalid.yaml
 	expected := []string{
 		"dev",
-		"prod",
 		"integration-dev",
 		"integration-prod",
-		"valid-test",
 		"invalid",
+		"prod",
+		"valid-test",
 	}
 
-	// Sort both slices to ensure consistent comparison
-	slices.Sort(configs)
-	slices.Sort(expected)
-
 	if !slices.Equal(configs, expected) {
 		t.Errorf("Expected configs %v, got %v", expected, configs)
 	}
@@ -450,10 +738,6 @@ func TestServer_InvalidConfigsOnly(t *testing.T) {
 	// Invalid configs directory should only contain invalid.yaml
 	expected := []string{"invalid"}
 
-	// Sort both slices to ensure consistent comparison
-	slices.Sort(configs)
-	slices.Sort(expected)
-
 	if !slices.Equal(configs, expected) {
 		t.Errorf("Expected configs %v, got %v", expected, configs)
 	}
@@ -488,18 +772,12 @@ func TestServer_getRequestedConfigNames(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req := httptest.NewRequest("GET", tt.url, nil)
-			result := server.getRequestedConfigNames(req, allConfigs)
-
-			// Sort both slices to ensure consistent comparison
-			resultCopy := make([]string, len(result))
-			copy(resultCopy, result)
-			expectedCopy := make([]string, len(tt.expected))
-			copy(expectedCopy, tt.expected)
-
-			slices.Sort(resultCopy)
-			slices.Sort(expectedCopy)
+			result, err := server.getRequestedConfigNames(req, allConfigs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
 
-			if !slices.Equal(resultCopy, expectedCopy) {
+			if !slices.Equal(result, tt.expected) {
 				t.Errorf("Expected configs %v, got %v", tt.expected, result)
 			}
 		})
@@ -572,6 +850,56 @@ func TestNewServer_ErrorCases(t *testing.T) {
 	})
 }
 
+// TestNewServer_SkipMergeValidation tests startup with conflicting configs
+func TestNewServer_SkipMergeValidation(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{
+		"dev.yaml":      "dev.yaml",
+		"dev-copy.yaml": "dev.yaml",
+	})
+
+	logger := log.New(os.Stderr)
+	logger.SetLevel(log.FatalLevel)
+
+	tests := []struct {
+		name                string
+		skipMergeValidation bool
+		wantErr             bool
+	}{
+		{name: "validation fails on conflicting configs", skipMergeValidation: false, wantErr: true},
+		{name: "validation skipped", skipMergeValidation: true, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := NewServer(&Server{
+				ConfigsDir:          configsDir,
+				WebDir:              testutil.GetTestDataDir(t),
+				Logger:              logger,
+				SkipMergeValidation: tt.skipMergeValidation,
+			})
+
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "configs cannot be merged together") {
+					t.Errorf("Expected merge validation error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// Configs are still served independently
+			req := httptest.NewRequest("GET", "/json/get?name=dev-copy", nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsJson(w, req)
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+		})
+	}
+}
+
 // TestServer_Start tests the Start function (note: this will fail to bind to port in tests)
 func TestServer_Start_InvalidPort(t *testing.T) {
 	server, _ := createTestServerValid(t)
@@ -612,88 +940,372 @@ func TestServer_Start_SuccessfulSetup(t *testing.T) {
 	}
 }
 
-// TestServer_TemplateIndex_ErrorCases tests error scenarios for TemplateIndex
-func TestServer_TemplateIndex_ErrorCases(t *testing.T) {
-	logger := log.New(os.Stderr)
-	logger.SetLevel(log.ErrorLevel)
-
-	t.Run("invalid configs directory", func(t *testing.T) {
-		server := &Server{
-			ConfigsDir:    "/nonexistent/configs/dir",
-			WebDir:        testutil.GetTestDataDir(t),
-			Logger:        logger,
-			LoadedConfigs: make(map[string]*KubeConfig), // Empty configs for error test
-		}
-
-		err := server.TemplateIndex(nil)
-		if err != nil {
-			t.Errorf("Unexpected error: %v", err) // TemplateIndex should work with empty configs
-		}
-	})
+// TestServer_Start_UnixSocket tests serving requests over a Unix socket
+func TestServer_Start_UnixSocket(t *testing.T) {
+	server, _ := createTestServerValid(t)
+	server.ListenSocket = filepath.Join(t.TempDir(), "kubedepot.sock")
 
-	t.Run("template execution error", func(t *testing.T) {
-		server, _ := createTestServerValid(t)
+	go func() {
+		_ = server.Start("")
+	}()
 
-		// Create a response writer that will fail on write
-		w := &failingResponseWriter{}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", server.ListenSocket)
+			},
+		},
+	}
 
-		err := server.TemplateIndex(w)
+	// Wait for the server to start listening
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		resp, err = client.Get("http://kubedepot/json/list")
 		if err == nil {
-			t.Error("Expected error for template execution failure, got nil")
+			break
 		}
-	})
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Failed to request over Unix socket: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var configs []string
+	if err := json.NewDecoder(resp.Body).Decode(&configs); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if len(configs) != 5 {
+		t.Errorf("Expected 5 configs, got %d", len(configs))
+	}
 }
 
-// TestServer_HandleIndex_ErrorCases tests error scenarios for HandleIndex
-func TestServer_HandleIndex_ErrorCases(t *testing.T) {
-	logger := log.New(os.Stderr)
-	logger.SetLevel(log.ErrorLevel)
+// TestServer_Start_UnixSocketKeepsRegularFile tests a regular file at the socket path is
+// never removed
+func TestServer_Start_UnixSocketKeepsRegularFile(t *testing.T) {
+	server, _ := createTestServerValid(t)
+	server.ListenSocket = filepath.Join(t.TempDir(), "kubedepot.sock")
+	if err := os.WriteFile(server.ListenSocket, []byte("keep me"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
 
-	t.Run("template error", func(t *testing.T) {
-		server := &Server{
-			ConfigsDir:    "/nonexistent/configs/dir",
-			WebDir:        testutil.GetTestDataDir(t),
-			Logger:        logger,
-			LoadedConfigs: make(map[string]*KubeConfig), // Empty configs
-		}
+	err := server.Start("")
+	if err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("Expected error for a regular file at the socket path, got %v", err)
+	}
+	data, err := os.ReadFile(server.ListenSocket)
+	if err != nil || string(data) != "keep me" {
+		t.Errorf("Expected the regular file to be left alone, got %q, %v", data, err)
+	}
+}
 
-		req := httptest.NewRequest("GET", "/", nil)
-		w := httptest.NewRecorder()
+// TestServer_Shutdown tests that a running server drains and stops on Shutdown
+func TestServer_Shutdown(t *testing.T) {
+	server, _ := createTestServerValid(t)
 
-		server.HandleIndex(w, req)
+	startErr := make(chan error, 1)
+	go func() {
+		startErr <- server.Start("0")
+	}()
 
-		if w.Code != http.StatusOK {
-			t.Errorf(
-				"Expected status code %d, got %d",
-				http.StatusOK,
-				w.Code,
-			) // Should work with empty configs
-		}
-	})
-}
+	// Wait for the server to start listening
+	for i := 0; i < 50 && server.Addr() == nil; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if server.Addr() == nil {
+		t.Fatal("Server didn't start listening")
+	}
 
-// TestServer_ListConfigs_ErrorCases tests error scenarios for ListConfigs
-func TestServer_ListConfigs_ErrorCases(t *testing.T) {
-	logger := log.New(os.Stderr)
-	logger.SetLevel(log.ErrorLevel)
+	resp, err := http.Get("http://" + server.Addr().String() + "/json/list")
+	if err != nil {
+		t.Fatalf("Failed to request server: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
 
-	t.Run("invalid configs directory", func(t *testing.T) {
-		server := &Server{
-			ConfigsDir:    "/nonexistent/configs/dir",
-			WebDir:        testutil.GetTestDataDir(t),
-			Logger:        logger,
-			LoadedConfigs: make(map[string]*KubeConfig), // Empty configs
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Expected shutdown without error, got %v", err)
+	}
+	select {
+	case err := <-startErr:
+		if err != nil {
+			t.Errorf("Expected Start to return nil after shutdown, got %v", err)
 		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start didn't return after shutdown")
+	}
+}
 
-		configs, err := server.listConfigs()
-		if err != nil {
-			t.Errorf("Unexpected error: %v", err) // Should work with empty configs
+// TestServer_ShutdownBeforeServing tests that a shutdown requested before Start begins
+// serving makes Start return instead of serving forever
+func TestServer_ShutdownBeforeServing(t *testing.T) {
+	t.Run("before start", func(t *testing.T) {
+		server, _ := createTestServerValid(t)
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Expected shutdown without error, got %v", err)
 		}
-		if len(configs) != 0 {
-			t.Errorf("Expected 0 configs, got %d", len(configs))
+		if err := server.Start("0"); err != nil {
+			t.Errorf("Expected Start to return nil after shutdown, got %v", err)
 		}
 	})
-}
+
+	t.Run("while starting", func(t *testing.T) {
+		server, _ := createTestServerValid(t)
+		startErr := make(chan error, 1)
+		go func() {
+			startErr <- server.Start("0")
+		}()
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Expected shutdown without error, got %v", err)
+		}
+
+		select {
+		case err := <-startErr:
+			if err != nil {
+				t.Errorf("Expected Start to return nil after shutdown, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Start didn't return after shutdown")
+		}
+	})
+}
+
+// startTestServer starts the server on a random port and returns its address,
+// the server is shut down when the test finishes
+func startTestServer(t *testing.T, server *Server) string {
+	go func() {
+		_ = server.Start("0")
+	}()
+	t.Cleanup(func() {
+		_ = server.Shutdown(context.Background())
+	})
+
+	// Wait for the server to start listening
+	for i := 0; i < 50 && server.Addr() == nil; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if server.Addr() == nil {
+		t.Fatal("Server didn't start listening")
+	}
+	return server.Addr().String()
+}
+
+// TestServer_Start_DefaultPort tests that an empty port binds the default port
+func TestServer_Start_DefaultPort(t *testing.T) {
+	// The default port may be taken on the machine running the tests
+	probe, err := net.Listen("tcp", ":"+config.DefaultPort)
+	if err != nil {
+		t.Skipf("Default port %s is not available: %v", config.DefaultPort, err)
+	}
+	probe.Close()
+
+	server, _ := createTestServerValid(t)
+	go func() {
+		_ = server.Start("")
+	}()
+	t.Cleanup(func() {
+		_ = server.Shutdown(context.Background())
+	})
+
+	for i := 0; i < 50 && server.Addr() == nil; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if server.Addr() == nil {
+		t.Fatal("Server didn't start listening")
+	}
+	_, port, err := net.SplitHostPort(server.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to parse listen address: %v", err)
+	}
+	if port != config.DefaultPort {
+		t.Errorf("Expected default port %s, got %s", config.DefaultPort, port)
+	}
+}
+
+// TestServer_Start_BindAddress tests that the server only listens on BindAddress
+func TestServer_Start_BindAddress(t *testing.T) {
+	server, _ := createTestServerValid(t)
+	server.BindAddress = "127.0.0.1"
+	go func() {
+		_ = server.Start("0")
+	}()
+	t.Cleanup(func() {
+		_ = server.Shutdown(context.Background())
+	})
+
+	for i := 0; i < 50 && server.Addr() == nil; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if server.Addr() == nil {
+		t.Fatal("Server didn't start listening")
+	}
+	host, _, err := net.SplitHostPort(server.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to parse listen address: %v", err)
+	}
+	if host != "127.0.0.1" {
+		t.Errorf("Expected server to listen on 127.0.0.1, got %s", host)
+	}
+
+	resp, err := http.Get("http://" + server.Addr().String() + "/ping")
+	if err != nil {
+		t.Fatalf("Failed to request server: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestServer_validateBindAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: "", wantErr: false},
+		{address: "127.0.0.1", wantErr: false},
+		{address: "::1", wantErr: false},
+		{address: "[::1]", wantErr: false},
+		{address: "localhost", wantErr: false},
+		{address: "127.0.0.1:8080", wantErr: true},
+		{address: "http://localhost", wantErr: true},
+		{address: "local host", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			server := &Server{BindAddress: tt.address}
+			if err := server.validateBindAddress(); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestServer_KeepAlive tests that keep-alives can be disabled
+func TestServer_KeepAlive(t *testing.T) {
+	tests := []struct {
+		name             string
+		disableKeepAlive bool
+		expectClose      bool
+	}{
+		{name: "keep-alive by default", disableKeepAlive: false, expectClose: false},
+		{name: "keep-alive disabled", disableKeepAlive: true, expectClose: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.DisableKeepAlive = tt.disableKeepAlive
+			server.IdleTimeout = time.Second
+			addr := startTestServer(t, server)
+
+			resp, err := http.Get("http://" + addr + "/ping")
+			if err != nil {
+				t.Fatalf("Failed to request server: %v", err)
+			}
+			resp.Body.Close()
+
+			// resp.Close reflects the Connection: close response header
+			if resp.Close != tt.expectClose {
+				t.Errorf("Expected connection close %v, got %v", tt.expectClose, resp.Close)
+			}
+		})
+	}
+}
+
+// TestServer_TemplateIndex_ErrorCases tests error scenarios for TemplateIndex
+func TestServer_TemplateIndex_ErrorCases(t *testing.T) {
+	logger := log.New(os.Stderr)
+	logger.SetLevel(log.ErrorLevel)
+
+	t.Run("invalid configs directory", func(t *testing.T) {
+		server := &Server{
+			ConfigsDir:    "/nonexistent/configs/dir",
+			WebDir:        testutil.GetTestDataDir(t),
+			Logger:        logger,
+			LoadedConfigs: make(map[string]*KubeConfig), // Empty configs for error test
+		}
+
+		err := server.TemplateIndex(nil)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err) // TemplateIndex should work with empty configs
+		}
+	})
+
+	t.Run("template execution error", func(t *testing.T) {
+		server, _ := createTestServerValid(t)
+
+		// Create a response writer that will fail on write
+		w := &failingResponseWriter{}
+
+		err := server.TemplateIndex(w)
+		if err == nil {
+			t.Error("Expected error for template execution failure, got nil")
+		}
+	})
+}
+
+// TestServer_HandleIndex_ErrorCases tests error scenarios for HandleIndex
+func TestServer_HandleIndex_ErrorCases(t *testing.T) {
+	logger := log.New(os.Stderr)
+	logger.SetLevel(log.ErrorLevel)
+
+	t.Run("template error", func(t *testing.T) {
+		server := &Server{
+			ConfigsDir:    "/nonexistent/configs/dir",
+			WebDir:        testutil.GetTestDataDir(t),
+			Logger:        logger,
+			LoadedConfigs: make(map[string]*KubeConfig), // Empty configs
+		}
+
+		req := httptest.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+
+		server.HandleIndex(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf(
+				"Expected status code %d, got %d",
+				http.StatusOK,
+				w.Code,
+			) // Should work with empty configs
+		}
+	})
+}
+
+// TestServer_ListConfigs_ErrorCases tests error scenarios for ListConfigs
+func TestServer_ListConfigs_ErrorCases(t *testing.T) {
+	logger := log.New(os.Stderr)
+	logger.SetLevel(log.ErrorLevel)
+
+	t.Run("invalid configs directory", func(t *testing.T) {
+		server := &Server{
+			ConfigsDir:    "/nonexistent/configs/dir",
+			WebDir:        testutil.GetTestDataDir(t),
+			Logger:        logger,
+			LoadedConfigs: make(map[string]*KubeConfig), // Empty configs
+		}
+
+		configs, err := server.listConfigs()
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err) // Should work with empty configs
+		}
+		if len(configs) != 0 {
+			t.Errorf("Expected 0 configs, got %d", len(configs))
+		}
+	})
+}
 
 // TestServer_listConfigs_ErrorCases tests error scenarios for getConfigNames
 func TestServer_listConfigs_ErrorCases(t *testing.T) {
@@ -1155,8 +1767,6 @@ func TestServer_EdgeCases(t *testing.T) {
 
 		// Should list all files regardless of extension, with extensions stripped
 		expected := []string{"config1", "config2", "config3", "config4"}
-		slices.Sort(configs)
-		slices.Sort(expected)
 
 		if !slices.Equal(configs, expected) {
 			t.Errorf("Expected configs %v, got %v", expected, configs)
@@ -1191,6 +1801,41 @@ func TestServer_ConcurrentAccess(t *testing.T) {
 			<-done
 		}
 	})
+
+	// Reads racing with reloads, uploads and deletes, run with -race to detect unguarded access
+	t.Run("concurrent reads and writes", func(t *testing.T) {
+		server := createUploadTestServer(t)
+		prodConfig := string(testutil.LoadTestData(t, "kubeconfigs/prod.yaml"))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(4)
+			go func() {
+				defer wg.Done()
+				w := httptest.NewRecorder()
+				server.HandleGetKubeConfigsJson(w, httptest.NewRequest("GET", "/json/get?name=dev", nil))
+				if w.Code != http.StatusOK {
+					t.Errorf("Concurrent get failed with status %d", w.Code)
+				}
+				server.HandleListConfigsJson(httptest.NewRecorder(), httptest.NewRequest("GET", "/json/list", nil))
+			}()
+			go func() {
+				defer wg.Done()
+				req := newAdminRequest("POST", "/upload?name=prod", strings.NewReader(prodConfig))
+				server.HandleUploadConfig(httptest.NewRecorder(), req)
+			}()
+			go func() {
+				defer wg.Done()
+				server.HandleDeleteConfig(httptest.NewRecorder(), newAdminRequest("DELETE", "/config?name=prod", nil))
+			}()
+			go func() {
+				defer wg.Done()
+				// Reloads may see files being uploaded half-written, only races matter here
+				server.Reload()
+			}()
+		}
+		wg.Wait()
+	})
 }
 
 // TestServer_Benchmarks provides benchmark tests for performance monitoring
@@ -1241,20 +1886,6 @@ func TestServer_CoverageDocumentation(t *testing.T) {
 	// This test documents the remaining uncovered lines in the server package
 	// and explains why they cannot be practically tested in unit tests.
 
-	t.Run("Start function return nil", func(t *testing.T) {
-		// The line "return nil" in the Start function (server.go:53) is unreachable
-		// in unit tests because http.ListenAndServe blocks indefinitely on success.
-		// This line would only be reached if the server shuts down gracefully,
-		// which requires external intervention (signals, context cancellation, etc.)
-		// that is not suitable for unit testing.
-
-		// The error path is tested in TestServer_Start_InvalidPort
-		// The setup validation is tested in TestServer_Start_SuccessfulSetup
-
-		t.Log("Start function 'return nil' line is unreachable in unit tests")
-		t.Log("This represents normal HTTP server behavior where ListenAndServe blocks")
-	})
-
 	t.Run("NewKubeConfig empty string error", func(t *testing.T) {
 		// The error handling for NewKubeConfig("") in loadAndMergeConfigs is very
 		// difficult to trigger because NewKubeConfig("") creates a minimal valid
@@ -1304,3 +1935,1830 @@ type mockError struct {
 func (e *mockError) Error() string {
 	return e.message
 }
+
+// TestServer_MultiClusterConfig tests loading and merging a config bundling several clusters
+func TestServer_MultiClusterConfig(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{
+		"bundle.yaml": "multi-cluster.yaml",
+		"dev.yaml":    "dev.yaml",
+		"prod.yaml":   "prod.yaml",
+	})
+
+	server, _ := createTestServerRaw(t, configsDir)
+	entries, err := os.ReadDir(configsDir)
+	if err != nil {
+		t.Fatalf("Failed to read configs directory: %v", err)
+	}
+	for _, entry := range entries {
+		if err := server.loadSingleConfig(filepath.Join(configsDir, entry.Name()), entry); err != nil {
+			t.Fatalf("Failed to load %s: %v", entry.Name(), err)
+		}
+	}
+	if err := server.validateAllConfigsMergeable(); err != nil {
+		t.Fatalf("Expected configs to be mergeable: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/yaml/get?name=bundle&name=dev&name=prod", nil)
+	w := httptest.NewRecorder()
+	server.HandleGetKubeConfigsYaml(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var kubeConfig KubeConfig
+	if err := yaml.Unmarshal(w.Body.Bytes(), &kubeConfig); err != nil {
+		t.Fatalf("Failed to parse YAML response: %v", err)
+	}
+	var clusters []string
+	for _, cluster := range kubeConfig.Clusters {
+		clusters = append(clusters, cluster.Name)
+	}
+	expected := []string{
+		"bundle-dev-cluster",
+		"bundle-staging-cluster",
+		"bundle-prod-cluster",
+		"dev-cluster",
+		"prod-cluster",
+	}
+	if !slices.Equal(clusters, expected) {
+		t.Errorf("Expected clusters %v, got %v", expected, clusters)
+	}
+	if len(kubeConfig.Contexts) != 5 || len(kubeConfig.Users) != 3 {
+		t.Errorf("Expected 5 contexts and 3 users, got %d and %d",
+			len(kubeConfig.Contexts), len(kubeConfig.Users))
+	}
+	if kubeConfig.CurrentContext != "bundle-dev-context" {
+		t.Errorf("Expected current context bundle-dev-context, got %s", kubeConfig.CurrentContext)
+	}
+}
+
+// TestServer_validateAllConfigsMergeable_HiddenDuplicate tests that startup validation catches
+// a config colliding with an entry that isn't the first of the accumulated result
+func TestServer_validateAllConfigsMergeable_HiddenDuplicate(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{
+		"bundle.yaml": "multi-cluster.yaml",
+		"dev.yaml":    "dev.yaml",
+	})
+	// Collides with the second cluster of the bundle only
+	staging := strings.NewReplacer(
+		"dev-cluster", "bundle-staging-cluster",
+		"dev-context", "staging-context",
+		"dev-user", "staging-user",
+	).Replace(string(testutil.LoadTestData(t, "kubeconfigs/dev.yaml")))
+	if err := os.WriteFile(filepath.Join(configsDir, "staging.yaml"), []byte(staging), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	server, _ := createTestServerRaw(t, configsDir)
+	if err := server.loadAllConfigs(); err != nil {
+		t.Fatalf("Failed to load configs: %v", err)
+	}
+
+	err := server.validateAllConfigsMergeable()
+	if err == nil {
+		t.Fatal("Expected duplicate cluster error, got nil")
+	}
+	if !strings.Contains(err.Error(), "duplicate cluster name: bundle-staging-cluster") {
+		t.Errorf("Expected colliding cluster name in error, got %v", err)
+	}
+}
+
+// TestServer_validateAllConfigsMergeable_AllConflicts tests that startup validation reports
+// every config that can't be merged, not just the first
+func TestServer_validateAllConfigsMergeable_AllConflicts(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{
+		"dev.yaml":   "dev.yaml",
+		"dev-a.yaml": "dev.yaml",
+		"dev-b.yaml": "dev.yaml",
+		"prod.yaml":  "prod.yaml",
+	})
+
+	server, _ := createTestServerRaw(t, configsDir)
+	if err := server.loadAllConfigs(); err != nil {
+		t.Fatalf("Failed to load configs: %v", err)
+	}
+
+	err := server.validateAllConfigsMergeable()
+	if err == nil {
+		t.Fatal("Expected merge error, got nil")
+	}
+	for _, expected := range []string{"failed to merge 2 configs", "'dev-a'", "'dev-b'", "duplicate cluster name: dev-cluster"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q in error, got %v", expected, err)
+		}
+	}
+	if strings.Contains(err.Error(), "'prod'") {
+		t.Errorf("Expected prod to merge cleanly, got %v", err)
+	}
+}
+
+// TestServer_Normalize tests that messily formatted configs are served canonically
+func TestServer_Normalize(t *testing.T) {
+	messy := "apiVersion: v1   \n" +
+		"kind: 'Config'\n" +
+		"clusters:\n" +
+		"- cluster:\n" +
+		"    certificate-authority-data: \"ZGV2LWNlcnQ=\"\n" +
+		"    server: \"https://dev.example.com  \"\n" +
+		"  name: 'dev-cluster  '\n" +
+		"contexts:\n" +
+		"- context: {cluster: dev-cluster, user: \"dev-user \"}\n" +
+		"  name: dev-context\t\n" +
+		"current-context: \"dev-context\"\n" +
+		"users:\n" +
+		"- name: dev-user\n" +
+		"  user:\n" +
+		"    token: \"dev-token \"\n"
+
+	serve := func(t *testing.T, configsDir string, normalize bool) string {
+		server, _ := createTestServerRaw(t, configsDir)
+		server.Normalize = normalize
+		if err := server.loadAllConfigs(); err != nil {
+			t.Fatalf("Failed to load configs: %v", err)
+		}
+		req := httptest.NewRequest("GET", "/yaml/get?name=dev", nil)
+		w := httptest.NewRecorder()
+		server.HandleGetKubeConfigsYaml(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		return w.Body.String()
+	}
+
+	cleanDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, cleanDir, map[string]string{"dev.yaml": "dev.yaml"})
+	messyDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(messyDir, "dev.yaml"), []byte(messy), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	canonical := serve(t, cleanDir, false)
+	if got := serve(t, messyDir, false); got == canonical {
+		t.Fatal("Expected messy config to differ without normalization")
+	}
+	if got := serve(t, messyDir, true); got != canonical {
+		t.Errorf("Expected normalized output:\n%s\ngot:\n%s", canonical, got)
+	}
+
+	t.Run("keeps leading whitespace", func(t *testing.T) {
+		kubeConfig, err := parseKubeConfig([]byte(strings.Replace(messy, `current-context: "dev-context"`, `current-context: " dev-context "`, 1)))
+		if err != nil {
+			t.Fatalf("Failed to parse config: %v", err)
+		}
+		normalized, err := normalizeKubeConfig(kubeConfig)
+		if err != nil {
+			t.Fatalf("Failed to normalize config: %v", err)
+		}
+		if normalized.CurrentContext != " dev-context" {
+			t.Errorf("Expected only trailing whitespace to be trimmed, got %q", normalized.CurrentContext)
+		}
+	})
+}
+
+// TestServer_AllowedExtensions tests that only files with allowed extensions are loaded as configs
+func TestServer_AllowedExtensions(t *testing.T) {
+	tempDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, tempDir, map[string]string{
+		"dev.yaml":  "dev.yaml",
+		"prod.yml":  "prod.yaml",
+		"test.conf": "valid-test.yaml",
+	})
+	// Files that aren't kubeconfigs would fail to load if parsed
+	for _, file := range []string{"README.md", "NOTES"} {
+		if err := os.WriteFile(filepath.Join(tempDir, file), []byte("# Not a [kubeconfig"), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", file, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		allowed  []string
+		expected []string
+	}{
+		{name: "default", allowed: nil, expected: []string{"dev", "prod"}},
+		{name: "custom", allowed: []string{"yaml", ".CONF"}, expected: []string{"dev", "test"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerRaw(t, tempDir)
+			server.AllowedExtensions = tt.allowed
+
+			if err := server.loadAllConfigs(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			configs := server.getAllConfigNames()
+			if !slices.Equal(configs, tt.expected) {
+				t.Errorf("Expected configs %v, got %v", tt.expected, configs)
+			}
+		})
+	}
+}
+
+func TestServer_Include(t *testing.T) {
+	tempDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, tempDir, map[string]string{
+		"dev.yaml":     "dev.yaml",
+		"prod-eu.yaml": "prod.yaml",
+		"prod-us.yaml": "valid-test.yaml",
+	})
+
+	server, _ := createTestServerRaw(t, tempDir)
+	server.Include = []string{"prod-*"}
+	if err := server.loadAllConfigs(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	configs := server.getAllConfigNames()
+	if expected := []string{"prod-eu", "prod-us"}; !slices.Equal(configs, expected) {
+		t.Errorf("Expected configs %v, got %v", expected, configs)
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := NewServer(&Server{ConfigsDir: tempDir, Logger: server.Logger, Include: []string{"prod-["}})
+		if err == nil {
+			t.Error("Expected error for invalid include pattern")
+		}
+	})
+}
+
+// TestServer_JSONConfigFile tests that .json config files are parsed as JSON and served as YAML
+func TestServer_JSONConfigFile(t *testing.T) {
+	configsDir := t.TempDir()
+	// Escaped slashes, as emitted by some JSON tools, are valid JSON but not valid YAML
+	config := "{\n\t\"apiVersion\": \"v1\",\n\t\"kind\": \"Config\",\n" +
+		"\t\"clusters\": [{\"name\": \"foo-cluster\", \"cluster\": {\"server\": \"https:\\/\\/foo.example.com\"}}],\n" +
+		"\t\"contexts\": [{\"name\": \"foo-context\", \"context\": {\"cluster\": \"foo-cluster\", \"user\": \"foo-user\"}}],\n" +
+		"\t\"current-context\": \"foo-context\",\n" +
+		"\t\"users\": [{\"name\": \"foo-user\", \"user\": {\"token\": \"foo-token\"}}]\n}\n"
+	if err := os.WriteFile(filepath.Join(configsDir, "foo.json"), []byte(config), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := parseKubeConfig([]byte(config)); err == nil {
+		t.Fatal("Expected the test config not to be valid YAML")
+	}
+	server, _ := createTestServerWithConfigs(t, configsDir)
+
+	req := httptest.NewRequest("GET", "/yaml/get?name=foo", nil)
+	w := httptest.NewRecorder()
+	server.HandleGetKubeConfigsYaml(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var served KubeConfig
+	if err := yaml.Unmarshal(w.Body.Bytes(), &served); err != nil {
+		t.Fatalf("Failed to parse YAML response: %v", err)
+	}
+	if served.CurrentContext != "foo-context" || len(served.Clusters) != 1 ||
+		served.Clusters[0].Cluster.Server != "https://foo.example.com" {
+		t.Errorf("Expected the JSON config to be served as YAML, got:\n%s", w.Body.String())
+	}
+}
+
+func TestServer_NameCase(t *testing.T) {
+	tempDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, tempDir, map[string]string{
+		"Dev.yaml":        "dev.yaml",
+		"PROD.yaml":       "prod.yaml",
+		"Valid-Test.yaml": "valid-test.yaml",
+	})
+
+	tests := []struct {
+		name     string
+		nameCase string
+		expected []string
+		wantErr  bool
+	}{
+		{name: "default", nameCase: "", expected: []string{"Dev", "PROD", "Valid-Test"}},
+		{name: "preserve", nameCase: NameCasePreserve, expected: []string{"Dev", "PROD", "Valid-Test"}},
+		{name: "lower", nameCase: NameCaseLower, expected: []string{"dev", "prod", "valid-test"}},
+		{name: "upper", nameCase: NameCaseUpper, expected: []string{"DEV", "PROD", "VALID-TEST"}},
+		{name: "unknown", nameCase: "title", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConfig, _ := createTestServerRaw(t, tempDir)
+			serverConfig.NameCase = tt.nameCase
+			server, err := NewServer(serverConfig)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error for unknown name case")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+
+			configs := server.getAllConfigNames()
+			if !slices.Equal(configs, tt.expected) {
+				t.Errorf("Expected configs %v, got %v", tt.expected, configs)
+			}
+		})
+	}
+}
+
+func TestServer_NameCaseCollision(t *testing.T) {
+	tempDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, tempDir, map[string]string{
+		"Dev.yaml": "prod.yaml",
+		"dev.yaml": "dev.yaml",
+	})
+
+	t.Run("preserve", func(t *testing.T) {
+		serverConfig, _ := createTestServerRaw(t, tempDir)
+		serverConfig.NameCase = NameCasePreserve
+		server, err := NewServer(serverConfig)
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		if configs := server.getAllConfigNames(); !slices.Equal(configs, []string{"Dev", "dev"}) {
+			t.Errorf("Expected both configs to be loaded, got %v", configs)
+		}
+	})
+
+	t.Run("lower", func(t *testing.T) {
+		serverConfig, _ := createTestServerRaw(t, tempDir)
+		serverConfig.NameCase = NameCaseLower
+		_, err := NewServer(serverConfig)
+		if err == nil {
+			t.Fatal("Expected error for files mapping to the same config name")
+		}
+		if !strings.Contains(err.Error(), "both map to config name dev") {
+			t.Errorf("Expected collision error, got: %v", err)
+		}
+	})
+}
+
+// createConfigMapMount creates a directory laid out like a Kubernetes ConfigMap or Secret
+// volume: the files live in a timestamped directory linked by ..data, and every key is a
+// link into ..data
+func createConfigMapMount(t *testing.T, configs map[string]string) string {
+	mountDir := t.TempDir()
+	dataDir := filepath.Join(mountDir, "..2026_10_17_12_00_00.000000001")
+	if err := os.Mkdir(dataDir, 0755); err != nil {
+		t.Fatalf("Failed to create data directory: %v", err)
+	}
+	testutil.CopyTestKubeConfigs(t, dataDir, configs)
+	if err := os.Symlink(filepath.Base(dataDir), filepath.Join(mountDir, "..data")); err != nil {
+		t.Fatalf("Failed to create ..data symlink: %v", err)
+	}
+	for fileName := range configs {
+		if err := os.Symlink(filepath.Join("..data", fileName), filepath.Join(mountDir, fileName)); err != nil {
+			t.Fatalf("Failed to create key symlink: %v", err)
+		}
+	}
+	return mountDir
+}
+
+// TestServer_ConfigMapMount tests loading configs from a mounted ConfigMap, either from the
+// mount directory or from its ..data link
+func TestServer_ConfigMapMount(t *testing.T) {
+	mountDir := createConfigMapMount(t, map[string]string{"dev.yaml": "dev.yaml", "prod.yaml": "prod.yaml"})
+
+	tests := []struct {
+		name       string
+		configsDir string
+	}{
+		{name: "mount directory", configsDir: mountDir},
+		{name: "data link", configsDir: filepath.Join(mountDir, "..data")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerRaw(t, tt.configsDir)
+			if err := server.loadAllConfigs(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			configs := server.getAllConfigNames()
+			if expected := []string{"dev", "prod"}; !slices.Equal(configs, expected) {
+				t.Fatalf("Expected configs %v, got %v", expected, configs)
+			}
+			if path := server.ConfigMeta["dev"].Path; path != filepath.Join(tt.configsDir, "dev.yaml") {
+				t.Errorf("Expected config path under the configs directory, got %s", path)
+			}
+		})
+	}
+}
+
+// TestServer_MaxScanDepth tests that nested directories are only scanned up to MaxScanDepth
+func TestServer_MaxScanDepth(t *testing.T) {
+	tempDir := t.TempDir()
+	nestedDir := filepath.Join(tempDir, "team", "deep")
+	if err := os.MkdirAll(nestedDir, 0755); err != nil {
+		t.Fatalf("Failed to create nested directory: %v", err)
+	}
+	testutil.CopyTestKubeConfigs(t, tempDir, map[string]string{"dev.yaml": "dev.yaml"})
+	testutil.CopyTestKubeConfigs(t, filepath.Join(tempDir, "team"), map[string]string{"prod.yaml": "prod.yaml"})
+	testutil.CopyTestKubeConfigs(t, nestedDir, map[string]string{"test.yaml": "valid-test.yaml"})
+	// A link back to the top must not be followed
+	if err := os.Symlink(tempDir, filepath.Join(nestedDir, "loop")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		maxScanDepth int
+		recursive    bool
+		expected     []string
+	}{
+		{name: "top level only by default", maxScanDepth: 0, expected: []string{"dev"}},
+		{name: "one level deep", maxScanDepth: 1, expected: []string{"dev", "team-prod"}},
+		{name: "two levels deep", maxScanDepth: 2, expected: []string{"dev", "team-deep-test", "team-prod"}},
+		{name: "recursive", recursive: true, expected: []string{"dev", "team-deep-test", "team-prod"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerRaw(t, tempDir)
+			server.MaxScanDepth = tt.maxScanDepth
+			server.RecursiveConfigs = tt.recursive
+
+			if err := server.loadAllConfigs(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			configs := server.getAllConfigNames()
+			if !slices.Equal(configs, tt.expected) {
+				t.Errorf("Expected configs %v, got %v", tt.expected, configs)
+			}
+		})
+	}
+}
+
+func TestServer_HandleReload(t *testing.T) {
+	t.Run("new config is listed", func(t *testing.T) {
+		server := createUploadTestServer(t)
+		testutil.CopyTestKubeConfigs(t, server.ConfigsDir, map[string]string{"prod.yaml": "prod.yaml"})
+
+		req := newAdminRequest("POST", "/reload", nil)
+		w := httptest.NewRecorder()
+		server.HandleReload(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var result map[string]int
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if result["count"] != 2 {
+			t.Errorf("Expected count 2, got %d", result["count"])
+		}
+		if !slices.Contains(listConfigNames(t, server), "prod") {
+			t.Error("Expected reloaded config to be listed")
+		}
+	})
+
+	t.Run("conflicting config keeps current configs", func(t *testing.T) {
+		server := createUploadTestServer(t)
+		testutil.CopyTestKubeConfigs(t, server.ConfigsDir, map[string]string{"dev-copy.yaml": "dev.yaml"})
+
+		req := newAdminRequest("POST", "/reload", nil)
+		w := httptest.NewRecorder()
+		server.HandleReload(w, req)
+
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("Expected status code %d, got %d", http.StatusInternalServerError, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "'dev-copy'") {
+			t.Errorf("Expected error to name the offending config, got %q", w.Body.String())
+		}
+		names := listConfigNames(t, server)
+		if len(names) != 1 || names[0] != "dev" {
+			t.Errorf("Expected current configs to be kept, got %v", names)
+		}
+	})
+}
+
+func TestServer_SingleFile(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml"})
+	configFile := filepath.Join(configsDir, "dev.yaml")
+
+	t.Run("file rejected by default", func(t *testing.T) {
+		server, _ := createTestServerRaw(t, configFile)
+		if err := server.loadAllConfigs(); err == nil {
+			t.Error("Expected error when ConfigsDir is a file")
+		}
+	})
+
+	t.Run("file served as one config", func(t *testing.T) {
+		server, _ := createTestServerRaw(t, configFile)
+		server.SingleFile = true
+		server, err := NewServer(server)
+		if err != nil {
+			t.Fatalf("Failed to create test server: %v", err)
+		}
+
+		names := listConfigNames(t, server)
+		if len(names) != 1 || names[0] != "dev" {
+			t.Errorf("Expected single config 'dev', got %v", names)
+		}
+	})
+}
+
+func TestServer_RequireName(t *testing.T) {
+	tests := []struct {
+		name           string
+		requireName    bool
+		url            string
+		expectedStatus int
+	}{
+		{name: "all configs merged by default", requireName: false, url: "/json/get", expectedStatus: http.StatusOK},
+		{name: "missing name rejected", requireName: true, url: "/json/get", expectedStatus: http.StatusBadRequest},
+		{name: "missing name rejected on download", requireName: true, url: "/download", expectedStatus: http.StatusBadRequest},
+		{name: "missing name rejected on archive", requireName: true, url: "/archive", expectedStatus: http.StatusBadRequest},
+		{name: "explicit name allowed", requireName: true, url: "/yaml/get?name=dev", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.RequireName = tt.requireName
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.url == "/json/get" && !tt.requireName {
+				var kubeConfig KubeConfig
+				if err := json.Unmarshal(w.Body.Bytes(), &kubeConfig); err != nil {
+					t.Fatalf("Failed to parse JSON response: %v", err)
+				}
+				if len(kubeConfig.Contexts) != len(server.getAllConfigNames()) {
+					t.Errorf("Expected all %d configs merged, got %d contexts",
+						len(server.getAllConfigNames()), len(kubeConfig.Contexts))
+				}
+			}
+		})
+	}
+}
+
+func TestServer_ForceSecure(t *testing.T) {
+	configsDir := t.TempDir()
+	insecureConfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: insecure-cluster
+  cluster:
+    server: https://insecure.example.com
+    insecure-skip-tls-verify: true
+contexts:
+- name: insecure-context
+  context:
+    cluster: insecure-cluster
+    user: insecure-user
+current-context: insecure-context
+users:
+- name: insecure-user
+  user:
+    token: insecure-token
+`
+	if err := os.WriteFile(filepath.Join(configsDir, "insecure.yaml"), []byte(insecureConfig), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml"})
+
+	tests := []struct {
+		name           string
+		forceSecure    bool
+		expectInsecure bool
+	}{
+		{name: "flag preserved by default", forceSecure: false, expectInsecure: true},
+		{name: "flag stripped when forced secure", forceSecure: true, expectInsecure: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerWithConfigs(t, configsDir)
+			server.ForceSecure = tt.forceSecure
+
+			req := httptest.NewRequest("GET", "/yaml/get?name=insecure&name=dev", nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsYaml(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			if got := strings.Contains(w.Body.String(), "insecure-skip-tls-verify: true"); got != tt.expectInsecure {
+				t.Errorf("Expected insecure-skip-tls-verify %v, got:\n%s", tt.expectInsecure, w.Body.String())
+			}
+
+			// The loaded config itself is never modified
+			loaded, _ := server.lookupConfig("insecure")
+			if !loaded.Clusters[0].Cluster.InsecureSkipTLSVerify {
+				t.Error("Expected loaded config to keep insecure-skip-tls-verify")
+			}
+		})
+	}
+}
+
+func TestServer_HandleListConfigContexts(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml", "prod.yaml": "prod.yaml"})
+	noContext := strings.Replace(string(testutil.LoadTestData(t, "kubeconfigs/valid-test.yaml")),
+		"current-context: test-context\n", "", 1)
+	if err := os.WriteFile(filepath.Join(configsDir, "test.yaml"), []byte(noContext), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	server, _ := createTestServerWithConfigs(t, configsDir)
+
+	req := httptest.NewRequest("GET", "/json/list/contexts", nil)
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var contexts []ConfigContext
+	if err := json.Unmarshal(w.Body.Bytes(), &contexts); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+
+	expected := []ConfigContext{
+		{Name: "dev", CurrentContext: "dev-context"},
+		{Name: "prod", CurrentContext: "prod-context"},
+		{Name: "test", CurrentContext: ""},
+	}
+	if !slices.Equal(contexts, expected) {
+		t.Errorf("Expected %v, got %v", expected, contexts)
+	}
+}
+
+func TestServer_HandleConfigsDetail(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml", "prod.yaml": "prod.yaml"})
+	server, _ := createTestServerWithConfigs(t, configsDir)
+
+	req := httptest.NewRequest("GET", "/json/configs", nil)
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if strings.Contains(w.Body.String(), "token") {
+		t.Errorf("Expected no credentials in response, got %s", w.Body.String())
+	}
+	var details []ConfigDetail
+	if err := json.Unmarshal(w.Body.Bytes(), &details); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+
+	expected := []ConfigDetail{
+		{Name: "dev", ClusterName: "dev-cluster", Server: "https://dev.example.com", CurrentContext: "dev-context", Contexts: 1},
+		{Name: "prod", ClusterName: "prod-cluster", Server: "https://prod.example.com", CurrentContext: "prod-context", Contexts: 1},
+	}
+	if !slices.Equal(details, expected) {
+		t.Errorf("Expected %v, got %v", expected, details)
+	}
+}
+
+// TestServer_TemplateIndex_Delims tests rendering the index template with custom delimiters
+func TestServer_TemplateIndex_Delims(t *testing.T) {
+	webDir := t.TempDir()
+	template := `<p v-if="{{ ready }}">[[ join .names "," ]]</p>`
+	if err := os.WriteFile(filepath.Join(webDir, "index.html"), []byte(template), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml", "prod.yaml": "prod.yaml"})
+
+	logger := log.New(os.Stderr)
+	logger.SetLevel(log.FatalLevel)
+	server, err := NewServer(&Server{
+		ConfigsDir:         configsDir,
+		WebDir:             webDir,
+		Logger:             logger,
+		TemplateLeftDelim:  "[[",
+		TemplateRightDelim: "]]",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	server.HandleIndex(w, httptest.NewRequest("GET", "/", nil))
+
+	expected := `<p v-if="{{ ready }}">dev,prod</p>`
+	if w.Body.String() != expected {
+		t.Errorf("Expected body %q, got %q", expected, w.Body.String())
+	}
+}
+
+func TestServer_TemplateIndex_DefaultFormat(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultFormat string
+		expected      string
+		wantErr       bool
+	}{
+		{name: "default", defaultFormat: "", expected: "yaml"},
+		{name: "json", defaultFormat: "json", expected: "json"},
+		{name: "unknown", defaultFormat: "toml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConfig, _ := createTestServerRaw(t, testutil.GetValidKubeConfigsDir(t))
+			serverConfig.DefaultFormat = tt.defaultFormat
+			server, err := NewServer(serverConfig)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error for unknown default format")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+
+			data, err := server.indexData()
+			if err != nil {
+				t.Fatalf("Failed to get index data: %v", err)
+			}
+			if data["defaultFormat"] != tt.expected {
+				t.Errorf("Expected default format %q, got %v", tt.expected, data["defaultFormat"])
+			}
+		})
+	}
+
+	t.Run("index page", func(t *testing.T) {
+		server, _ := createTestServerValid(t)
+		server.DefaultFormat = "json"
+		server.WebDir = filepath.Join("..", "..", "web")
+
+		w := httptest.NewRecorder()
+		server.HandleIndex(w, httptest.NewRequest("GET", "/", nil))
+		if !strings.Contains(w.Body.String(), `const defaultFormat = "json";`) {
+			t.Error("Expected the index page to fetch configs as JSON")
+		}
+	})
+}
+
+func TestServer_SkipInvalidConfigs(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{
+		"dev.yaml":     "dev.yaml",
+		"prod.yaml":    "prod.yaml",
+		"invalid.yaml": "invalid.yaml",
+	})
+
+	t.Run("startup fails by default", func(t *testing.T) {
+		serverConfig, _ := createTestServerRaw(t, configsDir)
+		if _, err := NewServer(serverConfig); err == nil {
+			t.Error("Expected error for invalid config")
+		}
+	})
+
+	t.Run("invalid configs skipped", func(t *testing.T) {
+		serverConfig, _ := createTestServerRaw(t, configsDir)
+		serverConfig.SkipInvalidConfigs = true
+		server, err := NewServer(serverConfig)
+		if err != nil {
+			t.Fatalf("Failed to create test server: %v", err)
+		}
+
+		if names := listConfigNames(t, server); !slices.Equal(names, []string{"dev", "prod"}) {
+			t.Errorf("Expected valid configs to be listed, got %v", names)
+		}
+
+		invalid := server.InvalidConfigs()
+		if len(invalid) != 1 || invalid[0].Name != "invalid" || invalid[0].Error == "" {
+			t.Fatalf("Expected invalid config with error, got %v", invalid)
+		}
+
+		req := httptest.NewRequest("GET", "/json/list?invalid=true", nil)
+		w := httptest.NewRecorder()
+		server.HandleListConfigsJson(w, req)
+
+		var listed []InvalidConfig
+		if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if !slices.Equal(listed, invalid) {
+			t.Errorf("Expected %v, got %v", invalid, listed)
+		}
+		if w.Header().Get("X-Total-Count") != "1" {
+			t.Errorf("Expected X-Total-Count 1, got %q", w.Header().Get("X-Total-Count"))
+		}
+	})
+}
+
+func TestServer_CurrentContextPriority(t *testing.T) {
+	tests := []struct {
+		name            string
+		priority        []string
+		defaultContext  string
+		url             string
+		expectedContext string
+	}{
+		{name: "sorted first without priority", url: "/json/get", expectedContext: "dev-context"},
+		{name: "priority wins", priority: []string{"prod"}, url: "/json/get", expectedContext: "prod-context"},
+		{name: "first present priority wins", priority: []string{"staging", "valid-test", "prod"}, url: "/json/get", expectedContext: "test-context"},
+		{name: "priority not requested", priority: []string{"prod"}, url: "/json/get?name=dev&name=valid-test", expectedContext: "dev-context"},
+		{name: "priority wins over default context", priority: []string{"prod"}, defaultContext: "dev-context", url: "/json/get", expectedContext: "prod-context"},
+		{name: "default context without priority match", priority: []string{"staging"}, defaultContext: "test-context", url: "/json/get", expectedContext: "test-context"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.CurrentContextPriority = tt.priority
+			server.DefaultCurrentContext = tt.defaultContext
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsJson(w, req)
+
+			var kubeConfig KubeConfig
+			if err := json.Unmarshal(w.Body.Bytes(), &kubeConfig); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			if kubeConfig.CurrentContext != tt.expectedContext {
+				t.Errorf("Expected current context %s, got %s", tt.expectedContext, kubeConfig.CurrentContext)
+			}
+		})
+	}
+}
+
+func TestServer_ClusterFieldsPreserved(t *testing.T) {
+	configsDir := t.TempDir()
+	cluster := `- cluster:
+    certificate-authority-data: dGVzdA==
+    server: https://corp.example.com
+    insecure-skip-tls-verify: true
+    tls-server-name: api.corp.internal
+    proxy-url: http://proxy.corp.example.com:3128
+  name: corp-cluster
+`
+	config := "apiVersion: v1\nkind: Config\nclusters:\n" + cluster + `contexts:
+- context:
+    cluster: corp-cluster
+    user: corp-user
+  name: corp-context
+current-context: corp-context
+users:
+- name: corp-user
+  user:
+    token: corp-token
+`
+	if err := os.WriteFile(filepath.Join(configsDir, "corp.yaml"), []byte(config), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	server, _ := createTestServerWithConfigs(t, configsDir)
+
+	req := httptest.NewRequest("GET", "/yaml/get?name=corp", nil)
+	w := httptest.NewRecorder()
+	server.HandleGetKubeConfigsYaml(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var served KubeConfig
+	if err := yaml.Unmarshal(w.Body.Bytes(), &served); err != nil {
+		t.Fatalf("Failed to parse YAML response: %v", err)
+	}
+	expected := clusterInfo{
+		CertificateAuthorityData: "dGVzdA==",
+		Server:                   "https://corp.example.com",
+		InsecureSkipTLSVerify:    true,
+		TLSServerName:            "api.corp.internal",
+		ProxyURL:                 "http://proxy.corp.example.com:3128",
+	}
+	if len(served.Clusters) != 1 || served.Clusters[0].Cluster != expected {
+		t.Errorf("Expected cluster %+v, got %+v", expected, served.Clusters)
+	}
+}
+
+func TestServer_ContextNamespacePreserved(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml"})
+	config := `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority-data: dGVzdA==
+    server: https://foo.example.com
+  name: foo-cluster
+contexts:
+- context:
+    cluster: foo-cluster
+    user: foo-user
+    namespace: team-a
+  name: foo-context
+current-context: foo-context
+users:
+- name: foo-user
+  user:
+    token: foo-token
+`
+	if err := os.WriteFile(filepath.Join(configsDir, "foo.yaml"), []byte(config), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	server, _ := createTestServerWithConfigs(t, configsDir)
+
+	for _, url := range []string{"/yaml/get?name=foo", "/yaml/get?name=dev&name=foo"} {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		server.HandleGetKubeConfigsYaml(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status code %d, got %d", url, http.StatusOK, w.Code)
+		}
+		var served KubeConfig
+		if err := yaml.Unmarshal(w.Body.Bytes(), &served); err != nil {
+			t.Fatalf("%s: failed to parse YAML response: %v", url, err)
+		}
+		index := slices.IndexFunc(served.Contexts, func(c contextEntry) bool { return c.Name == "foo-context" })
+		if index < 0 || served.Contexts[index].Context.Namespace != "team-a" {
+			t.Errorf("%s: expected foo-context namespace team-a, got %+v", url, served.Contexts)
+		}
+	}
+}
+
+func TestServer_YAMLQuotesAmbiguousScalars(t *testing.T) {
+	configsDir := t.TempDir()
+	config := `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority-data: dGVzdA==
+    server: https://foo.example.com
+  name: "1_000"
+contexts:
+- context:
+    cluster: "1_000"
+    user: "yes"
+  name: foo-context
+current-context: foo-context
+users:
+- name: "yes"
+  user:
+    token: "0123"
+`
+	if err := os.WriteFile(filepath.Join(configsDir, "foo.yaml"), []byte(config), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	server, _ := createTestServerWithConfigs(t, configsDir)
+
+	req := httptest.NewRequest("GET", "/yaml/get?name=foo", nil)
+	w := httptest.NewRecorder()
+	server.HandleGetKubeConfigsYaml(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	// Unquoted these would parse as numbers or booleans in strict or YAML 1.1 clients
+	for _, quoted := range []string{`token: "0123"`, `name: "yes"`, `name: "1_000"`} {
+		if !strings.Contains(w.Body.String(), quoted) {
+			t.Errorf("Expected response to contain %s, got:\n%s", quoted, w.Body.String())
+		}
+	}
+
+	var served map[string]any
+	if err := yaml.Unmarshal(w.Body.Bytes(), &served); err != nil {
+		t.Fatalf("Failed to parse YAML response: %v", err)
+	}
+	user := served["users"].([]any)[0].(map[string]any)
+	if token := user["user"].(map[string]any)["token"]; token != "0123" {
+		t.Errorf("Expected token to round-trip as string \"0123\", got %#v", token)
+	}
+}
+
+func TestServer_CurrentFrom(t *testing.T) {
+	server, _ := createTestServerValid(t)
+	// Prefer dev by default, the client's choice must still win
+	server.DefaultCurrentContext = "dev-context"
+	noCurrent := *server.LoadedConfigs["valid-test"]
+	noCurrent.CurrentContext = ""
+	server.LoadedConfigs["valid-test"] = &noCurrent
+
+	tests := []struct {
+		name            string
+		url             string
+		expectedStatus  int
+		expectedCurrent string
+	}{
+		{
+			name:            "current context of named config",
+			url:             "/json/get?name=dev&name=prod&current-from=prod",
+			expectedStatus:  http.StatusOK,
+			expectedCurrent: "prod-context",
+		},
+		{
+			name:            "without current-from",
+			url:             "/json/get?name=dev&name=prod",
+			expectedStatus:  http.StatusOK,
+			expectedCurrent: "dev-context",
+		},
+		{
+			name:           "config without current context",
+			url:            "/json/get?name=dev&name=valid-test&current-from=valid-test",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "config not merged",
+			url:            "/json/get?name=dev&current-from=prod",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsJson(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var served KubeConfig
+			if err := json.Unmarshal(w.Body.Bytes(), &served); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			if served.CurrentContext != tt.expectedCurrent {
+				t.Errorf("Expected current context %s, got %s", tt.expectedCurrent, served.CurrentContext)
+			}
+		})
+	}
+}
+
+func TestServer_GetEmptyStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		emptyStatus    int
+		expectedStatus int
+	}{
+		{name: "default", emptyStatus: 0, expectedStatus: http.StatusOK},
+		{name: "ok", emptyStatus: http.StatusOK, expectedStatus: http.StatusOK},
+		{name: "not found", emptyStatus: http.StatusNotFound, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configsDir := t.TempDir()
+			testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml"})
+			server, _ := createTestServerWithConfigs(t, configsDir)
+			server.GetEmptyStatus = tt.emptyStatus
+
+			// Empty the config set with a reload
+			if err := os.Remove(filepath.Join(configsDir, "dev.yaml")); err != nil {
+				t.Fatalf("Failed to remove config: %v", err)
+			}
+			if _, err := server.Reload(); err != nil {
+				t.Fatalf("Failed to reload: %v", err)
+			}
+
+			req := httptest.NewRequest("GET", "/json/get", nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsJson(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("invalid status", func(t *testing.T) {
+		server, _ := createTestServerRaw(t, testutil.GetValidKubeConfigsDir(t))
+		server.GetEmptyStatus = http.StatusTeapot
+		if _, err := NewServer(server); err == nil {
+			t.Error("Expected error for unsupported get empty status")
+		}
+	})
+}
+
+func TestServer_CurrentContextParam(t *testing.T) {
+	server, _ := createTestServerValid(t)
+
+	tests := []struct {
+		name            string
+		url             string
+		expectedStatus  int
+		expectedCurrent string
+	}{
+		{
+			name:            "merged context",
+			url:             "/json/get?name=dev&name=prod&current-context=prod-context",
+			expectedStatus:  http.StatusOK,
+			expectedCurrent: "prod-context",
+		},
+		{
+			name:            "default without current-context",
+			url:             "/json/get?name=dev&name=prod",
+			expectedStatus:  http.StatusOK,
+			expectedCurrent: "dev-context",
+		},
+		{
+			name:            "wins over current-from",
+			url:             "/json/get?name=dev&name=prod&current-from=dev&current-context=prod-context",
+			expectedStatus:  http.StatusOK,
+			expectedCurrent: "prod-context",
+		},
+		{
+			name:           "context not merged",
+			url:            "/json/get?name=dev&current-context=prod-context",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsJson(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var served KubeConfig
+			if err := json.Unmarshal(w.Body.Bytes(), &served); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			if served.CurrentContext != tt.expectedCurrent {
+				t.Errorf("Expected current context %s, got %s", tt.expectedCurrent, served.CurrentContext)
+			}
+		})
+	}
+}
+
+// TestServer_CurrentContextOnMissing requests a config whose current-context isn't defined in it
+func TestServer_CurrentContextOnMissing(t *testing.T) {
+	tempDir := t.TempDir()
+	data, err := os.ReadFile(filepath.Join(testutil.GetValidKubeConfigsDir(t), "dev.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	data = []byte(strings.Replace(string(data), "current-context: dev-context", "current-context: missing-context", 1))
+	if err := os.WriteFile(filepath.Join(tempDir, "dangling.yaml"), data, 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		onMissing       string
+		query           string
+		expectedStatus  int
+		expectedCurrent string
+	}{
+		{name: "default clears", onMissing: "", query: "name=dangling&current-from=dangling", expectedStatus: http.StatusOK},
+		{name: "clear", onMissing: CurrentContextOnMissingClear, query: "name=dangling&current-from=dangling", expectedStatus: http.StatusOK},
+		{name: "error", onMissing: CurrentContextOnMissingError, query: "name=dangling&current-from=dangling", expectedStatus: http.StatusBadRequest},
+		{name: "without current-from clears", onMissing: CurrentContextOnMissingClear, query: "name=dangling", expectedStatus: http.StatusOK},
+		{name: "without current-from errors", onMissing: CurrentContextOnMissingError, query: "name=dangling", expectedStatus: http.StatusBadRequest},
+		{
+			name:            "current-context override",
+			onMissing:       CurrentContextOnMissingError,
+			query:           "name=dangling&current-context=dev-context",
+			expectedStatus:  http.StatusOK,
+			expectedCurrent: "dev-context",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConfig, _ := createTestServerRaw(t, tempDir)
+			serverConfig.CurrentContextOnMissing = tt.onMissing
+			server, err := NewServer(serverConfig)
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+
+			req := httptest.NewRequest("GET", "/json/get?"+tt.query, nil)
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var served KubeConfig
+			if err := json.Unmarshal(w.Body.Bytes(), &served); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			if served.CurrentContext != tt.expectedCurrent {
+				t.Errorf("Expected current context %q, got %q", tt.expectedCurrent, served.CurrentContext)
+			}
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		serverConfig, _ := createTestServerRaw(t, testutil.GetValidKubeConfigsDir(t))
+		serverConfig.CurrentContextOnMissing = "ignore"
+		if _, err := NewServer(serverConfig); err == nil {
+			t.Error("Expected error for unknown current context on missing handling")
+		}
+	})
+}
+
+// writeConfigWithServer writes the dev config with its cluster server URL replaced and returns its dir
+func writeConfigWithServer(t *testing.T, server string) string {
+	tempDir := t.TempDir()
+	data, err := os.ReadFile(filepath.Join(testutil.GetValidKubeConfigsDir(t), "dev.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	data = []byte(strings.Replace(string(data), "https://dev.example.com", server, 1))
+	if err := os.WriteFile(filepath.Join(tempDir, "dev.yaml"), data, 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return tempDir
+}
+
+func TestServer_StrictServerURLs(t *testing.T) {
+	tests := []struct {
+		name        string
+		server      string
+		strict      bool
+		wantErr     bool
+		wantWarning bool
+	}{
+		{name: "https ok", server: "https://dev.example.com", strict: true},
+		{name: "http warns", server: "http://dev.example.com", wantWarning: true},
+		{name: "http fails in strict mode", server: "http://dev.example.com", strict: true, wantErr: true},
+		{name: "malformed fails in strict mode", server: "https://dev example com", strict: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConfig, _ := createTestServerRaw(t, writeConfigWithServer(t, tt.server))
+			var logs bytes.Buffer
+			serverConfig.Logger = log.New(&logs)
+			serverConfig.StrictServerURLs = tt.strict
+
+			server, err := NewServer(serverConfig)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected error for invalid server URL")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+			if _, exists := server.LoadedConfigs["dev"]; !exists {
+				t.Error("Expected config to be loaded")
+			}
+			if warned := strings.Contains(logs.String(), "Suspicious server URL"); warned != tt.wantWarning {
+				t.Errorf("Expected warning %v, got logs: %s", tt.wantWarning, logs.String())
+			}
+		})
+	}
+}
+
+func TestServer_ResolveServerHosts(t *testing.T) {
+	originalLookupHost := lookupHost
+	t.Cleanup(func() { lookupHost = originalLookupHost })
+	lookupHost = func(_ context.Context, host string) ([]string, error) {
+		if host == "dev.example.com" {
+			return []string{"192.0.2.1"}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	tests := []struct {
+		name        string
+		server      string
+		resolve     bool
+		strict      bool
+		wantErr     bool
+		wantWarning bool
+	}{
+		{name: "resolvable host", server: "https://dev.example.com", resolve: true, strict: true},
+		{name: "unresolvable host warns", server: "https://typo.example.com", resolve: true, wantWarning: true},
+		{name: "unresolvable host fails in strict mode", server: "https://typo.example.com", resolve: true, strict: true, wantErr: true},
+		{name: "ip address isn't looked up", server: "https://192.0.2.10:6443", resolve: true, strict: true},
+		{name: "disabled", server: "https://typo.example.com", strict: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConfig, _ := createTestServerRaw(t, writeConfigWithServer(t, tt.server))
+			var logs bytes.Buffer
+			serverConfig.Logger = log.New(&logs)
+			serverConfig.StrictServerURLs = tt.strict
+			serverConfig.ResolveServerHosts = tt.resolve
+
+			server, err := NewServer(serverConfig)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected error for unresolvable server host")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+			if _, exists := server.LoadedConfigs["dev"]; !exists {
+				t.Error("Expected config to be loaded")
+			}
+			if warned := strings.Contains(logs.String(), "unresolvable server host"); warned != tt.wantWarning {
+				t.Errorf("Expected warning %v, got logs: %s", tt.wantWarning, logs.String())
+			}
+		})
+	}
+}
+
+func TestServer_ConvertCAFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, tempDir, map[string]string{"dev.yaml": "dev.yaml"})
+	data, err := os.ReadFile(filepath.Join(testutil.GetValidKubeConfigsDir(t), "prod.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	data = []byte(strings.Replace(string(data),
+		"certificate-authority-data: cHJvZC1jZXJ0", "certificate-authority: certs/prod-ca.crt", 1))
+	if err := os.WriteFile(filepath.Join(tempDir, "prod.yaml"), data, 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(tempDir, "certs"), 0o755); err != nil {
+		t.Fatalf("Failed to create certs dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "certs", "prod-ca.crt"), []byte("prod-cert"), 0o644); err != nil {
+		t.Fatalf("Failed to write certificate authority: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		convert      bool
+		config       string
+		expectedData string
+		expectedCA   string
+	}{
+		{name: "data form", convert: true, config: "dev", expectedData: "ZGV2LWNlcnQ="},
+		{name: "file reference inlined", convert: true, config: "prod", expectedData: "cHJvZC1jZXJ0"},
+		{name: "file reference kept by default", config: "prod", expectedCA: "certs/prod-ca.crt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConfig, _ := createTestServerRaw(t, tempDir)
+			serverConfig.ConvertCAFiles = tt.convert
+			server, err := NewServer(serverConfig)
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+
+			req := httptest.NewRequest("GET", "/json/get?name="+tt.config, nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsJson(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			var served KubeConfig
+			if err := json.Unmarshal(w.Body.Bytes(), &served); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			cluster := served.Clusters[0].Cluster
+			if cluster.CertificateAuthorityData != tt.expectedData || cluster.CertificateAuthority != tt.expectedCA {
+				t.Errorf("Expected data %q and reference %q, got %q and %q",
+					tt.expectedData, tt.expectedCA, cluster.CertificateAuthorityData, cluster.CertificateAuthority)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		missingDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(missingDir, "prod.yaml"), data, 0o644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		serverConfig, _ := createTestServerRaw(t, missingDir)
+		serverConfig.ConvertCAFiles = true
+		if _, err := NewServer(serverConfig); err == nil {
+			t.Error("Expected error for missing certificate authority file")
+		}
+	})
+}
+
+// writeManyConfigs writes count configs with distinct names based on dev.yaml of sourceDir
+// into a temp dir and returns it
+func writeManyConfigs(tb testing.TB, sourceDir string, count int) string {
+	data, err := os.ReadFile(filepath.Join(sourceDir, "dev.yaml"))
+	if err != nil {
+		tb.Fatalf("Failed to read config: %v", err)
+	}
+	dir := tb.TempDir()
+	for i := range count {
+		name := fmt.Sprintf("cluster%03d", i)
+		config := strings.ReplaceAll(string(data), "dev", name)
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(config), 0o644); err != nil {
+			tb.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestServer_LoadConcurrency(t *testing.T) {
+	configsDir := writeManyConfigs(t, testutil.GetValidKubeConfigsDir(t), 500)
+
+	for _, concurrency := range []int{0, 1, 16} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			serverConfig, _ := createTestServerRaw(t, configsDir)
+			serverConfig.LoadConcurrency = concurrency
+			server, err := NewServer(serverConfig)
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+
+			names, err := server.listConfigs()
+			if err != nil {
+				t.Fatalf("Failed to list configs: %v", err)
+			}
+			if len(names) != 500 {
+				t.Fatalf("Expected 500 configs, got %d", len(names))
+			}
+			if !slices.IsSorted(names) || names[0] != "cluster000" {
+				t.Errorf("Expected sorted names starting with cluster000, got %v", names[:3])
+			}
+		})
+	}
+
+	t.Run("first invalid file in order", func(t *testing.T) {
+		dir := writeManyConfigs(t, testutil.GetValidKubeConfigsDir(t), 50)
+		for _, name := range []string{"cluster010.yaml", "cluster040.yaml"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("invalid: [yaml"), 0o644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+		}
+		serverConfig, _ := createTestServerRaw(t, dir)
+		serverConfig.LoadConcurrency = 8
+		_, err := NewServer(serverConfig)
+		if err == nil || !strings.Contains(err.Error(), "cluster010.yaml") {
+			t.Errorf("Expected error of the first invalid file, got %v", err)
+		}
+	})
+}
+
+func TestServer_OutputApiVersion(t *testing.T) {
+	tests := []struct {
+		name             string
+		outputApiVersion string
+		expected         string
+	}{
+		{name: "default", outputApiVersion: "", expected: "v1"},
+		{name: "custom", outputApiVersion: "v2alpha1", expected: "v2alpha1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := createTestServerValid(t)
+			server.OutputApiVersion = tt.outputApiVersion
+
+			for _, url := range []string{"/yaml/get?name=dev", "/yaml/get?name=dev&name=prod"} {
+				req := httptest.NewRequest("GET", url, nil)
+				w := httptest.NewRecorder()
+				server.HandleGetKubeConfigsYaml(w, req)
+
+				if w.Code != http.StatusOK {
+					t.Fatalf("%s: expected status code %d, got %d", url, http.StatusOK, w.Code)
+				}
+				if !strings.HasPrefix(w.Body.String(), "apiVersion: "+tt.expected+"\n") {
+					t.Errorf("%s: expected apiVersion %s, got:\n%s", url, tt.expected, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestServer_MergeSkipModes(t *testing.T) {
+	server, _ := createTestServerValid(t)
+
+	t.Run("summary lists skipped missing config", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/json/get?name=dev&name=nonexistent&on-missing=skip&summary=true", nil)
+		w := httptest.NewRecorder()
+		server.HandleGetKubeConfigsJson(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var summary MergeSummary
+		if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if summary.Config == nil || summary.Config.CurrentContext != "dev-context" {
+			t.Errorf("Expected merged dev config, got %+v", summary.Config)
+		}
+		if len(summary.Warnings) != 1 || summary.Warnings[0].Name != "nonexistent" ||
+			!strings.Contains(summary.Warnings[0].Reason, "not found") {
+			t.Errorf("Expected warning for the missing config, got %+v", summary.Warnings)
+		}
+	})
+
+	t.Run("conflicting config is skipped", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/json/get?name=dev&name=dev&on-conflict=skip&summary=true", nil)
+		w := httptest.NewRecorder()
+		server.HandleGetKubeConfigsJson(w, req)
+
+		var summary MergeSummary
+		if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if len(summary.Config.Contexts) != 1 || len(summary.Warnings) != 1 ||
+			!strings.Contains(summary.Warnings[0].Reason, "duplicate") {
+			t.Errorf("Expected the second dev config to be skipped, got %+v", summary)
+		}
+	})
+
+	t.Run("errors by default", func(t *testing.T) {
+		tests := []struct {
+			url  string
+			code int
+		}{
+			{"/json/get?name=dev&name=nonexistent", http.StatusNotFound},
+			{"/json/get?name=dev&on-missing=ignore", http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsJson(w, req)
+			if w.Code != tt.code {
+				t.Errorf("%s: expected status code %d, got %d", tt.url, tt.code, w.Code)
+			}
+		}
+	})
+
+	t.Run("summary is ignored in YAML mode", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/yaml/get?name=dev&on-missing=skip&summary=true", nil)
+		w := httptest.NewRecorder()
+		server.HandleGetKubeConfigsYaml(w, req)
+		if strings.Contains(w.Body.String(), "warnings") {
+			t.Errorf("Expected plain kubeconfig, got %s", w.Body.String())
+		}
+	})
+}
+
+func TestServer_MergeConflictStrategy(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml", "team.yaml": "dev.yaml"})
+	logger := log.New(os.Stderr)
+	logger.SetLevel(log.ErrorLevel)
+
+	t.Run("error", func(t *testing.T) {
+		if _, err := NewServer(&Server{ConfigsDir: configsDir, Logger: logger}); err == nil {
+			t.Fatal("Expected conflicting configs to fail merge validation")
+		}
+
+		server, err := NewServer(&Server{
+			ConfigsDir:            configsDir,
+			WebDir:                testutil.GetTestDataDir(t),
+			Logger:                logger,
+			SkipMergeValidation:   true,
+			MergeConflictStrategy: MergeConflictError,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		req := httptest.NewRequest("GET", "/yaml/get", nil)
+		w := httptest.NewRecorder()
+		server.HandleGetKubeConfigsYaml(w, req)
+		if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "duplicate") {
+			t.Errorf("Expected duplicate names to fail, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		server, err := NewServer(&Server{
+			ConfigsDir:            configsDir,
+			WebDir:                testutil.GetTestDataDir(t),
+			Logger:                logger,
+			MergeConflictStrategy: MergeConflictRename,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		req := httptest.NewRequest("GET", "/yaml/get?name=dev&name=team", nil)
+		w := httptest.NewRecorder()
+		server.HandleGetKubeConfigsYaml(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var served KubeConfig
+		if err := yaml.Unmarshal(w.Body.Bytes(), &served); err != nil {
+			t.Fatalf("Failed to parse YAML response: %v", err)
+		}
+		// The later config is renamed and its context points at its own renamed entries
+		expected := []contextEntry{
+			{Name: "dev-context", Context: contextInfo{Cluster: "dev-cluster", User: "dev-user"}},
+			{Name: "team-dev-context", Context: contextInfo{Cluster: "team-dev-cluster", User: "team-dev-user"}},
+		}
+		if !slices.Equal(served.Contexts, expected) {
+			t.Errorf("Expected contexts %+v, got %+v", expected, served.Contexts)
+		}
+		if served.Clusters[1].Name != "team-dev-cluster" || served.Users[1].Name != "team-dev-user" {
+			t.Errorf("Expected renamed cluster and user, got %+v and %+v", served.Clusters, served.Users)
+		}
+		if served.CurrentContext != "dev-context" {
+			t.Errorf("Expected current context of the first config, got %s", served.CurrentContext)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := NewServer(&Server{ConfigsDir: configsDir, Logger: logger, MergeConflictStrategy: "merge"})
+		if err == nil {
+			t.Fatal("Expected unknown strategy to be rejected")
+		}
+	})
+}
+
+func TestServer_PrefixWithConfigName(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml", "team.yaml": "dev.yaml"})
+	logger := log.New(os.Stderr)
+	logger.SetLevel(log.ErrorLevel)
+
+	// The same names in both files don't conflict once prefixed
+	server, err := NewServer(&Server{
+		ConfigsDir:           configsDir,
+		WebDir:               testutil.GetTestDataDir(t),
+		Logger:               logger,
+		PrefixWithConfigName: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	team := server.LoadedConfigs["team"]
+	expected := contextEntry{
+		Name:    "team-dev-context",
+		Context: contextInfo{Cluster: "team-dev-cluster", User: "team-dev-user"},
+	}
+	if team.Contexts[0] != expected || team.Clusters[0].Name != "team-dev-cluster" ||
+		team.Users[0].Name != "team-dev-user" || team.CurrentContext != "team-dev-context" {
+		t.Errorf("Expected team entries to be prefixed, got %+v", team)
+	}
+	// Names already starting with the config name are kept
+	if dev := server.LoadedConfigs["dev"]; dev.Contexts[0].Name != "dev-context" {
+		t.Errorf("Expected already prefixed names to be kept, got %+v", dev.Contexts)
+	}
+
+	req := httptest.NewRequest("GET", "/yaml/get", nil)
+	w := httptest.NewRecorder()
+	server.HandleGetKubeConfigsYaml(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var served KubeConfig
+	if err := yaml.Unmarshal(w.Body.Bytes(), &served); err != nil {
+		t.Fatalf("Failed to parse YAML response: %v", err)
+	}
+	if err := served.Validate(); err != nil {
+		t.Errorf("Expected a valid merged config: %v", err)
+	}
+	if err := (&KubeConfig{}).HasDuplicateNames(&served); err != nil {
+		t.Errorf("Expected unique names in merged config: %v", err)
+	}
+	if len(served.Contexts) != 2 {
+		t.Errorf("Expected contexts of both configs, got %+v", served.Contexts)
+	}
+}
+
+func TestServer_ServerOverride(t *testing.T) {
+	server, _ := createTestServerValid(t)
+
+	req := httptest.NewRequest("GET", "/yaml/get?name=dev&server=https://localhost:6443", nil)
+	w := httptest.NewRecorder()
+	server.HandleGetKubeConfigsYaml(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var served KubeConfig
+	if err := yaml.Unmarshal(w.Body.Bytes(), &served); err != nil {
+		t.Fatalf("Failed to parse YAML response: %v", err)
+	}
+	if served.Clusters[0].Cluster.Server != "https://localhost:6443" {
+		t.Errorf("Expected overridden server, got %s", served.Clusters[0].Cluster.Server)
+	}
+	if got := server.LoadedConfigs["dev"].Clusters[0].Cluster.Server; got != "https://dev.example.com" {
+		t.Errorf("Expected loaded config to be left unchanged, got %s", got)
+	}
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"multiple clusters", "/yaml/get?name=dev&name=prod&server=https://localhost:6443"},
+		{"relative URL", "/yaml/get?name=dev&server=localhost:6443"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			server.HandleGetKubeConfigsYaml(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
+
+func TestServer_ContextNamePattern(t *testing.T) {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml"})
+	nonConforming := strings.ReplaceAll(string(testutil.LoadTestData(t, "kubeconfigs/prod.yaml")),
+		"prod-context", "Prod_Context")
+	if err := os.WriteFile(filepath.Join(configsDir, "prod.yaml"), []byte(nonConforming), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	logger := log.New(os.Stderr)
+	logger.SetLevel(log.ErrorLevel)
+
+	tests := []struct {
+		name        string
+		pattern     string
+		skipInvalid bool
+		wantErr     bool
+		wantConfigs []string
+	}{
+		{name: "disabled", pattern: "", wantConfigs: []string{"dev", "prod"}},
+		{name: "non-conforming rejected", pattern: "^[a-z0-9-]+$", wantErr: true},
+		{name: "non-conforming skipped", pattern: "^[a-z0-9-]+$", skipInvalid: true, wantConfigs: []string{"dev"}},
+		{name: "all conforming", pattern: "^[a-zA-Z0-9_-]+$", wantConfigs: []string{"dev", "prod"}},
+		{name: "invalid pattern", pattern: "[", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := NewServer(&Server{
+				ConfigsDir:         configsDir,
+				WebDir:             testutil.GetTestDataDir(t),
+				Logger:             logger,
+				ContextNamePattern: tt.pattern,
+				SkipInvalidConfigs: tt.skipInvalid,
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected server creation to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+			if names := server.getAllConfigNames(); !slices.Equal(names, tt.wantConfigs) {
+				t.Errorf("Expected configs %v, got %v", tt.wantConfigs, names)
+			}
+		})
+	}
+}
+
+// benchmarkReadAllConfigs benchmarks loading a directory of 500 configs
+func benchmarkReadAllConfigs(b *testing.B, concurrency int) {
+	t := &testing.T{}
+	serverConfig, _ := createTestServerRaw(t, writeManyConfigs(b, testutil.GetValidKubeConfigsDir(t), 500))
+	serverConfig.LoadConcurrency = concurrency
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := serverConfig.readAllConfigs(); err != nil {
+			b.Fatalf("Benchmark failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkServer_ReadAllConfigs_Sequential(b *testing.B) {
+	benchmarkReadAllConfigs(b, 1)
+}
+
+func BenchmarkServer_ReadAllConfigs_Concurrent(b *testing.B) {
+	benchmarkReadAllConfigs(b, 0)
+}
+
+func TestNewValidationServer(t *testing.T) {
+	serverConfig, _ := createTestServerRaw(t, testutil.GetValidKubeConfigsDir(t))
+	server, err := NewValidationServer(serverConfig)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if names := server.getAllConfigNames(); len(names) != 0 {
+		t.Fatalf("Expected no configs to be loaded before validation, got %v", names)
+	}
+
+	count, err := server.ValidateConfigs()
+	if err != nil {
+		t.Fatalf("Failed to validate configs: %v", err)
+	}
+	if count != 5 {
+		t.Errorf("Expected 5 valid configs, got %d", count)
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+// writeSelfSignedCert writes a self-signed certificate for 127.0.0.1 and its key to dir
+func writeSelfSignedCert(t *testing.T, dir string) (certFile, keyFile string, pool *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kubedepot-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certFile = filepath.Join(dir, "tls.crt")
+	keyFile = filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
+
+func TestServer_Start_TLS(t *testing.T) {
+	certFile, keyFile, pool := writeSelfSignedCert(t, t.TempDir())
+	serverConfig, _ := createTestServerRaw(t, testutil.GetValidKubeConfigsDir(t))
+	serverConfig.TLSCertFile = certFile
+	serverConfig.TLSKeyFile = keyFile
+	server, err := NewServer(serverConfig)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	go func() {
+		_ = server.Start("0")
+	}()
+	defer server.Shutdown(context.Background())
+
+	// Wait for the server to start listening
+	for i := 0; i < 50 && server.Addr() == nil; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if server.Addr() == nil {
+		t.Fatal("Server didn't start listening")
+	}
+	_, port, _ := net.SplitHostPort(server.Addr().String())
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Get("https://127.0.0.1:" + port + "/json/list")
+	if err != nil {
+		t.Fatalf("Failed to request over HTTPS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var configs []string
+	if err := json.NewDecoder(resp.Body).Decode(&configs); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if len(configs) != 5 {
+		t.Errorf("Expected 5 configs, got %d", len(configs))
+	}
+}
+
+func TestNewServer_TLSValidation(t *testing.T) {
+	certFile, keyFile, _ := writeSelfSignedCert(t, t.TempDir())
+
+	tests := []struct {
+		name     string
+		certFile string
+		keyFile  string
+		wantErr  bool
+	}{
+		{name: "no TLS", certFile: "", keyFile: "", wantErr: false},
+		{name: "both files", certFile: certFile, keyFile: keyFile, wantErr: false},
+		{name: "only certificate", certFile: certFile, keyFile: "", wantErr: true},
+		{name: "only key", certFile: "", keyFile: keyFile, wantErr: true},
+		{name: "missing key file", certFile: certFile, keyFile: keyFile + ".missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConfig, _ := createTestServerRaw(t, testutil.GetValidKubeConfigsDir(t))
+			serverConfig.TLSCertFile = tt.certFile
+			serverConfig.TLSKeyFile = tt.keyFile
+
+			_, err := NewServer(serverConfig)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxUploadSize limits the size of uploaded kubeconfigs
+const maxUploadSize = 1 << 20
+
+// validateConfigName checks that a client-provided config name can be used as a file name
+func validateConfigName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// readUploadedConfig reads kubeconfig data from a multipart form field "config" or the raw body
+func readUploadedConfig(r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(nil, r.Body, maxUploadSize)
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return io.ReadAll(r.Body)
+	}
+
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		return nil, err
+	}
+	if file, _, err := r.FormFile("config"); err == nil {
+		defer file.Close()
+		return io.ReadAll(file)
+	}
+	return []byte(r.FormValue("config")), nil
+}
+
+// servesConfigsDir reports whether configs are loaded from the files of a configs directory,
+// not from Secrets or a single config file, so configs can be added to and removed from it
+func (s *Server) servesConfigsDir() bool {
+	if s.SecretSource {
+		return false
+	}
+	info, err := os.Stat(s.ConfigsDir)
+	return err == nil && info.IsDir()
+}
+
+// HandleUploadConfig stores a new kubeconfig in the configs directory and serves it right away.
+// The upload is loaded like the file it's written to, so it's served the same way after a reload
+func (s *Server) HandleUploadConfig(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if !s.servesConfigsDir() {
+		s.handleHTTPError(w, nil, "Uploads need a configs directory, not secrets or a single config file", http.StatusForbidden)
+		return
+	}
+
+	// Uploaded names follow NameCase like names derived from file names, so the config keeps
+	// its name when the written file is loaded again
+	name := s.applyNameCase(r.URL.Query().Get("name"))
+	if !validateConfigName(name) {
+		s.handleHTTPError(w, nil, "Invalid config name: "+name, http.StatusBadRequest)
+		return
+	}
+	// Files that wouldn't be loaded back on reload aren't written
+	fileName := name + ".yaml"
+	if !s.isAllowedExtension(fileName) {
+		s.handleHTTPError(w, nil, "Uploaded configs are stored as .yaml files, not an allowed extension", http.StatusBadRequest)
+		return
+	}
+	if !s.isIncluded(name) {
+		s.handleHTTPError(w, nil, "Config name doesn't match include patterns: "+name, http.StatusBadRequest)
+		return
+	}
+
+	data, err := readUploadedConfig(r)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to read uploaded config", http.StatusBadRequest)
+		return
+	}
+
+	filePath := filepath.Join(s.ConfigsDir, fileName)
+	loaded, err := s.loadConfigData(name, filePath, data)
+	if err != nil {
+		s.handleHTTPError(w, err, "Invalid kubeconfig", http.StatusBadRequest)
+		return
+	}
+	if err := loaded.kubeConfig.Validate(); err != nil {
+		s.handleHTTPError(w, err, "Invalid kubeconfig", http.StatusBadRequest)
+		return
+	}
+	if err := loaded.kubeConfig.HasMultipleEntries(); err != nil {
+		s.handleHTTPError(w, err, "Invalid kubeconfig", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.LoadedConfigs[name]; exists {
+		s.handleHTTPError(w, nil, "Config already exists: "+name, http.StatusConflict)
+		return
+	}
+	if !s.SkipMergeValidation {
+		configs := maps.Clone(s.LoadedConfigs)
+		configs[name] = loaded.kubeConfig
+		if err := s.validateConfigsMergeable(configs); err != nil {
+			s.handleHTTPError(w, err, "Config can't be merged with the loaded configs", http.StatusConflict)
+			return
+		}
+	}
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		s.handleHTTPError(w, err, "Config file already exists: "+name, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to write config file", http.StatusInternalServerError)
+		return
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filePath)
+		s.handleHTTPError(w, err, "Failed to write config file", http.StatusInternalServerError)
+		return
+	}
+
+	s.invalidateMergedCache()
+	s.LoadedConfigs[name] = loaded.kubeConfig
+	if s.ConfigMeta == nil {
+		s.ConfigMeta = make(map[string]*ConfigMeta)
+	}
+	s.ConfigMeta[name] = loaded.meta
+	s.rawCache.put(name, &rawConfig{data: data, modTime: time.Now()})
+	s.Logger.Info("Uploaded config", "name", name, "path", filePath)
+
+	w.WriteHeader(http.StatusCreated)
+	err = createJSONEncoder(w).Encode(map[string]string{"name": name})
+	if err != nil {
+		s.Logger.Error("Failed to encode upload response", "error", err)
+	}
+}
+
+// HandleDeleteConfig removes a loaded config and deletes its file from the configs directory
+func (s *Server) HandleDeleteConfig(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	// Configs from Secrets would come back on reload, and deleting the single config file
+	// would remove the configured kubeconfig itself
+	if !s.servesConfigsDir() {
+		s.handleHTTPError(w, nil, "Deletes need a configs directory, not secrets or a single config file", http.StatusForbidden)
+		return
+	}
+
+	// Loaded config names follow NameCase, so the name is looked up in the same case
+	name := s.applyNameCase(r.URL.Query().Get("name"))
+	if !validateConfigName(name) {
+		s.handleHTTPError(w, nil, "Invalid config name: "+name, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.LoadedConfigs[name]; !exists {
+		s.handleHTTPError(w, nil, "Config not found: "+name, http.StatusNotFound)
+		return
+	}
+
+	// Only files inside the configs directory are ever deleted, never the directory itself
+	if meta, exists := s.ConfigMeta[name]; exists && meta.Path != "" {
+		rel, err := filepath.Rel(s.ConfigsDir, meta.Path)
+		if err != nil || rel == "." || !filepath.IsLocal(rel) {
+			s.handleHTTPError(w, err, "Config file is outside of configs directory", http.StatusForbidden)
+			return
+		}
+		if err := os.Remove(meta.Path); err != nil && !os.IsNotExist(err) {
+			s.handleHTTPError(w, err, "Failed to delete config file", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	s.invalidateMergedCache()
+	delete(s.LoadedConfigs, name)
+	delete(s.ConfigMeta, name)
+	s.rawCache.remove(name)
+	s.Logger.Info("Deleted config", "name", name)
+
+	err := createJSONEncoder(w).Encode(map[string]string{"name": name})
+	if err != nil {
+		s.Logger.Error("Failed to encode delete response", "error", err)
+	}
+}
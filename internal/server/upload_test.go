@@ -0,0 +1,431 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+// testAdminToken is the AdminToken of upload test servers
+const testAdminToken = "admin-secret"
+
+// createUploadTestServer creates a server over a writable temp configs directory with
+// testAdminToken enabling the endpoints changing configs
+func createUploadTestServer(t *testing.T) *Server {
+	configsDir := t.TempDir()
+	testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml"})
+	server, _ := createTestServerWithConfigs(t, configsDir)
+	server.AdminToken = testAdminToken
+	return server
+}
+
+// newAdminRequest creates a request carrying testAdminToken
+func newAdminRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return req
+}
+
+// listConfigNames returns config names served by /json/list
+func listConfigNames(t *testing.T, server *Server) []string {
+	req := httptest.NewRequest("GET", "/json/list", nil)
+	w := httptest.NewRecorder()
+	server.HandleListConfigsJson(w, req)
+
+	var names []string
+	if err := json.Unmarshal(w.Body.Bytes(), &names); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	return names
+}
+
+func TestServer_ConfigChangesRequireAdmin(t *testing.T) {
+	prodConfig := string(testutil.LoadTestData(t, "kubeconfigs/prod.yaml"))
+	requests := []struct {
+		method string
+		target string
+		body   string
+	}{
+		{method: "POST", target: "/upload?name=prod", body: prodConfig},
+		{method: "DELETE", target: "/config?name=dev"},
+		{method: "POST", target: "/reload"},
+	}
+	tests := []struct {
+		name           string
+		adminToken     string
+		authToken      string
+		token          string
+		expectedStatus int
+	}{
+		{name: "disabled without admin token", token: testAdminToken, expectedStatus: http.StatusForbidden},
+		{name: "missing token", adminToken: testAdminToken, expectedStatus: http.StatusUnauthorized},
+		{name: "auth token isn't enough", adminToken: testAdminToken, authToken: "secret", token: "secret", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		for _, request := range requests {
+			t.Run(tt.name+" "+request.method+" "+request.target, func(t *testing.T) {
+				server := createUploadTestServer(t)
+				server.AdminToken = tt.adminToken
+				server.AuthToken = tt.authToken
+
+				req := httptest.NewRequest(request.method, request.target, strings.NewReader(request.body))
+				if tt.token != "" {
+					req.Header.Set("Authorization", "Bearer "+tt.token)
+				}
+				w := httptest.NewRecorder()
+				server.Handler().ServeHTTP(w, req)
+
+				if w.Code != tt.expectedStatus {
+					t.Errorf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+				}
+				if names := listConfigNames(t, server); !slices.Equal(names, []string{"dev"}) {
+					t.Errorf("Expected configs to be unchanged, got %v", names)
+				}
+				if _, err := os.Stat(filepath.Join(server.ConfigsDir, "prod.yaml")); !os.IsNotExist(err) {
+					t.Errorf("Expected no config file to be written, got %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestServer_HandleUploadConfig(t *testing.T) {
+	prodConfig := string(testutil.LoadTestData(t, "kubeconfigs/prod.yaml"))
+
+	t.Run("success", func(t *testing.T) {
+		server := createUploadTestServer(t)
+
+		req := newAdminRequest("POST", "/upload?name=prod", strings.NewReader(prodConfig))
+		w := httptest.NewRecorder()
+		server.HandleUploadConfig(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		if !slices.Contains(listConfigNames(t, server), "prod") {
+			t.Error("Expected uploaded config to be listed")
+		}
+		data, err := os.ReadFile(filepath.Join(server.ConfigsDir, "prod.yaml"))
+		if err != nil {
+			t.Fatalf("Expected uploaded config file to be written: %v", err)
+		}
+		if string(data) != prodConfig {
+			t.Error("Expected uploaded config file to match the request body")
+		}
+	})
+
+	t.Run("multipart form", func(t *testing.T) {
+		server := createUploadTestServer(t)
+
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, err := writer.CreateFormFile("config", "prod.yaml")
+		if err != nil {
+			t.Fatalf("Failed to create form file: %v", err)
+		}
+		part.Write([]byte(prodConfig))
+		writer.Close()
+
+		req := newAdminRequest("POST", "/upload?name=prod", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		server.HandleUploadConfig(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		if !slices.Contains(listConfigNames(t, server), "prod") {
+			t.Error("Expected uploaded config to be listed")
+		}
+	})
+
+	t.Run("name case", func(t *testing.T) {
+		server := createUploadTestServer(t)
+		server.NameCase = NameCaseLower
+
+		req := newAdminRequest("POST", "/upload?name=Prod", strings.NewReader(prodConfig))
+		w := httptest.NewRecorder()
+		server.HandleUploadConfig(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		if names := listConfigNames(t, server); !slices.Equal(names, []string{"dev", "prod"}) {
+			t.Errorf("Expected uploaded config to be listed as prod, got %v", names)
+		}
+		if _, err := os.Stat(filepath.Join(server.ConfigsDir, "prod.yaml")); err != nil {
+			t.Errorf("Expected uploaded config file to be written as prod.yaml: %v", err)
+		}
+
+		req = newAdminRequest("POST", "/upload?name=DEV", strings.NewReader(prodConfig))
+		w = httptest.NewRecorder()
+		server.HandleUploadConfig(w, req)
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("Expected status code %d for a name differing only by case, got %d", http.StatusConflict, w.Code)
+		}
+	})
+
+	t.Run("loaded like config files", func(t *testing.T) {
+		server := createUploadTestServer(t)
+		server.Normalize = true
+		body := "# labels: env=prod\n" + strings.Replace(prodConfig, "https://prod.example.com", "https://prod.example.com  ", 1)
+
+		w := httptest.NewRecorder()
+		server.HandleUploadConfig(w, newAdminRequest("POST", "/upload?name=prod", strings.NewReader(body)))
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		if got := server.LoadedConfigs["prod"].Clusters[0].Cluster.Server; got != "https://prod.example.com" {
+			t.Errorf("Expected uploaded config to be normalized, got server %q", got)
+		}
+		if got := server.ConfigMeta["prod"].Labels["env"]; got != "prod" {
+			t.Errorf("Expected uploaded config labels, got %v", server.ConfigMeta["prod"].Labels)
+		}
+	})
+
+	t.Run("rejected like config files", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			configure      func(server *Server)
+			configName     string
+			body           string
+			expectedStatus int
+		}{
+			{
+				name:           "conflicting entry names",
+				configName:     "dev-copy",
+				body:           string(testutil.LoadTestData(t, "kubeconfigs/dev.yaml")),
+				expectedStatus: http.StatusConflict,
+			},
+			{
+				name:           "strict server urls",
+				configure:      func(server *Server) { server.StrictServerURLs = true },
+				configName:     "prod",
+				body:           strings.Replace(prodConfig, "https://prod.example.com", "http://prod.example.com", 1),
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:           "disallowed extension",
+				configure:      func(server *Server) { server.AllowedExtensions = []string{".yml"} },
+				configName:     "prod",
+				body:           prodConfig,
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:           "not included",
+				configure:      func(server *Server) { server.Include = []string{"dev*"} },
+				configName:     "prod",
+				body:           prodConfig,
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:           "secret source",
+				configure:      func(server *Server) { server.SecretSource = true },
+				configName:     "prod",
+				body:           prodConfig,
+				expectedStatus: http.StatusForbidden,
+			},
+			{
+				name: "single file",
+				configure: func(server *Server) {
+					server.SingleFile = true
+					server.ConfigsDir = filepath.Join(server.ConfigsDir, "dev.yaml")
+				},
+				configName:     "prod",
+				body:           prodConfig,
+				expectedStatus: http.StatusForbidden,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				server := createUploadTestServer(t)
+				configsDir := server.ConfigsDir
+				if tt.configure != nil {
+					tt.configure(server)
+				}
+
+				w := httptest.NewRecorder()
+				server.HandleUploadConfig(w, newAdminRequest("POST", "/upload?name="+tt.configName, strings.NewReader(tt.body)))
+
+				if w.Code != tt.expectedStatus {
+					t.Errorf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+				}
+				if _, exists := server.LoadedConfigs[tt.configName]; exists {
+					t.Error("Expected rejected config not to be served")
+				}
+				if _, err := os.Stat(filepath.Join(configsDir, tt.configName+".yaml")); !os.IsNotExist(err) {
+					t.Errorf("Expected no config file to be written, got %v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("error cases", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			configName     string
+			body           string
+			expectedStatus int
+		}{
+			{name: "duplicate name", configName: "dev", body: prodConfig, expectedStatus: http.StatusConflict},
+			{name: "invalid yaml", configName: "broken", body: "invalid: yaml: content", expectedStatus: http.StatusBadRequest},
+			{name: "missing users", configName: "nousers", body: "clusters:\n  - name: c\ncontexts:\n  - name: x\n", expectedStatus: http.StatusBadRequest},
+			{name: "path separator", configName: "../prod", body: prodConfig, expectedStatus: http.StatusBadRequest},
+			{name: "empty name", configName: "", body: prodConfig, expectedStatus: http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				server := createUploadTestServer(t)
+
+				req := newAdminRequest("POST", "/upload?name="+tt.configName, strings.NewReader(tt.body))
+				w := httptest.NewRecorder()
+				server.HandleUploadConfig(w, req)
+
+				if w.Code != tt.expectedStatus {
+					t.Errorf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+				}
+				if names := listConfigNames(t, server); !slices.Equal(names, []string{"dev"}) {
+					t.Errorf("Expected configs to be unchanged, got %v", names)
+				}
+			})
+		}
+	})
+}
+
+func TestServer_HandleDeleteConfig(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		server := createUploadTestServer(t)
+		filePath := filepath.Join(server.ConfigsDir, "dev.yaml")
+
+		req := newAdminRequest("DELETE", "/config?name=dev", nil)
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+			t.Errorf("Expected config file to be deleted, got %v", err)
+		}
+		if names := listConfigNames(t, server); len(names) != 0 {
+			t.Errorf("Expected no configs to be listed, got %v", names)
+		}
+	})
+
+	t.Run("uploaded config", func(t *testing.T) {
+		server := createUploadTestServer(t)
+		prodConfig := testutil.LoadTestData(t, "kubeconfigs/prod.yaml")
+
+		w := httptest.NewRecorder()
+		server.HandleUploadConfig(w, newAdminRequest("POST", "/upload?name=prod", bytes.NewReader(prodConfig)))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Failed to upload config: %d", w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		server.HandleDeleteConfig(w, newAdminRequest("DELETE", "/config?name=prod", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if _, err := os.Stat(filepath.Join(server.ConfigsDir, "prod.yaml")); !os.IsNotExist(err) {
+			t.Errorf("Expected uploaded config file to be deleted, got %v", err)
+		}
+	})
+
+	t.Run("single file", func(t *testing.T) {
+		configsDir := t.TempDir()
+		testutil.CopyTestKubeConfigs(t, configsDir, map[string]string{"dev.yaml": "dev.yaml"})
+		filePath := filepath.Join(configsDir, "dev.yaml")
+		serverConfig, _ := createTestServerRaw(t, filePath)
+		serverConfig.SingleFile = true
+		server, err := NewServer(serverConfig)
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		server.AdminToken = testAdminToken
+
+		w := httptest.NewRecorder()
+		server.HandleDeleteConfig(w, newAdminRequest("DELETE", "/config?name=dev", nil))
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status code %d, got %d", http.StatusForbidden, w.Code)
+		}
+		if _, err := os.Stat(filePath); err != nil {
+			t.Errorf("Expected the single config file to be kept: %v", err)
+		}
+	})
+
+	t.Run("secret source", func(t *testing.T) {
+		server := createUploadTestServer(t)
+		server.SecretSource = true
+
+		w := httptest.NewRecorder()
+		server.HandleDeleteConfig(w, newAdminRequest("DELETE", "/config?name=dev", nil))
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status code %d, got %d", http.StatusForbidden, w.Code)
+		}
+		if _, exists := server.LoadedConfigs["dev"]; !exists {
+			t.Error("Expected config to stay loaded")
+		}
+	})
+
+	t.Run("path is the configs directory", func(t *testing.T) {
+		server := createUploadTestServer(t)
+		server.ConfigMeta["dev"].Path = server.ConfigsDir
+
+		w := httptest.NewRecorder()
+		server.HandleDeleteConfig(w, newAdminRequest("DELETE", "/config?name=dev", nil))
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status code %d, got %d", http.StatusForbidden, w.Code)
+		}
+		if _, err := os.Stat(filepath.Join(server.ConfigsDir, "dev.yaml")); err != nil {
+			t.Errorf("Expected dev config file to be kept: %v", err)
+		}
+	})
+
+	t.Run("error cases", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			configName     string
+			expectedStatus int
+		}{
+			{name: "unknown name", configName: "nonexistent", expectedStatus: http.StatusNotFound},
+			{name: "directory traversal", configName: "../dev", expectedStatus: http.StatusBadRequest},
+			{name: "empty name", configName: "", expectedStatus: http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				server := createUploadTestServer(t)
+
+				req := newAdminRequest("DELETE", "/config?name="+tt.configName, nil)
+				w := httptest.NewRecorder()
+				server.HandleDeleteConfig(w, req)
+
+				if w.Code != tt.expectedStatus {
+					t.Errorf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+				}
+				if _, err := os.Stat(filepath.Join(server.ConfigsDir, "dev.yaml")); err != nil {
+					t.Errorf("Expected dev config file to be kept: %v", err)
+				}
+			})
+		}
+	})
+}
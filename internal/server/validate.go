@@ -0,0 +1,52 @@
+package server
+
+import "net/http"
+
+// ValidationReport lists every problem that keeps a kubeconfig from being uploaded
+type ValidationReport struct {
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors"`
+}
+
+// validateKubeConfigData runs the checks of uploads on a kubeconfig and collects all
+// problems instead of stopping at the first one
+func (s *Server) validateKubeConfigData(data []byte) ValidationReport {
+	report := ValidationReport{Errors: []string{}}
+
+	kubeConfig, err := parseKubeConfig(data)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+
+	errs := append(kubeConfig.missingEntries(), kubeConfig.multipleEntries()...)
+	if err := s.validateContextNames(kubeConfig); err != nil {
+		errs = append(errs, err)
+	}
+	for _, err := range errs {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	report.Valid = len(report.Errors) == 0
+	return report
+}
+
+// HandleValidate checks a posted kubeconfig the way uploads do without storing it. The
+// report is returned with 200 whether the config is valid or not, so clients can show all
+// problems at once, e.g. in CI before committing a new config file
+func (s *Server) HandleValidate(w http.ResponseWriter, r *http.Request) {
+	data, err := readUploadedConfig(r)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to read config", http.StatusBadRequest)
+		return
+	}
+
+	report := s.validateKubeConfigData(data)
+	s.Logger.Info("Validated config", "valid", report.Valid, "errors", len(report.Errors))
+
+	err = createJSONEncoder(w).Encode(report)
+	if err != nil {
+		s.handleHTTPError(w, err, "Failed to encode validation report", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+func TestServer_HandleValidate(t *testing.T) {
+	server := createUploadTestServer(t)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedValid  bool
+		expectedErrors []string
+	}{
+		{
+			name:          "valid config",
+			body:          string(testutil.LoadTestData(t, "kubeconfigs/prod.yaml")),
+			expectedValid: true,
+		},
+		{
+			name: "multi-entry config",
+			body: string(testutil.LoadTestData(t, "kubeconfigs/multi-cluster.yaml")),
+			expectedErrors: []string{
+				"kubeconfig has more than one cluster",
+				"kubeconfig has more than one context",
+			},
+		},
+		{
+			name: "missing users",
+			body: `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://foo.example.com
+  name: foo-cluster
+contexts:
+- context:
+    cluster: foo-cluster
+    user: foo-user
+  name: foo-context
+`,
+			expectedErrors: []string{"kubeconfig has no users"},
+		},
+		{
+			name:           "unparsable config",
+			body:           "clusters: [unclosed",
+			expectedErrors: []string{"yaml: "},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/validate", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			var report ValidationReport
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			if report.Valid != tt.expectedValid {
+				t.Errorf("Expected valid %v, got %v", tt.expectedValid, report.Valid)
+			}
+			if len(report.Errors) != len(tt.expectedErrors) {
+				t.Fatalf("Expected errors %v, got %v", tt.expectedErrors, report.Errors)
+			}
+			for i, expected := range tt.expectedErrors {
+				if !strings.Contains(report.Errors[i], expected) {
+					t.Errorf("Expected error %d to contain %q, got %q", i, expected, report.Errors[i])
+				}
+			}
+		})
+	}
+
+	// Validated configs aren't stored
+	if names := listConfigNames(t, server); !slices.Equal(names, []string{"dev"}) {
+		t.Errorf("Expected validated configs not to be stored, got %v", names)
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/joomcode/errorx"
+)
+
+// watchDebounce is how long the configs directory has to stay quiet before changes are reloaded
+const watchDebounce = 500 * time.Millisecond
+
+// watchedDir returns the directory to watch for config changes
+func (s *Server) watchedDir() string {
+	if s.SingleFile {
+		if info, err := os.Stat(s.ConfigsDir); err == nil && !info.IsDir() {
+			return filepath.Dir(s.ConfigsDir)
+		}
+	}
+	return s.ConfigsDir
+}
+
+// startWatching watches the configs directory and reloads changed configs until the
+// returned stop function is called
+func (s *Server) startWatching() (func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to create watcher")
+	}
+	dir := s.watchedDir()
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, errorx.Decorate(err, "failed to watch directory: %s", dir)
+	}
+
+	s.Logger.Info("Watching configs directory for changes", "dir", dir)
+	done := make(chan struct{})
+	go s.watchLoop(watcher, done)
+	return func() {
+		close(done)
+		watcher.Close()
+	}, nil
+}
+
+// watchLoop debounces watcher events and reloads configs once they settle
+func (s *Server) watchLoop(watcher *fsnotify.Watcher, done <-chan struct{}) {
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			s.Logger.Debug("Config change detected", "file", event.Name, "op", event.Op)
+			timer.Reset(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			s.Logger.Error("Config watcher error", "error", err)
+		case <-timer.C:
+			s.reloadChangedConfigs()
+		}
+	}
+}
+
+// reloadChangedConfigs reloads configs after a change, sharing an in-flight reload if any
+func (s *Server) reloadChangedConfigs() {
+	if _, err := s.sharedReload(s.reloadChanged); err != nil {
+		s.Logger.Error("Failed to reload changed configs", "error", err)
+	}
+}
+
+// reloadChanged re-reads the configs directory after a change. Unlike Reload it
+// tolerates individual files: a file that fails to load is logged and keeps its previously
+// loaded version, and configs of removed files are dropped
+func (s *Server) reloadChanged() (int, error) {
+	s.Logger.Info("Reloading changed configs", "configsDir", s.ConfigsDir)
+
+	if err := s.validateConfigsDirectory(); err != nil {
+		s.ready.Store(false)
+		return 0, err
+	}
+	files, err := s.readConfigFiles()
+	if err != nil {
+		s.ready.Store(false)
+		return 0, err
+	}
+
+	s.mu.RLock()
+	previousConfigs := s.LoadedConfigs
+	previousMeta := s.ConfigMeta
+	previousRaw := s.rawCache
+	s.mu.RUnlock()
+
+	set := newConfigSet(len(files))
+	for _, file := range files {
+		loaded, err := s.loadConfigFile(file.path, file.entry)
+		if err != nil {
+			s.Logger.Error("Skipping config file that failed to load", "file", file.path, "error", err)
+			name := s.configNameFromPath(file.path)
+			set.invalid[name] = err.Error()
+			if previous, exists := previousConfigs[name]; exists {
+				raw, _ := previousRaw.get(name)
+				loaded = &loadedConfig{name: name, kubeConfig: previous, meta: previousMeta[name], raw: raw}
+			}
+		}
+		if loaded != nil {
+			if err := set.add(loaded); err != nil {
+				s.ready.Store(false)
+				return 0, errorx.Decorate(err, "keeping current configs")
+			}
+		}
+	}
+
+	if err := s.validateReloadedGroups(set.configs); err != nil {
+		s.ready.Store(false)
+		return 0, errorx.Decorate(err, "groups refer to removed configs, keeping current configs")
+	}
+	if !s.SkipMergeValidation {
+		if err := s.validateConfigsMergeable(set.configs); err != nil {
+			s.ready.Store(false)
+			return 0, errorx.Decorate(err, "changed configs cannot be merged together, keeping current configs")
+		}
+	}
+
+	count, invalid := len(set.configs), len(set.invalid)
+	s.storeConfigSet(set)
+	s.ready.Store(true)
+
+	s.Logger.Info("Successfully reloaded changed configs", "count", count, "invalid", invalid)
+	return count, nil
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rgeraskin/kubedepot/internal/testutil"
+)
+
+// waitForConfigs polls until the loaded config names satisfy cond or the timeout expires
+func waitForConfigs(t *testing.T, server *Server, cond func(names []string) bool) []string {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		names := server.getAllConfigNames()
+		if cond(names) || time.Now().After(deadline) {
+			return names
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestServer_Watch(t *testing.T) {
+	server := createUploadTestServer(t)
+	stop, err := server.startWatching()
+	if err != nil {
+		t.Fatalf("Failed to start watching: %v", err)
+	}
+	defer stop()
+
+	// A new file appears in the list
+	testutil.CopyTestKubeConfigs(t, server.ConfigsDir, map[string]string{"prod.yaml": "prod.yaml"})
+	names := waitForConfigs(t, server, func(names []string) bool {
+		return slices.Contains(names, "prod")
+	})
+	if !slices.Contains(names, "prod") {
+		t.Fatalf("Expected created config to be loaded, got %v", names)
+	}
+
+	// A file that fails to parse is skipped without dropping the others
+	invalidPath := filepath.Join(server.ConfigsDir, "broken.yaml")
+	if err := os.WriteFile(invalidPath, []byte("clusters: [unclosed"), 0o644); err != nil {
+		t.Fatalf("Failed to write invalid config: %v", err)
+	}
+	time.Sleep(2 * watchDebounce)
+	names = server.getAllConfigNames()
+	if slices.Contains(names, "broken") || !slices.Contains(names, "dev") || !slices.Contains(names, "prod") {
+		t.Errorf("Expected invalid config to be skipped, got %v", names)
+	}
+	if err := os.Remove(invalidPath); err != nil {
+		t.Fatalf("Failed to remove invalid config: %v", err)
+	}
+
+	// A removed file is dropped
+	if err := os.Remove(filepath.Join(server.ConfigsDir, "prod.yaml")); err != nil {
+		t.Fatalf("Failed to remove config: %v", err)
+	}
+	names = waitForConfigs(t, server, func(names []string) bool {
+		return !slices.Contains(names, "prod")
+	})
+	if slices.Contains(names, "prod") || !slices.Contains(names, "dev") {
+		t.Errorf("Expected removed config to be dropped, got %v", names)
+	}
+}